@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"go/format"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func main() {
+	pkgPath := flag.String("package.path", "", "import path of the package with the struct types")
+	types := flag.String("types", "", "comma-separated names of the struct types")
+	out := flag.String("output", "context.jsonld", "file to write the context document to")
+	protected := flag.Bool("protected", false, "mark all terms as protected")
+	flag.Parse()
+
+	if *pkgPath == "" {
+		panic("need a package import path")
+	}
+
+	if *types == "" {
+		panic("need at least one type")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	// The types can only be reflected over from a program that imports
+	// them, so generate one and run it. It needs to be inside the module
+	// that depends on the package, hence the current directory.
+	dir, err := os.MkdirTemp(".", "ctxgen")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var prog bytes.Buffer
+	prog.WriteString("package main\n\n")
+	prog.WriteString("import (\n\t\"os\"\n\n\tld \"sourcery.dny.nu/longdistance\"\n\tpkg " + strconv.Quote(*pkgPath) + "\n)\n\n")
+	prog.WriteString("func main() {\n")
+	prog.WriteString("\tres, err := ld.GenerateContext([]any{\n")
+	for name := range strings.SplitSeq(*types, ",") {
+		prog.WriteString("\t\tpkg." + strings.TrimSpace(name) + "{},\n")
+	}
+	prog.WriteString("\t}, ld.WithProtectedTerms(" + strconv.FormatBool(*protected) + "))\n")
+	prog.WriteString("\tif err != nil {\n\t\tpanic(err)\n\t}\n\n")
+	prog.WriteString("\tos.Stdout.Write(res)\n")
+	prog.WriteString("}\n")
+
+	src, err := format.Source(prog.Bytes())
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), src, 0o644); err != nil {
+		panic(err)
+	}
+
+	cmd := exec.CommandContext(ctx, "go", "run", "./"+filepath.ToSlash(dir))
+	cmd.Stderr = os.Stderr
+
+	res, err := cmd.Output()
+	if err != nil {
+		panic(err)
+	}
+
+	doc, err := json.MarshalIndent(map[string]json.RawMessage{ld.KeywordContext: res}, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(*out, append(doc, '\n'), 0o644); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func main() {
+	lock := flag.String("lock", "contexts.lock.json", "lockfile to write or verify")
+	dir := flag.String("dir", "", "directory of context documents")
+	base := flag.String("dir.base", "", "IRI the files in -dir are published under")
+	keepExt := flag.Bool("dir.ext", false, "keep the file extension in the IRIs of the files in -dir")
+	verify := flag.Bool("verify", false, "verify the contexts against the lockfile instead of writing it")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout for retrieving a context")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [IRI...]\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	if *dir != "" && *base == "" {
+		fatal(errors.New("-dir requires -dir.base"))
+	}
+
+	client := &http.Client{Timeout: *timeout}
+	loader := ld.NewHTTPContextLoader(client)
+
+	src := source{dir: *dir, base: *base, keepExt: *keepExt}
+
+	var err error
+	if *verify {
+		err = verifyLockfile(ctx, *lock, src, flag.Args(), loader)
+	} else {
+		err = writeLockfile(ctx, *lock, src, flag.Args(), loader)
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// source is a directory of context documents and the IRI they're published
+// under.
+type source struct {
+	dir     string
+	base    string
+	keepExt bool
+}
+
+// iri returns the IRI the file at rel, relative to the directory, is
+// published under.
+func (s source) iri(rel string) string {
+	rel = filepath.ToSlash(rel)
+	if !s.keepExt {
+		rel = strings.TrimSuffix(rel, filepath.Ext(rel))
+	}
+
+	return strings.TrimSuffix(s.base, "/") + "/" + rel
+}
+
+func writeLockfile(
+	ctx context.Context,
+	lock string,
+	src source,
+	iris []string,
+	loader ld.RemoteContextLoaderFunc,
+) error {
+	if src.dir == "" && len(iris) == 0 {
+		return errors.New("need a directory or IRIs to pin")
+	}
+
+	pins, err := digests(ctx, src, iris, loader)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(lock, append(data, '\n'), 0o644)
+}
+
+func verifyLockfile(
+	ctx context.Context,
+	lock string,
+	src source,
+	iris []string,
+	loader ld.RemoteContextLoaderFunc,
+) error {
+	data, err := os.ReadFile(lock)
+	if err != nil {
+		return err
+	}
+
+	var pins map[string]string
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return fmt.Errorf("invalid lockfile %s: %w", lock, err)
+	}
+
+	// without any sources, retrieve everything that's pinned
+	if src.dir == "" && len(iris) == 0 {
+		iris = slices.Sorted(maps.Keys(pins))
+	}
+
+	got, err := digests(ctx, src, iris, loader)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, iri := range slices.Sorted(maps.Keys(got)) {
+		want, ok := pins[iri]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("%s: not pinned", iri))
+		case want != got[iri]:
+			errs = append(errs, fmt.Errorf("%s: expected %s, got %s", iri, want, got[iri]))
+		default:
+			fmt.Printf("%s: ok\n", iri)
+		}
+	}
+
+	// with a directory, everything that's pinned has to be verified, so a
+	// removed or renamed file doesn't go unnoticed
+	if src.dir != "" {
+		for _, iri := range slices.Sorted(maps.Keys(pins)) {
+			if _, ok := got[iri]; !ok {
+				errs = append(errs, fmt.Errorf("%s: pinned, but not found", iri))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// digests computes the digest of every context in the directory of src and of
+// every IRI.
+func digests(
+	ctx context.Context,
+	src source,
+	iris []string,
+	loader ld.RemoteContextLoaderFunc,
+) (map[string]string, error) {
+	res := map[string]string{}
+
+	if src.dir != "" {
+		err := filepath.WalkDir(src.dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || !isContextFile(path) {
+				return nil
+			}
+
+			rel, err := filepath.Rel(src.dir, path)
+			if err != nil {
+				return err
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+
+			digest, err := documentDigest(data)
+			if err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+
+			iri := src.iri(rel)
+			if _, ok := res[iri]; ok {
+				return fmt.Errorf("%s: more than one file for %s", path, iri)
+			}
+			res[iri] = digest
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, iri := range iris {
+		doc, err := loader(ctx, iri)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", iri, err)
+		}
+
+		digest, err := ld.ContextDigest(doc.Context)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", iri, err)
+		}
+
+		res[iri] = digest
+	}
+
+	return res, nil
+}
+
+func isContextFile(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".jsonld" || ext == ".json"
+}
+
+// documentDigest computes the digest of the @context of a context document,
+// like a loader would return it.
+func documentDigest(data []byte) (string, error) {
+	var obj map[string]json.RawMessage
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&obj); err != nil || obj == nil {
+		return "", ld.ErrInvalidRemoteContext
+	}
+
+	lctx, ok := obj[ld.KeywordContext]
+	if !ok {
+		lctx = json.RawMessage(`{}`)
+	}
+
+	return ld.ContextDigest(lctx)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"go/format"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/contexts"
+)
+
+func main() {
+	doc := flag.String("context", "", "context file")
+	docIRI := flag.String("document.iri", "", "remote context IRI for this file")
+	pkgName := flag.String("package.name", "vocab", "Go package name")
+	out := flag.String("output", ".", "directory to write the Go source and snapshot to")
+	name := flag.String("file.name", "context", "base name of the generated files")
+	flag.Parse()
+
+	if *doc == "" {
+		panic("need a context file to read")
+	}
+
+	if *docIRI == "" {
+		panic("need a document IRI")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer cancel()
+
+	data, err := os.ReadFile(*doc)
+	if err != nil {
+		panic(err)
+	}
+
+	var rawCtx map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rawCtx); err != nil {
+		panic(err)
+	}
+
+	// bundled contexts are used without going to the network
+	proc := ld.NewProcessor(
+		ld.WithRemoteContextLoader(contexts.Loader(ld.NewHTTPContextLoader(nil))),
+	)
+
+	res, err := proc.Context(ctx, bytes.NewReader(rawCtx[ld.KeywordContext]), *docIRI)
+	if err != nil {
+		panic(err)
+	}
+
+	snapshot, err := json.MarshalIndent(res, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	snapshotFile := *name + ".snapshot.json"
+	if err := os.WriteFile(filepath.Join(*out, snapshotFile), append(snapshot, '\n'), 0o644); err != nil {
+		panic(err)
+	}
+
+	var result bytes.Buffer
+	result.WriteString("// Code generated by ctxsnapshot. DO NOT EDIT.\n\n")
+	result.WriteString("package " + *pkgName + "\n\n")
+	result.WriteString("import (\n\t_ \"embed\"\n\t\"sync\"\n\n\tld \"sourcery.dny.nu/longdistance\"\n)\n\n")
+
+	result.WriteString("// IRI is the remote context IRI.\n")
+	result.WriteString("const IRI = " + strconv.Quote(*docIRI) + "\n\n")
+
+	result.WriteString("//go:embed " + snapshotFile + "\n")
+	result.WriteString("var snapshot []byte\n\n")
+
+	result.WriteString("// Context returns the processed context. It's restored from the embedded\n// snapshot on first use.\n")
+	result.WriteString("var Context = sync.OnceValue(func() *ld.Context {\n\treturn ld.MustLoadContextSnapshot(snapshot)\n})\n\n")
+
+	result.WriteString("// ProcessedContext returns a [ld.ProcessorOption] that installs [Context] for\n// [IRI] with [ld.WithProcessedContext].\n")
+	result.WriteString("func ProcessedContext() ld.ProcessorOption {\n\treturn ld.WithProcessedContext(IRI, Context())\n}\n")
+
+	src, err := format.Source(result.Bytes())
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(*out, *name+".go"), src, 0o644); err != nil {
+		panic(err)
+	}
+}
@@ -14,6 +14,35 @@
 	"sourcery.dny.nu/longdistance/internal/json"
 )
 
+// CompactIRI compacts iri to a term or compact IRI using the active context
+// activeCtx. It's the reverse of [Processor.ExpandIRI].
+//
+// With vocab set, iri is compacted like a property: it's matched against
+// terms and @vocab. Without vocab, iri is compacted like the value of @id and
+// can become a relative IRI, see [WithCompactToRelative].
+//
+// IRIs that can't be compacted are returned as-is.
+func (p *Processor) CompactIRI(
+	activeCtx *Context,
+	iri string,
+	vocab bool,
+) (string, error) {
+	if activeCtx == nil {
+		return iri, nil
+	}
+
+	// the inverse context is built lazily during compaction. Work on a copy
+	// so the context can be shared between goroutines, unless its inverse is
+	// complete and read-only.
+	if activeCtx.inverse == nil || !activeCtx.inverse.complete {
+		cp := *activeCtx
+		cp.inverse = nil
+		activeCtx = &cp
+	}
+
+	return p.compactIRI(activeCtx, iri, nil, vocab, false)
+}
+
 func (p *Processor) compactIRI(
 	activeContext *Context,
 	key string,
@@ -56,8 +85,10 @@ func (p *Processor) compactIRI(
 			defaultLanguage = "_" + activeContext.defaultLang
 		}
 
-		// 4.2) we don't have @preserve
-		// if value != nil && value.Preserve != nil {}
+		// 4.2)
+		if isObject && len(object.Properties[KeywordPreserve]) > 0 {
+			object = object.Properties[KeywordPreserve][0]
+		}
 
 		// 4.3)
 		containers := make([]string, 0, 4)
@@ -509,12 +540,26 @@ func (p *Processor) Compact(
 	document []Node,
 	documentURL string,
 ) error {
+	ctx = withRemoteContexts(ctx)
+
 	dec := json.NewDecoder(bytes.NewReader(compactionCtx))
 	ldCtx, err := p.context(ctx, nil, dec, documentURL, newCtxProcessingOpts())
 	if err != nil {
 		return err
 	}
 
+	return p.compactTo(ctx, dst, ldCtx, compactionCtx, document)
+}
+
+// compactTo compacts the document using the processed ldCtx and writes the
+// result to dst.
+func (p *Processor) compactTo(
+	ctx context.Context,
+	dst io.Writer,
+	ldCtx *Context,
+	compactionCtx json.RawMessage,
+	document []Node,
+) error {
 	enc := json.NewEncoder(dst)
 
 	if len(document) == 0 {
@@ -525,44 +570,69 @@ func (p *Processor) Compact(
 		return enc.Encode(document)
 	}
 
-	res, err := p.compact(
-		ctx,
-		ldCtx,
-		"",
-		document,
-		p.compactArrays,
-	)
-
+	res, err := p.compactDocument(ctx, ldCtx, compactionCtx, document, false)
 	if err != nil {
 		return err
 	}
 
-	if res == nil {
-		return enc.Encode(json.RawMessage(`{}`))
-	}
+	return enc.Encode(res)
+}
 
-	if v, isObject := res.(map[string]any); isObject && p.compactArrays {
-		if len(compactionCtx) > 2 {
-			v[KeywordContext] = compactionCtx
+// compactDocument compacts the document and returns the top-level map,
+// including the compaction context.
+//
+// When graph is set, the nodes are always returned in a top-level @graph.
+func (p *Processor) compactDocument(
+	ctx context.Context,
+	ldCtx *Context,
+	compactionCtx json.RawMessage,
+	document []Node,
+	graph bool,
+) (map[string]any, error) {
+	var res any
+
+	if graph {
+		nodes := make([]any, 0, len(document))
+		for _, node := range document {
+			compacted, err := p.compact(ctx, ldCtx, "", node, p.compactArrays)
+			if err != nil {
+				return nil, err
+			}
+
+			if compacted != nil {
+				nodes = append(nodes, compacted)
+			}
+		}
+		res = nodes
+	} else {
+		compacted, err := p.compact(ctx, ldCtx, "", document, p.compactArrays)
+		if err != nil {
+			return nil, err
 		}
 
-		return enc.Encode(v)
+		if compacted == nil {
+			return map[string]any{}, nil
+		}
+		res = compacted
 	}
 
-	alias, err := p.compactIRI(ldCtx, KeywordGraph, nil, true, false)
-	if err != nil {
-		return err
-	}
+	result, isObject := res.(map[string]any)
+	if !isObject || !p.compactArrays {
+		alias, err := p.compactIRI(ldCtx, KeywordGraph, nil, true, false)
+		if err != nil {
+			return nil, err
+		}
 
-	result := map[string]any{
-		alias: res,
+		result = map[string]any{
+			alias: res,
+		}
 	}
 
 	if len(compactionCtx) > 2 {
 		result[KeywordContext] = compactionCtx
 	}
 
-	return enc.Encode(result)
+	return result, nil
 }
 
 func (p *Processor) compact(
@@ -711,7 +781,7 @@ func (p *Processor) compact(
 	}
 
 	// 12)
-	for expandedProperty := range object.PropertySet() {
+	for expandedProperty := range entries(object.PropertySet(), p.ordered) {
 		// 12.1)
 		if expandedProperty == KeywordID {
 			// 12.1.1)
@@ -767,7 +837,7 @@ func (p *Processor) compact(
 		if expandedProperty == KeywordReverse {
 			// 12.3.1)
 			res := make([]any, 0, len(object.Reverse))
-			for k, elem := range object.Reverse {
+			for k, elem := range entries(object.Reverse, p.ordered) {
 				compactedValue, err := p.compact(
 					ctx,
 					activeContext,
@@ -834,7 +904,24 @@ func (p *Processor) compact(
 
 		// 12.4)
 		if expandedProperty == KeywordPreserve {
-			return nil, ErrPreserveUnsupported
+			// 12.4.1)
+			compactedValue, err := p.compact(
+				ctx,
+				activeContext,
+				activeProperty,
+				object.Properties[KeywordPreserve],
+				compactArrays,
+			)
+			if err != nil {
+				return nil, err
+			}
+
+			// 12.4.2)
+			if vlist, ok := compactedValue.([]any); !ok || len(vlist) != 0 {
+				result[KeywordPreserve] = compactedValue
+			}
+
+			continue
 		}
 
 		// 12.5)
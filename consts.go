@@ -11,10 +11,12 @@
 	DirectionRTL = "rtl"
 )
 
-// JSON-LD MIME types and profiles.
+// MIME types and JSON-LD profiles.
 const (
 	ApplicationLDJSON = "application/ld+json"
 	ApplicationJSON   = "application/json"
+	TextHTML          = "text/html"
+	ApplicationXHTML  = "application/xhtml+xml"
 
 	ProfileExpanded  = "http://www.w3.org/ns/json-ld#expanded"
 	ProfileCompacted = "http://www.w3.org/ns/json-ld#compacted"
@@ -22,4 +24,7 @@
 	ProfileFlattened = "http://www.w3.org/ns/json-ld#flattened"
 	ProfileFrame     = "http://www.w3.org/ns/json-ld#frame"
 	ProfileFramed    = "http://www.w3.org/ns/json-ld#framed"
+
+	// LinkRelContext is the link relation of a context in a Link header.
+	LinkRelContext = "http://www.w3.org/ns/json-ld#context"
 )
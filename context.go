@@ -32,15 +32,31 @@ type Context struct {
 	defaultDirection string
 	previousCtx      *Context
 	inverse          *lazyInverse
+
+	// cacheKey identifies how the context was derived from a blank context by
+	// applying remote contexts. It's empty if the context was modified any
+	// other way.
+	cacheKey string
+	// baseDependent is set once a relative @vocab or @base was resolved
+	// against the base IRI, so the context differs between documents.
+	baseDependent bool
 }
 
 type lazyInverse struct {
 	context *Context
 	defs    map[string]map[string]mapping
 	built   map[string]struct{}
+
+	// complete is set once the mappings for all terms have been built. After
+	// that the inverse context is read-only.
+	complete bool
 }
 
 func (l *lazyInverse) get(iri string) (map[string]mapping, bool) {
+	if l.complete {
+		return l.defs[iri], true
+	}
+
 	if _, ok := l.built[iri]; ok {
 		return l.defs[iri], ok
 	}
@@ -80,6 +96,60 @@ func (c *Context) TermMap() map[string]Term {
 	return maps.Clone(c.defs)
 }
 
+// Term returns the definition of a term, if any.
+func (c *Context) Term(name string) (Term, bool) {
+	def, ok := c.defs[name]
+	return def, ok
+}
+
+// VocabularyMapping returns the value of @vocab, or the empty string if
+// there's none.
+func (c *Context) VocabularyMapping() string {
+	return c.vocabMapping
+}
+
+// BaseIRI returns the base IRI relative IRIs are resolved against. It's the
+// value of @base if one was set, otherwise the IRI of the document.
+func (c *Context) BaseIRI() string {
+	return c.currentBaseIRI
+}
+
+// OriginalBaseIRI returns the IRI of the document the context was created
+// for, regardless of any @base.
+func (c *Context) OriginalBaseIRI() string {
+	return c.originalBaseIRI
+}
+
+// DefaultLanguage returns the value of @language, lowercased, or the empty
+// string if there's none.
+func (c *Context) DefaultLanguage() string {
+	return c.defaultLang
+}
+
+// DefaultDirection returns the value of @direction, or the empty string if
+// there's none.
+func (c *Context) DefaultDirection() string {
+	return c.defaultDirection
+}
+
+// IsProtected returns if the definition of a term is protected.
+func (c *Context) IsProtected(name string) bool {
+	_, ok := c.protected[name]
+	return ok
+}
+
+// ProtectedTerms returns the protected terms, sorted.
+func (c *Context) ProtectedTerms() []string {
+	return slices.Sorted(maps.Keys(c.protected))
+}
+
+// PreviousContext returns the context that's reverted to when a node object
+// is entered, as set by a context with @propagate set to false. It returns
+// nil otherwise.
+func (c *Context) PreviousContext() *Context {
+	return c.previousCtx
+}
+
 func (c *Context) initInverse() {
 	if c.inverse == nil {
 		c.inverse = &lazyInverse{
@@ -90,6 +160,25 @@ func (c *Context) initInverse() {
 	}
 }
 
+// buildInverse builds the complete inverse context of c and the contexts it
+// reverts to. Afterwards they're safe to use for compaction from multiple
+// goroutines.
+func (c *Context) buildInverse() {
+	for ; c != nil; c = c.previousCtx {
+		c.initInverse()
+		if c.inverse.complete {
+			continue
+		}
+
+		for _, def := range c.defs {
+			if _, ok := c.inverse.built[def.IRI]; !ok {
+				c.inverse.workIt(def.IRI)
+			}
+		}
+		c.inverse.complete = true
+	}
+}
+
 func (c *Context) clone() *Context {
 	return &Context{
 		defs:             maps.Clone(c.defs),
@@ -102,6 +191,8 @@ func (c *Context) clone() *Context {
 		defaultDirection: c.defaultDirection,
 		previousCtx:      c.previousCtx,
 		inverse:          nil,
+		cacheKey:         c.cacheKey,
+		baseDependent:    c.baseDependent,
 	}
 }
 
@@ -125,6 +216,8 @@ func (c *Context) isBlank() bool {
 func (p *Processor) Context(
 	ctx context.Context,
 	rawCtx io.Reader, baseURL string) (*Context, error) {
+	ctx = withRemoteContexts(ctx)
+
 	dec := json.NewDecoder(rawCtx)
 
 	res, err := p.context(ctx, nil, dec, baseURL, newCtxProcessingOpts())
@@ -164,19 +257,15 @@ func (p *Processor) context(
 		activeCtx = newContext(baseURL)
 	}
 
-	activeCtx.currentBaseIRI = cmp.Or(
+	// 1) always work on a copy, the active context may be a prepared one
+	// that's shared between goroutines
+	result := activeCtx.clone()
+
+	result.currentBaseIRI = cmp.Or(
 		p.baseIRI,
-		activeCtx.currentBaseIRI,
+		result.currentBaseIRI,
 	)
 
-	// 1)
-	var result *Context
-	if activeCtx.isBlank() {
-		result = activeCtx
-	} else {
-		result = activeCtx.clone()
-	}
-
 	tok, err := rawCtx.Token()
 	if err != nil {
 		return nil, errors.Join(err, ErrInvalidLocalContext)
@@ -219,6 +308,9 @@ func (p *Processor) context(
 				return nil, err
 			}
 
+			// the result no longer only derives from remote contexts
+			result.cacheKey = ""
+
 			// 2) Check @propagate on first context
 			if first && ctxObj.Propagate.Set && ctxObj.Propagate.Valid {
 				opts.propagate = ctxObj.Propagate.Value
@@ -347,6 +439,8 @@ func (p *Processor) context(
 			}
 			opts.remotes = append(opts.remotes, iri)
 
+			key := p.remoteContextCacheKey(result, iri, opts.validate)
+
 			cached := false
 			if result.isBlank() {
 				if pctx, ok := p.processedContext[iri]; ok {
@@ -356,6 +450,20 @@ func (p *Processor) context(
 					result = pctx.clone()
 					result.currentBaseIRI = curIRI
 					result.originalBaseIRI = origIRI
+					result.cacheKey = ""
+					if !result.baseDependent {
+						result.cacheKey = key
+					}
+
+					cached = true
+				}
+			}
+
+			if !cached && key != "" {
+				if pctx, ok := p.contextCache.get(key); ok {
+					pctx.currentBaseIRI = result.currentBaseIRI
+					pctx.originalBaseIRI = result.originalBaseIRI
+					result = pctx
 
 					cached = true
 				}
@@ -384,6 +492,14 @@ func (p *Processor) context(
 					return nil, err
 				}
 
+				// contexts that don't propagate reference the active
+				// context, and a relative @vocab is resolved against the
+				// base IRI of the document, so neither can be reused
+				if key != "" && res != nil && res.previousCtx == nil && !res.baseDependent {
+					res.cacheKey = key
+					p.contextCache.add(key, res)
+				}
+
 				result = res
 			}
 		default:
@@ -698,6 +814,10 @@ func (p *Processor) handleVocab(ctx context.Context, result *Context, vocab null
 		return ErrInvalidVocabMapping
 	}
 
+	if result.vocabMapping == "" && !iri.IsAbsolute(vocab.Value) && vocab.Value != BlankNode {
+		result.baseDependent = true
+	}
+
 	u, err := p.expandIRI(ctx, result, vocab.Value, true, true, nil, nil)
 	if err != nil {
 		return err
@@ -727,6 +847,7 @@ func (p *Processor) handleBase(result *Context, base null[string]) error {
 			return ErrInvalidBaseIRI
 		}
 		result.currentBaseIRI = u
+		result.baseDependent = true
 		return nil
 	}
 
@@ -823,6 +944,33 @@ func (p *Processor) handleVersion(ver null[float64]) error {
 	return nil
 }
 
+// remoteContextCacheKey returns the key under which the result of applying
+// the remote context iri to the active context is cached. It returns the
+// empty string if the result can't be cached.
+//
+// Results that depend on the base IRI are never cached, see
+// [Context.baseDependent].
+func (p *Processor) remoteContextCacheKey(active *Context, iri string, validate bool) string {
+	parent := "_"
+	if !active.isBlank() {
+		parent = active.cacheKey
+	}
+
+	if parent == "" {
+		return ""
+	}
+
+	key := parent + "\n" + iri
+	if !validate {
+		key += "\n!"
+	}
+	if p.modeLD10 {
+		key += "\n1.0"
+	}
+
+	return key
+}
+
 func (p *Processor) retrieveRemoteContext(
 	ctx context.Context,
 	iri string,
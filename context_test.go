@@ -0,0 +1,158 @@
+package longdistance_test
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+const accessorsContext = `[{
+	"@propagate": false,
+	"@base": "https://example.org/base/",
+	"@vocab": "https://example.org/ns#",
+	"@language": "en-GB",
+	"@direction": "ltr",
+	"as": "https://www.w3.org/ns/activitystreams#",
+	"sensitive": "as:sensitive",
+	"knows": {"@id": "https://example.org/ns#knows", "@type": "@id", "@protected": true}
+}, {
+	"local": "https://example.org/local#name"
+}]`
+
+func TestContextAccessors(t *testing.T) {
+	lctx := ProcessContext(t, json.RawMessage(accessorsContext), "https://example.org/document")
+
+	if got := lctx.VocabularyMapping(); got != "https://example.org/ns#" {
+		t.Errorf("unexpected vocabulary mapping: %s", got)
+	}
+
+	if got := lctx.BaseIRI(); got != "https://example.org/base/" {
+		t.Errorf("unexpected base IRI: %s", got)
+	}
+
+	if got := lctx.OriginalBaseIRI(); got != "https://example.org/document" {
+		t.Errorf("unexpected original base IRI: %s", got)
+	}
+
+	if got := lctx.DefaultLanguage(); got != "en-gb" {
+		t.Errorf("unexpected default language: %s", got)
+	}
+
+	if got := lctx.DefaultDirection(); got != "ltr" {
+		t.Errorf("unexpected default direction: %s", got)
+	}
+
+	if !lctx.IsProtected("knows") || lctx.IsProtected("sensitive") {
+		t.Errorf("expected only knows to be protected")
+	}
+
+	if got := lctx.ProtectedTerms(); !slices.Equal(got, []string{"knows"}) {
+		t.Errorf("unexpected protected terms: %v", got)
+	}
+
+	def, ok := lctx.Term("sensitive")
+	if !ok || def.IRI != "https://www.w3.org/ns/activitystreams#sensitive" {
+		t.Errorf("unexpected term definition: %+v", def)
+	}
+
+	if _, ok := lctx.Term("missing"); ok {
+		t.Errorf("expected no term definition for missing")
+	}
+
+	prev := lctx.PreviousContext()
+	if prev == nil {
+		t.Fatalf("expected a previous context")
+	}
+
+	if _, ok := prev.Term("knows"); ok {
+		t.Errorf("expected the previous context not to define knows")
+	}
+}
+
+func TestExpandIRI(t *testing.T) {
+	p := ld.NewProcessor()
+	lctx := ProcessContext(t, json.RawMessage(accessorsContext), "https://example.org/document")
+
+	tests := []struct {
+		name  string
+		value string
+		vocab bool
+		want  string
+	}{
+		{name: "term", value: "sensitive", vocab: true, want: "https://www.w3.org/ns/activitystreams#sensitive"},
+		{name: "compact IRI", value: "as:Note", vocab: true, want: "https://www.w3.org/ns/activitystreams#Note"},
+		{name: "vocab", value: "unknown", vocab: true, want: "https://example.org/ns#unknown"},
+		{name: "keyword", value: "@type", vocab: true, want: "@type"},
+		{name: "absolute IRI", value: "https://example.com/", vocab: true, want: "https://example.com/"},
+		{name: "relative", value: "alice", want: "https://example.org/base/alice"},
+		{name: "term as value", value: "sensitive", want: "https://example.org/base/sensitive"},
+		{name: "compact IRI as value", value: "as:Public", want: "https://www.w3.org/ns/activitystreams#Public"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := p.ExpandIRI(t.Context(), lctx, tc.value, tc.vocab)
+			if err != nil {
+				t.Fatalf("expected no error, got: %s", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("expected: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCompactIRI(t *testing.T) {
+	p := ld.NewProcessor()
+	lctx := ProcessContext(t, json.RawMessage(accessorsContext), "https://example.org/document")
+
+	tests := []struct {
+		name  string
+		iri   string
+		vocab bool
+		want  string
+	}{
+		{name: "term", iri: "https://www.w3.org/ns/activitystreams#sensitive", vocab: true, want: "sensitive"},
+		{name: "compact IRI", iri: "https://www.w3.org/ns/activitystreams#Note", vocab: true, want: "as:Note"},
+		{name: "vocab", iri: "https://example.org/ns#unknown", vocab: true, want: "unknown"},
+		{name: "unrelated", iri: "https://example.com/", vocab: true, want: "https://example.com/"},
+		{name: "relative", iri: "https://example.org/base/alice", want: "alice"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := p.CompactIRI(lctx, tc.iri, tc.vocab)
+			if err != nil {
+				t.Fatalf("expected no error, got: %s", err)
+			}
+
+			if got != tc.want {
+				t.Errorf("expected: %s, got: %s", tc.want, got)
+			}
+		})
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				got, err := p.CompactIRI(lctx, "https://www.w3.org/ns/activitystreams#sensitive", true)
+				if err != nil || got != "sensitive" {
+					t.Errorf("expected sensitive, got: %s, %v", got, err)
+				}
+			}()
+		}
+		wg.Wait()
+	})
+}
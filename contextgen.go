@@ -0,0 +1,214 @@
+package longdistance
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"strings"
+)
+
+// GenerateContextOption can be used to configure [GenerateContext].
+type GenerateContextOption func(*contextGenerator)
+
+// WithProtectedTerms sets whether the generated context marks all its term
+// definitions as protected, preventing documents from redefining them.
+func WithProtectedTerms(protected bool) GenerateContextOption {
+	return func(g *contextGenerator) {
+		g.protected = protected
+	}
+}
+
+type contextGenerator struct {
+	protected bool
+
+	terms   map[string]any
+	origins map[string]string
+	seen    map[reflect.Type]struct{}
+}
+
+// GenerateContext generates a context with a term definition for each field
+// of the struct types, using the same ld struct tags as [MarshalNode] and
+// [UnmarshalNode]. Each element of types is a value of the struct type, a
+// pointer to one, or a [reflect.Type]. The types of struct fields that hold
+// nodes are included too.
+//
+// The term of a field is the name in its json struct tag if it has one, and
+// the field name in lower camel case otherwise. Fields tagged with @id,
+// @type or @index become aliases of those keywords. For the other fields the
+// term definition is derived from the field:
+//   - @id is the IRI from the ld tag.
+//   - @type is @id for fields with the ref option, the datatype from the
+//     type option, xsd:dateTime for a [time.Time] and @json for a
+//     [json.RawMessage].
+//   - @container is @list for slices with the list option, @set for other
+//     slices and @language for language maps.
+//   - @language is the language from the lang option.
+//
+// The result is the value of the @context entry, and can be processed with
+// [Processor.Context]. Documents compacted with it decode back into the
+// same structs. Terms that are defined differently by multiple fields result
+// in an error.
+func GenerateContext(types []any, opts ...GenerateContextOption) (json.RawMessage, error) {
+	g := &contextGenerator{
+		terms:   map[string]any{},
+		origins: map[string]string{},
+		seen:    map[reflect.Type]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	for _, v := range types {
+		t, ok := v.(reflect.Type)
+		if !ok {
+			t = reflect.TypeOf(v)
+		}
+
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("cannot generate a context for %v, expected a struct", t)
+		}
+
+		if err := g.addType(t); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make(map[string]any, len(g.terms)+2)
+	maps.Copy(res, g.terms)
+
+	res[KeywordVersion] = 1.1
+	if g.protected {
+		res[KeywordProtected] = true
+	}
+
+	return json.Marshal(res)
+}
+
+func (g *contextGenerator) addType(t reflect.Type) error {
+	if _, ok := g.seen[t]; ok {
+		return nil
+	}
+	g.seen[t] = struct{}{}
+
+	fields, err := fieldsOf(t)
+	if err != nil {
+		return fmt.Errorf("type %s: %w", t, err)
+	}
+
+	for _, f := range fields {
+		origin := t.String() + "." + f.name
+
+		var def any
+		switch f.iri {
+		case KeywordID, KeywordType, KeywordIndex:
+			def = f.iri
+		default:
+			def = termDefinition(f)
+		}
+
+		if err := g.addTerm(f.term, def, origin); err != nil {
+			return err
+		}
+
+		if f.datatype == KeywordJSON {
+			continue
+		}
+
+		if nt := nodeStruct(f.typ); nt != nil {
+			if err := g.addType(nt); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (g *contextGenerator) addTerm(term string, def any, origin string) error {
+	if term == "" || strings.HasPrefix(term, "@") {
+		return fmt.Errorf("%s: invalid term %q", origin, term)
+	}
+
+	if prev, ok := g.terms[term]; ok {
+		if !reflect.DeepEqual(prev, def) {
+			return fmt.Errorf("%s: term %s conflicts with %s", origin, term, g.origins[term])
+		}
+		return nil
+	}
+
+	g.terms[term] = def
+	g.origins[term] = origin
+	return nil
+}
+
+// nodeStruct returns the struct type of a field that holds nodes, or nil if
+// it doesn't hold any.
+func nodeStruct(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || t == timeType || t == nodeType {
+		return nil
+	}
+
+	return t
+}
+
+// termDefinition returns the expanded term definition for a field, or just
+// its IRI if there's nothing else to define.
+func termDefinition(f field) any {
+	def := map[string]any{KeywordID: f.iri}
+
+	t := f.typ
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case f.datatype == KeywordJSON, t == rawMessageType:
+		def[KeywordType] = KeywordJSON
+		return def
+	case t.Kind() == reflect.Slice:
+		if f.list {
+			def[KeywordContainer] = KeywordList
+		} else {
+			def[KeywordContainer] = KeywordSet
+		}
+
+		t = t.Elem()
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+	case t.Kind() == reflect.Map:
+		def[KeywordContainer] = KeywordLanguage
+		return def
+	}
+
+	switch {
+	case f.ref:
+		def[KeywordType] = KeywordID
+	case f.datatype != "":
+		def[KeywordType] = f.datatype
+	case t == timeType:
+		def[KeywordType] = XSDDateTime
+	case t == rawMessageType:
+		def[KeywordType] = KeywordJSON
+	}
+
+	if f.language != "" {
+		def[KeywordLanguage] = f.language
+	}
+
+	if len(def) == 1 {
+		return f.iri
+	}
+
+	return def
+}
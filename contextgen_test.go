@@ -0,0 +1,180 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestGenerateContext(t *testing.T) {
+	got, err := ld.GenerateContext([]any{testActivity{}})
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	want := json.RawMessage(`{
+		"@version": 1.1,
+		"id": "@id",
+		"type": "@type",
+		"actor": {"@id": "https://www.w3.org/ns/activitystreams#actor", "@type": "@id"},
+		"summary": {"@id": "https://www.w3.org/ns/activitystreams#summary", "@language": "en"},
+		"summaryNL": {"@id": "https://www.w3.org/ns/activitystreams#summary", "@language": "nl"},
+		"content": {"@id": "https://www.w3.org/ns/activitystreams#content", "@container": "@language"},
+		"sensitive": "https://www.w3.org/ns/activitystreams#sensitive",
+		"published": {"@id": "https://www.w3.org/ns/activitystreams#published", "@type": "http://www.w3.org/2001/XMLSchema#dateTime"},
+		"updated": {"@id": "https://www.w3.org/ns/activitystreams#updated", "@type": "http://www.w3.org/2001/XMLSchema#dateTime"},
+		"duration": {"@id": "https://www.w3.org/ns/activitystreams#duration", "@type": "http://www.w3.org/2001/XMLSchema#duration"},
+		"width": {"@id": "https://www.w3.org/ns/activitystreams#width", "@type": "http://www.w3.org/2001/XMLSchema#nonNegativeInteger"},
+		"items": {"@id": "https://www.w3.org/ns/activitystreams#items", "@type": "@id", "@container": "@list"},
+		"tags": {"@id": "https://www.w3.org/ns/activitystreams#tag", "@type": "@id", "@container": "@set"},
+		"object": "https://www.w3.org/ns/activitystreams#object",
+		"target": {"@id": "https://www.w3.org/ns/activitystreams#target", "@type": "@id"},
+		"extra": {"@id": "https://example.org/extra", "@type": "@json"},
+		"name": "https://www.w3.org/ns/activitystreams#name"
+	}`)
+
+	if diff := cmp.Diff(want, got, JSONDiff()); diff != "" {
+		t.Fatalf("context mismatch (-want +got):\n%s", diff)
+	}
+
+	act := testActivity{
+		ID:        "https://example.com/create/1",
+		Type:      []string{"https://www.w3.org/ns/activitystreams#Create"},
+		Actor:     "https://example.com/actor/1",
+		Summary:   "A summary",
+		SummaryNL: "Een samenvatting",
+		Content:   map[string]string{"": "Content", "en": "Content"},
+		Published: time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC),
+		Duration:  "PT5M",
+		Width:     42,
+		Items:     []string{"https://example.com/b", "https://example.com/a"},
+		Tags:      []string{"https://example.com/tag/1"},
+		Object: &testAttachment{
+			ID:   "https://example.com/object/1",
+			Type: "https://www.w3.org/ns/activitystreams#Note",
+			Name: "A note",
+		},
+		Target: &testAttachment{ID: "https://example.com/collection/1"},
+		Extra:  map[string]any{"a": []any{true}},
+	}
+
+	nodes, err := ld.Marshal(act)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := ld.NewProcessor()
+
+	var dst bytes.Buffer
+	if err := p.Compact(t.Context(), &dst, got, nodes, ""); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	compacted := json.RawMessage(`{
+		"@context": ` + string(got) + `,
+		"id": "https://example.com/create/1",
+		"type": "https://www.w3.org/ns/activitystreams#Create",
+		"actor": "https://example.com/actor/1",
+		"summary": "A summary",
+		"summaryNL": "Een samenvatting",
+		"content": {"@none": "Content", "en": "Content"},
+		"published": "2024-02-29T12:30:00Z",
+		"duration": "PT5M",
+		"width": "42",
+		"items": ["https://example.com/b", "https://example.com/a"],
+		"tags": ["https://example.com/tag/1"],
+		"object": {
+			"id": "https://example.com/object/1",
+			"type": "https://www.w3.org/ns/activitystreams#Note",
+			"name": "A note"
+		},
+		"target": "https://example.com/collection/1",
+		"extra": {"a": [true]},
+		"sensitive": false
+	}`)
+
+	if diff := cmp.Diff(compacted, json.RawMessage(dst.Bytes()), JSONDiff()); diff != "" {
+		t.Errorf("compaction mismatch (-want +got):\n%s", diff)
+	}
+
+	expanded, err := p.Expand(t.Context(), bytes.NewReader(dst.Bytes()), "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	var decoded testActivity
+	if err := ld.Unmarshal(expanded, &decoded); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(act, decoded); diff != "" {
+		t.Errorf("round-trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerateContextProtected(t *testing.T) {
+	type contact struct {
+		ID    string    `ld:"@id"`
+		Name  string    `ld:"https://schema.org/name"`
+		Knows []contact `ld:"https://schema.org/knows,ref"`
+	}
+
+	got, err := ld.GenerateContext([]any{reflect.TypeFor[contact]()}, ld.WithProtectedTerms(true))
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	lctx := ProcessContext(t, got, "")
+	if !lctx.IsProtected("name") || !lctx.IsProtected("knows") {
+		t.Errorf("expected all terms to be protected, got: %v", lctx.ProtectedTerms())
+	}
+
+	_, err = ld.NewProcessor().Expand(t.Context(), bytes.NewReader([]byte(`{
+		"@context": [`+string(got)+`, {"name": "https://example.org/name"}],
+		"name": "Alice"
+	}`)), "")
+	if !errors.Is(err, ld.ErrProtectedTermRedefinition) {
+		t.Errorf("expected error: %s, got: %v", ld.ErrProtectedTermRedefinition, err)
+	}
+}
+
+func TestGenerateContextErrors(t *testing.T) {
+	type a struct {
+		Name string `ld:"https://example.org/name"`
+	}
+
+	type b struct {
+		Name string `ld:"https://example.org/other"`
+	}
+
+	tests := []struct {
+		name  string
+		types []any
+	}{
+		{name: "not a struct", types: []any{"string"}},
+		{name: "nil", types: []any{nil}},
+		{name: "conflicting terms", types: []any{a{}, &b{}}},
+		{name: "keyword as term", types: []any{struct {
+			ID string `ld:"@id" json:"@id"`
+		}{}}},
+		{name: "invalid tag", types: []any{struct {
+			N int `ld:"https://example.org/n,ordered"`
+		}{}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := ld.GenerateContext(tc.types); err == nil {
+				t.Fatalf("expected an error")
+			}
+		})
+	}
+}
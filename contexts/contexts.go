@@ -0,0 +1,166 @@
+// Package contexts bundles copies of commonly used JSON-LD contexts.
+//
+// Retrieving these contexts over the network for every document is slow and
+// makes processing depend on the availability of third-party servers. The
+// [Loader] serves them from memory instead:
+//
+//	proc := ld.NewProcessor(
+//		ld.WithRemoteContextLoader(contexts.Loader(nil)),
+//	)
+//
+// Contexts that aren't bundled can be retrieved by passing a fallback loader,
+// like one returned by [ld.NewHTTPContextLoader].
+//
+// Each bundled context is also available in processed form using [Processed],
+// or all at once with [WithProcessedContexts].
+//
+// Mastodon doesn't publish a context of its own. The terms it adds are
+// included in every document it serves, and are covered by [Litepub] for
+// software that does link to them.
+package contexts
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// URLs of the bundled contexts.
+const (
+	ActivityStreams = "https://www.w3.org/ns/activitystreams"
+	SecurityV1      = "https://w3id.org/security/v1"
+	SecurityV2      = "https://w3id.org/security/v2"
+	DataIntegrityV1 = "https://w3id.org/security/data-integrity/v1"
+	DataIntegrityV2 = "https://w3id.org/security/data-integrity/v2"
+	MultikeyV1      = "https://w3id.org/security/multikey/v1"
+	DIDV1           = "https://www.w3.org/ns/did/v1"
+	CredentialsV1   = "https://www.w3.org/2018/credentials/v1"
+	CredentialsV2   = "https://www.w3.org/ns/credentials/v2"
+	Litepub         = "https://litepub.social/litepub/context.jsonld"
+)
+
+//go:embed *.jsonld
+var files embed.FS
+
+var bundled = map[string]string{
+	ActivityStreams: "activitystreams.jsonld",
+	SecurityV1:      "security-v1.jsonld",
+	SecurityV2:      "security-v2.jsonld",
+	DataIntegrityV1: "data-integrity-v1.jsonld",
+	DataIntegrityV2: "data-integrity-v2.jsonld",
+	MultikeyV1:      "multikey-v1.jsonld",
+	DIDV1:           "did-v1.jsonld",
+	CredentialsV1:   "credentials-v1.jsonld",
+	CredentialsV2:   "credentials-v2.jsonld",
+	Litepub:         "litepub-0.1.jsonld",
+}
+
+var documents = sync.OnceValue(func() map[string]ld.Document {
+	docs := make(map[string]ld.Document, len(bundled))
+
+	for url, name := range bundled {
+		data, err := files.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(data, &obj); err != nil {
+			panic(fmt.Sprintf("contexts: invalid JSON in %s: %s", name, err))
+		}
+
+		docs[url] = ld.Document{
+			URL:     url,
+			Context: obj[ld.KeywordContext],
+		}
+	}
+
+	return docs
+})
+
+var processed = sync.OnceValue(func() map[string]func() (*ld.Context, error) {
+	res := make(map[string]func() (*ld.Context, error), len(bundled))
+
+	for url := range bundled {
+		res[url] = sync.OnceValues(func() (*ld.Context, error) {
+			proc := ld.NewProcessor(ld.WithRemoteContextLoader(Loader(nil)))
+			return proc.Context(
+				context.Background(),
+				bytes.NewReader(documents()[url].Context),
+				url,
+			)
+		})
+	}
+
+	return res
+})
+
+// URLs returns the URLs of all bundled contexts, in lexical order.
+func URLs() iter.Seq[string] {
+	return slices.Values(slices.Sorted(maps.Keys(bundled)))
+}
+
+// Get returns the bundled context for url, if any.
+func Get(url string) (ld.Document, bool) {
+	doc, ok := documents()[url]
+	return doc, ok
+}
+
+// Loader returns a [ld.RemoteContextLoaderFunc] that serves the bundled
+// contexts.
+//
+// Any other context is retrieved with fallback. If fallback is nil, an error
+// wrapping [ld.ErrLoadingRemoteContext] is returned instead.
+func Loader(fallback ld.RemoteContextLoaderFunc) ld.RemoteContextLoaderFunc {
+	return func(ctx context.Context, url string) (ld.Document, error) {
+		if doc, ok := Get(url); ok {
+			return doc, nil
+		}
+
+		if fallback == nil {
+			return ld.Document{}, fmt.Errorf("%w: %s is not bundled", ld.ErrLoadingRemoteContext, url)
+		}
+
+		return fallback(ctx, url)
+	}
+}
+
+// Processed returns the bundled context for url, processed and ready to be
+// used with [ld.WithProcessedContext].
+//
+// Contexts are processed once, on first use.
+func Processed(url string) (*ld.Context, error) {
+	fn, ok := processed()[url]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not bundled", ld.ErrLoadingRemoteContext, url)
+	}
+
+	return fn()
+}
+
+// WithProcessedContexts returns a [ld.ProcessorOption] that installs all
+// bundled contexts with [ld.WithProcessedContext].
+//
+// A processed context is only used when it's the first context of a document,
+// so this should be combined with [Loader] for the remaining ones.
+//
+// It panics if a bundled context fails to process.
+func WithProcessedContexts() ld.ProcessorOption {
+	return func(p *ld.Processor) {
+		for url := range URLs() {
+			pctx, err := Processed(url)
+			if err != nil {
+				panic(fmt.Sprintf("contexts: failed to process %s: %s", url, err))
+			}
+			ld.WithProcessedContext(url, pctx)(p)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package contexts_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/contexts"
+)
+
+func TestProcessed(t *testing.T) {
+	for url := range contexts.URLs() {
+		t.Run(url, func(t *testing.T) {
+			t.Parallel()
+
+			doc, ok := contexts.Get(url)
+			if !ok {
+				t.Fatal("expected the context to be bundled")
+			}
+
+			if doc.URL != url || len(doc.Context) == 0 {
+				t.Fatalf("unexpected document: %+v", doc)
+			}
+
+			if _, err := contexts.Processed(url); err != nil {
+				t.Fatalf("expected successful processing, got: %s", err)
+			}
+		})
+	}
+
+	if _, err := contexts.Processed("https://example.org/context"); !errors.Is(err, ld.ErrLoadingRemoteContext) {
+		t.Errorf("expected error: %v, got: %v", ld.ErrLoadingRemoteContext, err)
+	}
+}
+
+func TestLoader(t *testing.T) {
+	fallback := func(_ context.Context, url string) (ld.Document, error) {
+		return ld.Document{URL: url, Context: json.RawMessage(`{}`)}, nil
+	}
+
+	tests := []struct {
+		name     string
+		fallback ld.RemoteContextLoaderFunc
+		url      string
+		err      error
+	}{
+		{name: "bundled", url: contexts.ActivityStreams},
+		{name: "bundled with fallback", fallback: fallback, url: contexts.SecurityV1},
+		{name: "fallback", fallback: fallback, url: "https://example.org/context"},
+		{name: "no fallback", url: "https://example.org/context", err: ld.ErrLoadingRemoteContext},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := contexts.Loader(tc.fallback)(t.Context(), tc.url)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error: %v, got: %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected successful load, got: %s", err)
+			}
+
+			if doc.URL != tc.url {
+				t.Errorf("expected URL: %s, got: %s", tc.url, doc.URL)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		out  string
+	}{
+		{
+			name: "actor",
+			in: `{
+				"@context": ["https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"],
+				"id": "https://example.org/users/alice",
+				"type": "Person",
+				"publicKey": {
+					"id": "https://example.org/users/alice#main-key",
+					"owner": "https://example.org/users/alice",
+					"publicKeyPem": "-----BEGIN PUBLIC KEY-----"
+				}
+			}`,
+			out: `[{
+				"@id": "https://example.org/users/alice",
+				"@type": ["https://www.w3.org/ns/activitystreams#Person"],
+				"https://w3id.org/security#publicKey": [{
+					"@id": "https://example.org/users/alice#main-key",
+					"https://w3id.org/security#owner": [{"@id": "https://example.org/users/alice"}],
+					"https://w3id.org/security#publicKeyPem": [{"@value": "-----BEGIN PUBLIC KEY-----"}]
+				}]
+			}]`,
+		},
+		{
+			name: "multikey",
+			in: `{
+				"@context": ["https://www.w3.org/ns/activitystreams", "https://w3id.org/security/multikey/v1"],
+				"id": "https://example.org/users/alice",
+				"assertionMethod": {
+					"id": "https://example.org/users/alice#ed25519-key",
+					"type": "Multikey",
+					"controller": "https://example.org/users/alice",
+					"publicKeyMultibase": "z6Mk"
+				}
+			}`,
+			out: `[{
+				"@id": "https://example.org/users/alice",
+				"_:assertionMethod": [{
+					"@id": "https://example.org/users/alice#ed25519-key",
+					"@type": ["https://w3id.org/security#Multikey"],
+					"https://w3id.org/security#controller": [{"@id": "https://example.org/users/alice"}],
+					"https://w3id.org/security#publicKeyMultibase": [{"@type": "https://w3id.org/security#multibase", "@value": "z6Mk"}]
+				}]
+			}]`,
+		},
+		{
+			name: "credential",
+			in: `{
+				"@context": ["https://www.w3.org/ns/credentials/v2"],
+				"id": "https://example.org/credentials/1",
+				"type": ["VerifiableCredential"],
+				"issuer": "did:example:issuer",
+				"validFrom": "2025-01-01T00:00:00Z",
+				"credentialSubject": {"id": "did:example:subject"}
+			}`,
+			out: `[{
+				"@id": "https://example.org/credentials/1",
+				"@type": ["https://www.w3.org/2018/credentials#VerifiableCredential"],
+				"https://www.w3.org/2018/credentials#issuer": [{"@id": "did:example:issuer"}],
+				"https://www.w3.org/2018/credentials#validFrom": [{"@type": "http://www.w3.org/2001/XMLSchema#dateTime", "@value": "2025-01-01T00:00:00Z"}],
+				"https://www.w3.org/2018/credentials#credentialSubject": [{"@id": "did:example:subject"}]
+			}]`,
+		},
+	}
+
+	procs := map[string]*ld.Processor{
+		"loader": ld.NewProcessor(ld.WithRemoteContextLoader(contexts.Loader(nil))),
+		"processed": ld.NewProcessor(
+			ld.WithRemoteContextLoader(contexts.Loader(nil)),
+			contexts.WithProcessedContexts(),
+		),
+	}
+
+	for _, tc := range tests {
+		for name, proc := range procs {
+			t.Run(tc.name+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				nodes, err := proc.Expand(t.Context(), strings.NewReader(tc.in), "")
+				if err != nil {
+					t.Fatalf("expected successful expansion, got: %s", err)
+				}
+
+				got, err := json.Marshal(nodes)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var want, have any
+				if err := json.Unmarshal([]byte(tc.out), &want); err != nil {
+					t.Fatal(err)
+				}
+				if err := json.Unmarshal(got, &have); err != nil {
+					t.Fatal(err)
+				}
+
+				if diff := cmp.Diff(want, have); diff != "" {
+					t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,474 @@
+package longdistance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnmarshalError is returned by [Unmarshal] and [UnmarshalNode] when a value
+// can't be decoded into a struct field.
+type UnmarshalError struct {
+	// Path of Go fields leading to the value, for example
+	// "Attachment[1].Name".
+	Path string
+	// Property is the IRI or keyword of the property being decoded.
+	Property string
+	Err      error
+}
+
+func (e *UnmarshalError) Error() string {
+	if e.Path == "" {
+		return "ld: " + e.Err.Error()
+	}
+	return fmt.Sprintf("ld: %s (%s): %s", e.Path, e.Property, e.Err)
+}
+
+func (e *UnmarshalError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	nodeType       = reflect.TypeFor[Node]()
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// Unmarshal decodes a list of nodes in expanded document form, as returned
+// by [Processor.Expand], into v.
+//
+// If v is a pointer to a slice, every node is decoded into an element of the
+// slice. Otherwise nodes must hold a single node, which is decoded like
+// [UnmarshalNode].
+func Unmarshal(nodes []Node, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &UnmarshalError{Err: fmt.Errorf("non-pointer %T", v)}
+	}
+
+	rv = rv.Elem()
+	if rv.Type() == reflect.TypeFor[[]Node]() {
+		rv.Set(reflect.ValueOf(nodes))
+		return nil
+	}
+
+	if rv.Kind() == reflect.Slice {
+		res := reflect.MakeSlice(rv.Type(), len(nodes), len(nodes))
+		for i, node := range nodes {
+			if err := decodeNode(node, res.Index(i), fmt.Sprintf("[%d]", i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(res)
+		return nil
+	}
+
+	if len(nodes) != 1 {
+		return &UnmarshalError{Err: fmt.Errorf("expected a single node, got %d", len(nodes))}
+	}
+
+	return decodeNode(nodes[0], rv, "")
+}
+
+// UnmarshalNode decodes a node into v, which must be a pointer to a struct.
+//
+// Struct fields are mapped to properties using the ld struct tag, holding
+// the expanded IRI of the property:
+//
+//	type Note struct {
+//		ID      string    `ld:"@id"`
+//		Type    []string  `ld:"@type"`
+//		Content string    `ld:"https://www.w3.org/ns/activitystreams#content"`
+//		Tags    []Tag     `ld:"https://www.w3.org/ns/activitystreams#tag"`
+//		Created time.Time `ld:"https://www.w3.org/ns/activitystreams#published"`
+//	}
+//
+// The keywords @id, @type and @index can be used in place of an IRI. Fields
+// without an ld tag are ignored, as are fields tagged with "-". The fields of
+// an embedded struct are treated as if they were fields of the outer struct.
+// A nil pointer to an embedded struct is allocated, unless its type is
+// unexported which results in an error. The tag options described in
+// [MarshalNode] are accepted too. A field with lang=<tag> only considers
+// values with that language, and a field with type=@json is decoded from a
+// JSON literal using [encoding/json].
+//
+// Values are decoded based on the type of the field:
+//   - A string holds the value of a value object, or the @id of a node
+//     object. Numbers and booleans are stored in their JSON form.
+//   - Booleans, integers and floats are decoded from the value of a value
+//     object. String values, as used by typed literals like xsd:integer, are
+//     parsed.
+//   - A [time.Time] is parsed from a value in the xsd:dateTime or xsd:date
+//     format. A value without a timezone is in UTC.
+//   - A [json.RawMessage] holds the value of a value object, which is useful
+//     for @json literals. For node objects it holds the node in expanded
+//     document form.
+//   - A map[string]string is a language map. Each value is stored with its
+//     @language as the key, or the empty string if it has none.
+//   - A struct is decoded from a node object. If the node is a reference, only
+//     its @id is set.
+//   - A [Node] holds the node as-is, for when you need the full details.
+//   - A slice holds every value of the property. Any other type holds the
+//     first one. The items of an @list are decoded as values of the property.
+//   - A pointer is allocated when the property has a value, and left as-is
+//     otherwise.
+//
+// Properties of the node that have no corresponding field are ignored.
+// Errors are returned as an [UnmarshalError] holding the path to the field
+// that couldn't be decoded.
+func UnmarshalNode(node Node, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return &UnmarshalError{Err: fmt.Errorf("non-pointer %T", v)}
+	}
+
+	return decodeNode(node, rv.Elem(), "")
+}
+
+// decodeNode decodes a node into a struct.
+func decodeNode(node Node, rv reflect.Value, path string) error {
+	rv = allocate(rv)
+
+	if rv.Type() == nodeType {
+		rv.Set(reflect.ValueOf(node))
+		return nil
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return &UnmarshalError{Path: path, Err: fmt.Errorf("cannot decode a node into %s", rv.Type())}
+	}
+
+	fields, err := fieldsOf(rv.Type())
+	if err != nil {
+		return &UnmarshalError{Path: path, Err: err}
+	}
+
+	for _, f := range fields {
+		fpath := f.name
+		if path != "" {
+			fpath = path + "." + f.name
+		}
+
+		if err := decodeField(node, f, rv, fpath); err != nil {
+			var uerr *UnmarshalError
+			if errors.As(err, &uerr) {
+				return err
+			}
+			return &UnmarshalError{Path: fpath, Property: f.iri, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func decodeField(node Node, f field, rv reflect.Value, path string) error {
+	switch f.iri {
+	case KeywordID:
+		if node.ID == "" {
+			return nil
+		}
+		fv, err := fieldByIndex(rv, f.index)
+		if err != nil {
+			return err
+		}
+		return decodeString(node.ID, fv)
+	case KeywordIndex:
+		if node.Index == "" {
+			return nil
+		}
+		fv, err := fieldByIndex(rv, f.index)
+		if err != nil {
+			return err
+		}
+		return decodeString(node.Index, fv)
+	case KeywordType:
+		if len(node.Type) == 0 {
+			return nil
+		}
+		fv, err := fieldByIndex(rv, f.index)
+		if err != nil {
+			return err
+		}
+		return decodeStrings(node.Type, fv)
+	}
+
+	values, ok := node.Properties[f.iri]
+	if !ok {
+		return nil
+	}
+
+	// the items of a list are values of the property
+	flat := make([]Node, 0, len(values))
+	for _, v := range values {
+		if v.List != nil {
+			flat = append(flat, v.List...)
+			continue
+		}
+		flat = append(flat, v)
+	}
+
+	if f.language != "" {
+		flat = slices.DeleteFunc(flat, func(v Node) bool {
+			return !strings.EqualFold(v.Language, f.language)
+		})
+	}
+
+	fv, err := fieldByIndex(rv, f.index)
+	if err != nil {
+		return err
+	}
+
+	if f.datatype == KeywordJSON && len(flat) > 0 && allocate(fv).Type() != rawMessageType {
+		if flat[0].Value == nil {
+			return fmt.Errorf("cannot decode a node object into %s", fv.Type())
+		}
+		return json.Unmarshal(flat[0].Value, fv.Addr().Interface())
+	}
+
+	return decodeValues(flat, fv, path, f.iri)
+}
+
+// fieldByIndex is like [reflect.Value.FieldByIndex] but allocates nil
+// pointers to embedded structs.
+//
+// A nil pointer to an unexported struct type can't be allocated through
+// reflection, so that's an error, like it is for encoding/json.
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				if !rv.CanSet() {
+					return reflect.Value{}, fmt.Errorf("cannot set embedded pointer to unexported struct: %s", rv.Type().Elem())
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+func decodeString(s string, rv reflect.Value) error {
+	rv = allocate(rv)
+	if rv.Kind() != reflect.String {
+		return fmt.Errorf("cannot decode a string into %s", rv.Type())
+	}
+
+	rv.SetString(s)
+	return nil
+}
+
+func decodeStrings(s []string, rv reflect.Value) error {
+	rv = allocate(rv)
+	if rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.String {
+		res := reflect.MakeSlice(rv.Type(), len(s), len(s))
+		for i, v := range s {
+			res.Index(i).SetString(v)
+		}
+		rv.Set(res)
+		return nil
+	}
+
+	return decodeString(s[0], rv)
+}
+
+// allocate allocates nil pointers and returns the value they point to.
+func allocate(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// decodeValues decodes the values of a property into a field.
+func decodeValues(values []Node, rv reflect.Value, path, property string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	rv = allocate(rv)
+	typ := rv.Type()
+
+	switch {
+	case typ == rawMessageType:
+		return decodeValue(values[0], rv, path)
+	case typ.Kind() == reflect.Slice && typ.Elem() == nodeType:
+		rv.Set(reflect.ValueOf(values))
+		return nil
+	case typ.Kind() == reflect.Slice:
+		res := reflect.MakeSlice(typ, len(values), len(values))
+		for i, v := range values {
+			ipath := fmt.Sprintf("%s[%d]", path, i)
+			if err := decodeValue(v, res.Index(i), ipath); err != nil {
+				var uerr *UnmarshalError
+				if errors.As(err, &uerr) {
+					return err
+				}
+				return &UnmarshalError{Path: ipath, Property: property, Err: err}
+			}
+		}
+		rv.Set(res)
+		return nil
+	case typ.Kind() == reflect.Map:
+		return decodeLanguageMap(values, rv)
+	default:
+		return decodeValue(values[0], rv, path)
+	}
+}
+
+// decodeValue decodes a single value object or node object.
+func decodeValue(value Node, rv reflect.Value, path string) error {
+	rv = allocate(rv)
+	typ := rv.Type()
+
+	switch {
+	case typ == nodeType:
+		rv.Set(reflect.ValueOf(value))
+		return nil
+	case typ == rawMessageType:
+		if value.Value != nil {
+			rv.SetBytes(value.Value)
+			return nil
+		}
+		data, err := json.Marshal(&value)
+		if err != nil {
+			return err
+		}
+		rv.SetBytes(data)
+		return nil
+	case typ == timeType:
+		return decodeTime(value, rv)
+	case typ.Kind() == reflect.Struct:
+		if value.Value != nil {
+			return fmt.Errorf("cannot decode a value object into %s", typ)
+		}
+		return decodeNode(value, rv, path)
+	}
+
+	if value.Value == nil {
+		if typ.Kind() == reflect.String && value.ID != "" {
+			rv.SetString(value.ID)
+			return nil
+		}
+		return fmt.Errorf("cannot decode a node object into %s", typ)
+	}
+
+	text, isString := valueText(value.Value)
+
+	switch typ.Kind() {
+	case reflect.String:
+		rv.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, typ.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(text, typ.Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(n)
+	default:
+		if isString {
+			return fmt.Errorf("cannot decode a string into %s", typ)
+		}
+		return fmt.Errorf("cannot decode %s into %s", value.Value, typ)
+	}
+
+	return nil
+}
+
+// valueText returns the text of a JSON scalar. Strings are unquoted, other
+// values are returned in their JSON form.
+func valueText(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+	return strings.TrimSpace(string(raw)), false
+}
+
+func decodeTime(value Node, rv reflect.Value) error {
+	if value.Value == nil {
+		return fmt.Errorf("cannot decode a node object into %s", rv.Type())
+	}
+
+	text, _ := valueText(value.Value)
+
+	// the error for the full xsd:dateTime format is the most useful one
+	var first error
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, text)
+		if err == nil {
+			rv.Set(reflect.ValueOf(t))
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// timeLayouts are the layouts a [time.Time] is parsed with, in order. The
+// timezone of an xsd:dateTime or xsd:date is optional, without one it's UTC.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	time.DateOnly,
+	"2006-01-02Z07:00",
+}
+
+// decodeLanguageMap decodes values into a map of language to string.
+func decodeLanguageMap(values []Node, rv reflect.Value) error {
+	typ := rv.Type()
+	if typ.Key().Kind() != reflect.String || typ.Elem().Kind() != reflect.String {
+		return fmt.Errorf("cannot decode into %s, only map[string]string is supported", typ)
+	}
+
+	if rv.IsNil() {
+		rv.Set(reflect.MakeMapWithSize(typ, len(values)))
+	}
+
+	for _, v := range values {
+		if v.Value == nil {
+			return fmt.Errorf("cannot decode a node object into %s", typ)
+		}
+
+		text, isString := valueText(v.Value)
+		if !isString {
+			return fmt.Errorf("cannot decode %s into %s", v.Value, typ)
+		}
+
+		rv.SetMapIndex(
+			reflect.ValueOf(v.Language).Convert(typ.Key()),
+			reflect.ValueOf(text).Convert(typ.Elem()),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,323 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+type testCollection struct {
+	ID         string `ld:"@id"`
+	TotalItems uint64 `ld:"https://www.w3.org/ns/activitystreams#totalItems"`
+}
+
+type testObject struct {
+	ID   string `ld:"@id"`
+	Type string `ld:"@type"`
+}
+
+type testNote struct {
+	testObject
+
+	Summary   string            `ld:"https://www.w3.org/ns/activitystreams#summary"`
+	Content   map[string]string `ld:"https://www.w3.org/ns/activitystreams#content"`
+	Sensitive bool              `ld:"https://www.w3.org/ns/activitystreams#sensitive"`
+	Published time.Time         `ld:"https://www.w3.org/ns/activitystreams#published"`
+	To        []string          `ld:"https://www.w3.org/ns/activitystreams#to"`
+	Likes     testCollection    `ld:"https://www.w3.org/ns/activitystreams#likes"`
+	Replies   *testCollection   `ld:"https://www.w3.org/ns/activitystreams#replies"`
+	InReplyTo *testObject       `ld:"https://www.w3.org/ns/activitystreams#inReplyTo"`
+	Ignored   string
+}
+
+type testCreate struct {
+	ID        string    `ld:"@id"`
+	Type      []string  `ld:"@type"`
+	Actor     string    `ld:"https://www.w3.org/ns/activitystreams#actor"`
+	Published time.Time `ld:"https://www.w3.org/ns/activitystreams#published"`
+	Object    *testNote `ld:"https://www.w3.org/ns/activitystreams#object"`
+}
+
+type testLinked struct {
+	*testLinked
+
+	ID string `ld:"@id"`
+}
+
+// RecursivePerson and RecursiveAccount are exported, as decoding into a nil
+// pointer to an embedded struct needs to allocate it.
+type RecursivePerson struct {
+	*RecursiveAccount
+
+	Name string `ld:"https://example.org/name"`
+}
+
+type RecursiveAccount struct {
+	*RecursivePerson
+
+	ID string `ld:"@id"`
+}
+
+// testHidden is embedded as a pointer in testOuter. Being unexported, a nil
+// pointer to it can't be allocated through reflection.
+type testHidden struct {
+	Name  string          `ld:"https://example.org/name"`
+	Data  json.RawMessage `ld:"https://example.org/data,type=@json"`
+	Other ld.Node         `ld:"https://example.org/other"`
+}
+
+type testOuter struct {
+	*testHidden
+	ID string `ld:"@id"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	p := ld.NewProcessor(
+		ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+	)
+
+	nodes, err := p.Expand(t.Context(), bytes.NewReader(LoadData(t, "observatory/createnote.json")), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got testCreate
+	if err := ld.Unmarshal(nodes, &got); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	published := time.Date(2020, 12, 31, 23, 0, 0, 0, time.UTC)
+	want := testCreate{
+		ID:        "https://example.com/create/1",
+		Type:      []string{"https://www.w3.org/ns/activitystreams#Create"},
+		Actor:     "https://example.com/actor/1",
+		Published: published,
+		Object: &testNote{
+			testObject: testObject{
+				ID:   "https://example.com/object/1",
+				Type: "https://www.w3.org/ns/activitystreams#Note",
+			},
+			Summary: "A summary",
+			Content: map[string]string{
+				"":   "The content of the message",
+				"en": "The content of the message",
+			},
+			Published: published,
+			To:        []string{"https://www.w3.org/ns/activitystreams#Public"},
+			Likes:     testCollection{ID: "https://example.com/object/1/likes", TotalItems: 5},
+			Replies:   &testCollection{ID: "https://example.com/object/1/replies"},
+		},
+	}
+
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(testNote{})); diff != "" {
+		t.Errorf("decoding mismatch (-want +got):\n%s", diff)
+	}
+
+	var all []testCreate
+	if err := ld.Unmarshal(nodes, &all); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if len(all) != 1 || all[0].ID != want.ID {
+		t.Errorf("expected a single activity, got: %+v", all)
+	}
+}
+
+func TestUnmarshalValues(t *testing.T) {
+	type values struct {
+		Count   int             `ld:"https://example.org/count"`
+		Big     int64           `ld:"https://example.org/big"`
+		Ratio   float64         `ld:"https://example.org/ratio"`
+		Flag    *bool           `ld:"https://example.org/flag"`
+		Date    time.Time       `ld:"https://example.org/date"`
+		Local   time.Time       `ld:"https://example.org/local"`
+		UTCDate time.Time       `ld:"https://example.org/utcDate"`
+		Zoned   time.Time       `ld:"https://example.org/zoned"`
+		Data    json.RawMessage `ld:"https://example.org/data"`
+		Items   []string        `ld:"https://example.org/items"`
+		Ref     string          `ld:"https://example.org/ref"`
+		Raw     ld.Node         `ld:"https://example.org/ref"`
+		Missing *string         `ld:"https://example.org/missing"`
+		Index   string          `ld:"@index"`
+	}
+
+	doc := []byte(`{
+		"@context": {
+			"@vocab": "https://example.org/",
+			"xsd": "http://www.w3.org/2001/XMLSchema#",
+			"big": {"@type": "xsd:integer"},
+			"date": {"@type": "xsd:date"},
+			"local": {"@type": "xsd:dateTime"},
+			"utcDate": {"@type": "xsd:date"},
+			"zoned": {"@type": "xsd:date"},
+			"data": {"@type": "@json"},
+			"items": {"@container": "@list"},
+			"ref": {"@type": "@id"}
+		},
+		"@index": "first",
+		"count": 3,
+		"big": "9007199254740993",
+		"ratio": 0.5,
+		"flag": true,
+		"date": "2024-02-29",
+		"local": "2024-02-29T13:45:30.5",
+		"utcDate": "2024-02-29Z",
+		"zoned": "2024-02-29-05:00",
+		"data": {"b": [1, 2], "a": null},
+		"items": ["one", "two", "three"],
+		"ref": "https://example.org/other"
+	}`)
+
+	nodes, err := ld.NewProcessor().Expand(t.Context(), bytes.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got values
+	if err := ld.Unmarshal(nodes, &got); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	flag := true
+	want := values{
+		Count:   3,
+		Big:     9007199254740993,
+		Ratio:   0.5,
+		Flag:    &flag,
+		Date:    time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		Local:   time.Date(2024, 2, 29, 13, 45, 30, 500_000_000, time.UTC),
+		UTCDate: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		Zoned:   time.Date(2024, 2, 29, 0, 0, 0, 0, time.FixedZone("", -5*60*60)),
+		Data:    json.RawMessage(`{"a":null,"b":[1,2]}`),
+		Items:   []string{"one", "two", "three"},
+		Ref:     "https://example.org/other",
+		Raw:     ld.Node{ID: "https://example.org/other"},
+		Index:   "first",
+	}
+
+	if diff := cmp.Diff(want, got, JSONDiff()); diff != "" {
+		t.Errorf("decoding mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestUnmarshalRecursiveEmbedding checks that structs embedding themselves,
+// directly or through another struct, are decoded like encoding/json does.
+func TestUnmarshalRecursiveEmbedding(t *testing.T) {
+	nodes := []ld.Node{{
+		ID: "https://example.org/1",
+		Properties: ld.Properties{
+			"https://example.org/name": {{Value: json.RawMessage(`"Alice"`)}},
+		},
+	}}
+
+	var linked testLinked
+	if err := ld.Unmarshal(nodes, &linked); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if linked.ID != "https://example.org/1" || linked.testLinked != nil {
+		t.Errorf("unexpected result: %+v", linked)
+	}
+
+	var person RecursivePerson
+	if err := ld.Unmarshal(nodes, &person); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if person.Name != "Alice" || person.RecursiveAccount == nil || person.ID != "https://example.org/1" {
+		t.Errorf("unexpected result: %+v", person)
+	}
+}
+
+// TestUnmarshalEmbeddedUnexportedPointer checks that a nil pointer to an
+// unexported struct is reported as an error, like encoding/json does, instead
+// of panicking.
+func TestUnmarshalEmbeddedUnexportedPointer(t *testing.T) {
+	node := ld.Node{
+		ID: "https://example.org/1",
+		Properties: ld.Properties{
+			"https://example.org/name": {{Value: json.RawMessage(`"Alice"`)}},
+		},
+	}
+
+	var outer testOuter
+	err := ld.UnmarshalNode(node, &outer)
+
+	var uerr *ld.UnmarshalError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected an unmarshal error, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "unexported struct") {
+		t.Errorf("expected the error to mention the unexported struct, got: %s", err)
+	}
+
+	// once allocated, the fields can be set
+	outer = testOuter{testHidden: &testHidden{}}
+	if err := ld.UnmarshalNode(node, &outer); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if outer.ID != "https://example.org/1" || outer.Name != "Alice" {
+		t.Errorf("unexpected result: %+v", outer)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	type tag struct {
+		Name int `ld:"https://example.org/name"`
+	}
+
+	type note struct {
+		Tags []tag `ld:"https://example.org/tag"`
+	}
+
+	nodes := []ld.Node{{
+		Properties: ld.Properties{
+			"https://example.org/tag": {
+				{Properties: ld.Properties{"https://example.org/name": {{Value: json.RawMessage(`1`)}}}},
+				{Properties: ld.Properties{"https://example.org/name": {{Value: json.RawMessage(`"one"`)}}}},
+			},
+		},
+	}}
+
+	var got note
+	err := ld.Unmarshal(nodes, &got)
+
+	var uerr *ld.UnmarshalError
+	if !errors.As(err, &uerr) {
+		t.Fatalf("expected an UnmarshalError, got: %v", err)
+	}
+
+	if uerr.Path != "Tags[1].Name" || uerr.Property != "https://example.org/name" {
+		t.Errorf("unexpected error location: %s (%s)", uerr.Path, uerr.Property)
+	}
+
+	tests := []struct {
+		name string
+		v    any
+	}{
+		{name: "not a pointer", v: note{}},
+		{name: "multiple nodes", v: &note{}},
+		{name: "invalid tag", v: &struct {
+			Graph []ld.Node `ld:"@graph"`
+		}{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ld.Unmarshal([]ld.Node{{}, {}}, tc.v)
+			if !errors.As(err, &uerr) {
+				t.Fatalf("expected an UnmarshalError, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package longdistance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// DefaultDiskCacheMaxAge is the default for [WithDiskCacheMaxAge].
+const DefaultDiskCacheMaxAge = 24 * time.Hour
+
+// DefaultDiskCacheRevalidateTimeout is the default for
+// [WithDiskCacheRevalidateTimeout].
+const DefaultDiskCacheRevalidateTimeout = 30 * time.Second
+
+// DiskCacheOption can be used to configure a [DiskCache].
+type DiskCacheOption func(*DiskCache)
+
+// WithDiskCacheMaxAge sets how long a context is considered fresh when the
+// response it was retrieved with has no max-age directive.
+//
+// Defaults to [DefaultDiskCacheMaxAge].
+func WithDiskCacheMaxAge(d time.Duration) DiskCacheOption {
+	return func(c *DiskCache) {
+		c.maxAge = d
+	}
+}
+
+// WithDiskCacheRevalidateTimeout limits how long a background revalidation
+// may take. It can't be cancelled by the request that started it, so this
+// ensures it doesn't run forever when the origin doesn't respond.
+//
+// Defaults to [DefaultDiskCacheRevalidateTimeout].
+func WithDiskCacheRevalidateTimeout(d time.Duration) DiskCacheOption {
+	return func(c *DiskCache) {
+		c.revalidateTimeout = d
+	}
+}
+
+// WithDiskCacheLogger sets the logger used to report failures to revalidate
+// or persist a context.
+//
+// Defaults to a logger using [slog.DiscardHandler].
+func WithDiskCacheLogger(l *slog.Logger) DiskCacheOption {
+	return func(c *DiskCache) {
+		c.logger = l
+	}
+}
+
+// DiskCache persists retrieved contexts to a directory, so they survive
+// restarts of the application.
+//
+// A context is fresh for the duration of the max-age directive of the
+// Cache-Control header it was retrieved with, or [WithDiskCacheMaxAge] if
+// absent. Responses with no-store are not persisted.
+//
+// Stale contexts are returned as-is, while they're revalidated in the
+// background using [WithConditionalRequest]. If revalidation fails, for
+// example because the origin is unreachable, the stale context remains in
+// use.
+//
+// Contexts retrieved with no-cache are revalidated before every use instead,
+// as RFC 9111 requires. If the origin can't be reached, the cached context is
+// served stale. Any other error, like a 404 or 410 response, is returned
+// rather than the cached context.
+type DiskCache struct {
+	dir               string
+	maxAge            time.Duration
+	revalidateTimeout time.Duration
+	logger            *slog.Logger
+
+	mu           sync.Mutex
+	revalidating map[string]struct{}
+	wg           sync.WaitGroup
+}
+
+// NewDiskCache returns a [DiskCache] storing contexts in dir. The directory
+// is created if it doesn't exist.
+func NewDiskCache(dir string, opts ...DiskCacheOption) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	c := &DiskCache{
+		dir:               dir,
+		maxAge:            DefaultDiskCacheMaxAge,
+		revalidateTimeout: DefaultDiskCacheRevalidateTimeout,
+		logger:            slog.New(slog.DiscardHandler),
+		revalidating:      make(map[string]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Wrap returns a [RemoteContextLoaderFunc] that serves contexts from the
+// cache, and retrieves them with next when they're missing or stale.
+//
+// It can be used as a [LoaderMiddleware].
+func (c *DiskCache) Wrap(next RemoteContextLoaderFunc) RemoteContextLoaderFunc {
+	return func(ctx context.Context, url string) (Document, error) {
+		entry, ok := c.read(url)
+		if !ok {
+			doc, err := next(ctx, url)
+			if err != nil {
+				return Document{}, err
+			}
+
+			c.write(url, doc)
+			return doc, nil
+		}
+
+		if _, _, noCache := parseCacheControl(entry.CacheControl, c.maxAge); noCache {
+			doc, err := c.fetch(ctx, next, url, entry)
+			if err != nil && unreachable(ctx, err) {
+				c.logger.Warn("failed to revalidate context, serving stale",
+					slog.String("url", url), slog.Any("error", err))
+				return entry.document(), nil
+			}
+			return doc, err
+		}
+
+		if time.Now().After(entry.Expires) {
+			c.revalidate(ctx, next, url, entry)
+		}
+
+		return entry.document(), nil
+	}
+}
+
+// Wait waits for all background revalidations to finish.
+func (c *DiskCache) Wait() {
+	c.wg.Wait()
+}
+
+// diskCacheEntry is the representation of a context on disk.
+type diskCacheEntry struct {
+	URL          string          `json:"url"`
+	DocumentURL  string          `json:"documentURL"`
+	Context      json.RawMessage `json:"context"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"lastModified,omitempty"`
+	CacheControl string          `json:"cacheControl,omitempty"`
+	Expires      time.Time       `json:"expires"`
+}
+
+func (e diskCacheEntry) document() Document {
+	return Document{
+		URL:     e.DocumentURL,
+		Context: e.Context,
+		Cache: CacheInfo{
+			ETag:         e.ETag,
+			LastModified: e.LastModified,
+			CacheControl: e.CacheControl,
+		},
+	}
+}
+
+func (c *DiskCache) path(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *DiskCache) read(url string) (diskCacheEntry, bool) {
+	data, err := os.ReadFile(c.path(url))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			c.logger.Warn("failed to read cached context",
+				slog.String("url", url), slog.Any("error", err))
+		}
+		return diskCacheEntry{}, false
+	}
+
+	var entry diskCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.URL != url {
+		c.logger.Warn("ignoring invalid cached context", slog.String("url", url))
+		return diskCacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+// write persists the document, unless the response forbids it.
+func (c *DiskCache) write(url string, doc Document) {
+	maxAge, noStore, _ := parseCacheControl(doc.Cache.CacheControl, c.maxAge)
+	if noStore {
+		return
+	}
+
+	entry := diskCacheEntry{
+		URL:          url,
+		DocumentURL:  doc.URL,
+		Context:      doc.Context,
+		ETag:         doc.Cache.ETag,
+		LastModified: doc.Cache.LastModified,
+		CacheControl: doc.Cache.CacheControl,
+		Expires:      time.Now().Add(maxAge),
+	}
+
+	if err := c.store(entry); err != nil {
+		c.logger.Warn("failed to persist context",
+			slog.String("url", url), slog.Any("error", err))
+	}
+}
+
+// store atomically replaces the entry on disk.
+func (c *DiskCache) store(entry diskCacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), c.path(entry.URL))
+}
+
+// revalidate starts a background revalidation of the entry, unless one is
+// already in progress.
+func (c *DiskCache) revalidate(
+	ctx context.Context,
+	next RemoteContextLoaderFunc,
+	url string,
+	entry diskCacheEntry,
+) {
+	c.mu.Lock()
+	if _, ok := c.revalidating[url]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[url] = struct{}{}
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	// the revalidation outlives the request that triggered it
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.revalidateTimeout)
+
+	go func() {
+		defer func() {
+			cancel()
+			c.mu.Lock()
+			delete(c.revalidating, url)
+			c.mu.Unlock()
+			c.wg.Done()
+		}()
+
+		if _, err := c.fetch(ctx, next, url, entry); err != nil {
+			c.logger.Warn("failed to revalidate context, serving stale",
+				slog.String("url", url), slog.Any("error", err))
+		}
+	}()
+}
+
+// fetch revalidates the entry with next and persists the result.
+func (c *DiskCache) fetch(
+	ctx context.Context,
+	next RemoteContextLoaderFunc,
+	url string,
+	entry diskCacheEntry,
+) (Document, error) {
+	doc, err := next(WithConditionalRequest(ctx, entry.document().Cache), url)
+	if err != nil {
+		return Document{}, err
+	}
+
+	if doc.Cache.NotModified {
+		doc.URL = entry.DocumentURL
+		doc.Context = entry.Context
+	}
+
+	c.write(url, doc)
+	return doc, nil
+}
+
+// unreachable reports whether err means the origin couldn't be reached, as
+// opposed to the origin responding with an error or the caller giving up.
+func unreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// parseCacheControl returns how long a response is fresh for, whether it may
+// be stored at all, and whether it must be revalidated before every use.
+func parseCacheControl(value string, fallback time.Duration) (time.Duration, bool, bool) {
+	maxAge := fallback
+	noCache := false
+
+	for directive := range strings.SplitSeq(value, ",") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+
+		switch strings.ToLower(name) {
+		case "no-store":
+			return 0, true, false
+		case "no-cache":
+			noCache = true
+		case "max-age":
+			secs, err := strconv.ParseInt(strings.Trim(arg, `"`), 10, 64)
+			if err != nil || secs < 0 {
+				continue
+			}
+			maxAge = time.Duration(min(secs, maxCacheAge)) * time.Second
+		}
+	}
+
+	if noCache {
+		return 0, false, true
+	}
+
+	return maxAge, false, false
+}
+
+// maxCacheAge caps max-age, in seconds, to avoid overflowing a
+// [time.Duration].
+const maxCacheAge = 100 * 365 * 24 * 60 * 60
@@ -0,0 +1,372 @@
+package longdistance_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// contextServer serves a context with an ETag, honouring If-None-Match.
+type contextServer struct {
+	mu           sync.Mutex
+	version      int
+	cacheControl string
+	hits         int
+	conditional  int
+}
+
+func (s *contextServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.hits++
+	etag := fmt.Sprintf(`"v%d"`, s.version)
+
+	w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", s.cacheControl)
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		s.conditional++
+		if inm == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	fmt.Fprintf(w, `{"@context": {"name": "https://example.org/v%d#name"}}`, s.version)
+}
+
+func (s *contextServer) set(version int, cacheControl string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version = version
+	s.cacheControl = cacheControl
+}
+
+func (s *contextServer) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits, s.conditional
+}
+
+func failingLoader(_ context.Context, url string) (ld.Document, error) {
+	return ld.Document{}, fmt.Errorf("%w: offline", ld.ErrLoadingRemoteContext)
+}
+
+func contextFor(version int) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"name": "https://example.org/v%d#name"}`, version))
+}
+
+func TestDiskCache(t *testing.T) {
+	t.Run("persisted across restarts", func(t *testing.T) {
+		srv := &contextServer{cacheControl: "max-age=3600"}
+		ts := httptest.NewServer(srv)
+		t.Cleanup(ts.Close)
+
+		dir := t.TempDir()
+		cache, err := ld.NewDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loader := cache.Wrap(ld.NewHTTPContextLoader(ts.Client()))
+		if _, err := loader(t.Context(), ts.URL); err != nil {
+			t.Fatalf("expected successful load, got: %s", err)
+		}
+
+		restarted, err := ld.NewDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		doc, err := restarted.Wrap(failingLoader)(t.Context(), ts.URL)
+		if err != nil {
+			t.Fatalf("expected context from disk, got: %s", err)
+		}
+
+		want := ld.Document{
+			URL:     ts.URL,
+			Context: contextFor(0),
+			Cache:   ld.CacheInfo{ETag: `"v0"`, CacheControl: "max-age=3600"},
+		}
+		if diff := cmp.Diff(want, doc, JSONDiff()); diff != "" {
+			t.Errorf("document mismatch (-want +got):\n%s", diff)
+		}
+
+		if hits, _ := srv.counts(); hits != 1 {
+			t.Errorf("expected 1 request, got: %d", hits)
+		}
+	})
+
+	t.Run("revalidated when not modified", func(t *testing.T) {
+		srv := &contextServer{cacheControl: "no-cache"}
+		ts := httptest.NewServer(srv)
+		t.Cleanup(ts.Close)
+
+		cache, err := ld.NewDiskCache(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loader := cache.Wrap(ld.NewHTTPContextLoader(ts.Client()))
+		loader(t.Context(), ts.URL)
+
+		srv.set(0, "max-age=3600")
+
+		doc, err := loader(t.Context(), ts.URL)
+		if err != nil {
+			t.Fatalf("expected stale context, got: %s", err)
+		}
+		cache.Wait()
+
+		if diff := cmp.Diff(contextFor(0), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("context mismatch (-want +got):\n%s", diff)
+		}
+
+		// now fresh, so no more requests
+		loader(t.Context(), ts.URL)
+		cache.Wait()
+
+		if hits, conditional := srv.counts(); hits != 2 || conditional != 1 {
+			t.Errorf("expected 2 requests of which 1 conditional, got: %d, %d", hits, conditional)
+		}
+	})
+
+	t.Run("no-cache revalidated before use", func(t *testing.T) {
+		srv := &contextServer{cacheControl: "no-cache"}
+		ts := httptest.NewServer(srv)
+		t.Cleanup(ts.Close)
+
+		cache, err := ld.NewDiskCache(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loader := cache.Wrap(ld.NewHTTPContextLoader(ts.Client()))
+		loader(t.Context(), ts.URL)
+
+		srv.set(1, "no-cache")
+
+		doc, err := loader(t.Context(), ts.URL)
+		if err != nil {
+			t.Fatalf("expected successful load, got: %s", err)
+		}
+
+		if diff := cmp.Diff(contextFor(1), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("expected updated context (-want +got):\n%s", diff)
+		}
+
+		ts.Close()
+
+		doc, err = loader(t.Context(), ts.URL)
+		if err != nil {
+			t.Fatalf("expected stale context when the origin is unreachable, got: %s", err)
+		}
+
+		if diff := cmp.Diff(contextFor(1), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("expected stale context (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("no-cache revalidation errors", func(t *testing.T) {
+		// seed returns a cache holding a context for url that's retrieved
+		// with no-cache
+		seed := func(t *testing.T, url string) *ld.DiskCache {
+			cache, err := ld.NewDiskCache(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cache.Wrap(func(_ context.Context, url string) (ld.Document, error) {
+				return ld.Document{
+					URL:     url,
+					Context: contextFor(0),
+					Cache:   ld.CacheInfo{ETag: `"v0"`, CacheControl: "no-cache"},
+				}, nil
+			})(t.Context(), url)
+
+			return cache
+		}
+
+		t.Run("network error", func(t *testing.T) {
+			loader := func(_ context.Context, _ string) (ld.Document, error) {
+				return ld.Document{}, fmt.Errorf("%w: %w", ld.ErrLoadingRemoteContext,
+					&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
+			}
+
+			doc, err := seed(t, "https://example.org/context").Wrap(loader)(t.Context(), "https://example.org/context")
+			if err != nil {
+				t.Fatalf("expected stale context, got: %s", err)
+			}
+
+			if diff := cmp.Diff(contextFor(0), doc.Context, JSONDiff()); diff != "" {
+				t.Errorf("context mismatch (-want +got):\n%s", diff)
+			}
+		})
+
+		t.Run("other error", func(t *testing.T) {
+			if _, err := seed(t, "https://example.org/context").Wrap(failingLoader)(t.Context(), "https://example.org/context"); !errors.Is(err, ld.ErrLoadingRemoteContext) {
+				t.Fatalf("expected error: %v, got: %v", ld.ErrLoadingRemoteContext, err)
+			}
+		})
+
+		for _, status := range []int{http.StatusNotFound, http.StatusGone} {
+			t.Run(http.StatusText(status), func(t *testing.T) {
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					w.WriteHeader(status)
+				}))
+				t.Cleanup(ts.Close)
+
+				if _, err := seed(t, ts.URL).Wrap(ld.NewHTTPContextLoader(ts.Client()))(t.Context(), ts.URL); !errors.Is(err, ld.ErrLoadingRemoteContext) {
+					t.Fatalf("expected error: %v, got: %v", ld.ErrLoadingRemoteContext, err)
+				}
+			})
+		}
+	})
+
+	t.Run("revalidated when modified", func(t *testing.T) {
+		srv := &contextServer{cacheControl: "max-age=0"}
+		ts := httptest.NewServer(srv)
+		t.Cleanup(ts.Close)
+
+		cache, err := ld.NewDiskCache(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loader := cache.Wrap(ld.NewHTTPContextLoader(ts.Client()))
+		loader(t.Context(), ts.URL)
+
+		srv.set(1, "max-age=3600")
+
+		doc, _ := loader(t.Context(), ts.URL)
+		cache.Wait()
+
+		if diff := cmp.Diff(contextFor(0), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("expected stale context (-want +got):\n%s", diff)
+		}
+
+		doc, _ = loader(t.Context(), ts.URL)
+		if diff := cmp.Diff(contextFor(1), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("expected updated context (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("stale when origin unreachable", func(t *testing.T) {
+		srv := &contextServer{cacheControl: "max-age=0"}
+		ts := httptest.NewServer(srv)
+
+		cache, err := ld.NewDiskCache(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		loader := cache.Wrap(ld.NewHTTPContextLoader(ts.Client()))
+		loader(t.Context(), ts.URL)
+		ts.Close()
+
+		for range 2 {
+			doc, err := loader(t.Context(), ts.URL)
+			if err != nil {
+				t.Fatalf("expected stale context, got: %s", err)
+			}
+			cache.Wait()
+
+			if diff := cmp.Diff(contextFor(0), doc.Context, JSONDiff()); diff != "" {
+				t.Errorf("context mismatch (-want +got):\n%s", diff)
+			}
+		}
+	})
+
+	t.Run("revalidation times out", func(t *testing.T) {
+		calls := 0
+		loader := func(ctx context.Context, url string) (ld.Document, error) {
+			calls++
+			if calls == 1 {
+				return ld.Document{URL: url, Context: contextFor(0)}, nil
+			}
+			<-ctx.Done()
+			return ld.Document{}, ctx.Err()
+		}
+
+		cache, err := ld.NewDiskCache(t.TempDir(),
+			ld.WithDiskCacheMaxAge(0),
+			ld.WithDiskCacheRevalidateTimeout(10*time.Millisecond),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wrapped := cache.Wrap(loader)
+		wrapped(t.Context(), "https://example.org/context")
+
+		doc, err := wrapped(t.Context(), "https://example.org/context")
+		if err != nil {
+			t.Fatalf("expected stale context, got: %s", err)
+		}
+		cache.Wait()
+
+		if diff := cmp.Diff(contextFor(0), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("context mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("not persisted with no-store", func(t *testing.T) {
+		srv := &contextServer{cacheControl: "no-store"}
+		ts := httptest.NewServer(srv)
+		t.Cleanup(ts.Close)
+
+		dir := t.TempDir()
+		cache, err := ld.NewDiskCache(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := cache.Wrap(ld.NewHTTPContextLoader(ts.Client()))(t.Context(), ts.URL); err != nil {
+			t.Fatalf("expected successful load, got: %s", err)
+		}
+
+		if _, err := cache.Wrap(failingLoader)(t.Context(), ts.URL); !errors.Is(err, ld.ErrLoadingRemoteContext) {
+			t.Fatalf("expected error: %v, got: %v", ld.ErrLoadingRemoteContext, err)
+		}
+	})
+
+	t.Run("any loader", func(t *testing.T) {
+		version := 0
+		loader := func(_ context.Context, url string) (ld.Document, error) {
+			version++
+			return ld.Document{URL: url, Context: contextFor(version)}, nil
+		}
+
+		cache, err := ld.NewDiskCache(t.TempDir(), ld.WithDiskCacheMaxAge(0))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wrapped := ld.WrapLoader(loader, cache.Wrap)
+		wrapped(t.Context(), "https://example.org/context")
+		wrapped(t.Context(), "https://example.org/context")
+		cache.Wait()
+
+		doc, err := wrapped(t.Context(), "https://example.org/context")
+		if err != nil {
+			t.Fatalf("expected successful load, got: %s", err)
+		}
+		cache.Wait()
+
+		if diff := cmp.Diff(contextFor(2), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("context mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
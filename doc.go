@@ -4,19 +4,50 @@
 // [Processor.Expand]. This will transform the document into a list of [Node].
 // Each node has dedicated fields for each JSON-LD keyword, and the catch-all
 // [Node.Properties] for everything else. If you serialise this document to JSON
-// you'll get JSON-LD Expanded Document form.
+// you'll get JSON-LD Expanded Document form. For large documents, like
+// collection exports, [Processor.ExpandSeq] yields the nodes one at a time
+// instead.
 //
 // By calling [Processor.Compact] you can compact a list of [Node] to what looks
 // like regular JSON, based on the provided compaction context. The result is
-// serialised JSON that you can send out.
+// serialised JSON that you can send out. When you compact many documents with
+// the same context, prepare it once with [Processor.PrepareContext] and use
+// [Processor.CompactWithContext] instead.
+//
+// A [Processor] is configured once and meant to be reused. When a call needs
+// different settings, like the base IRI of the request being handled, derive
+// one with [Processor.With]. It shares the caches of the processor it's
+// derived from.
+//
+// With [Processor.Flatten] all nested nodes are hoisted to the top level and
+// referenced by their @id instead. Nodes that share an @id are merged. The
+// result can optionally be compacted too.
+//
+// [Processor.Frame] reshapes a list of [Node] into a tree based on a frame.
+// The frame selects the nodes of interest and describes which of the nodes
+// they reference to embed. The result is compacted using the @context of the
+// frame.
+//
+// A list of [Node] can be turned into an RDF [Dataset] with [Processor.ToRDF].
+// [Processor.FromRDF] does the reverse, turning a [Dataset] into a list of
+// [Node] in expanded document form.
 //
 // By default a [Processor] cannot load remote contexts. You can install a
 // [RemoteContextLoaderFunc] using [WithRemoteContextLoader] when creating the
-// processor. You will need to provide your own. In order to not have
-// dependencies on the network when processing documents, it's strongly
-// recommended to create your own implementation of [RemoteContextLoaderFunc]
-// with the necessary contexts built-in. You can take a look at the FileLoader
-// in helpers_test.go.
+// processor. [NewHTTPContextLoader] retrieves contexts over HTTP, caching
+// them in memory. In order to not have dependencies on the network when
+// processing documents, it's strongly recommended to build the necessary
+// contexts into your application. [NewFSLoader] serves contexts from any
+// [fs.FS], like an [embed.FS]. The contexts package bundles commonly used
+// ones. To detect contexts that changed since you last reviewed them, pin them
+// to a digest with [PinContexts].
+//
+// To expand a document by its URL, use [Processor.ExpandURL]. This requires a
+// [RemoteDocumentLoaderFunc] to be installed using [WithRemoteDocumentLoader].
+// [NewHTTPDocumentLoader] retrieves documents over HTTP, applying a context
+// linked to with a Link header to plain JSON documents.
+// JSON-LD embedded in the script elements of an HTML document can be expanded
+// with the [sourcery.dny.nu/longdistance/html] package.
 //
 // # JSON typing
 //
@@ -35,6 +66,14 @@
 // a different value, like a timestamp or a duration. Those too should have a
 // type specifying how to interpret them.
 //
+// Instead of walking a list of [Node] by hand, you can decode it into your own
+// structs with [Unmarshal]. Fields are matched to properties using an ld
+// struct tag holding the expanded IRI of the property, and values are decoded
+// according to the type of the field. [Marshal] does the reverse, turning
+// your structs into a list of [Node] you can pass to [Processor.Compact]. To
+// compact them with a context that matches your structs, generate one with
+// [GenerateContext].
+//
 // # Constraints
 //
 // For JSON-LD, there are a few extra constraints on top of JSON:
@@ -0,0 +1,438 @@
+package longdistance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// MarshalError is returned by [Marshal] and [MarshalNode] when a struct
+// field can't be encoded.
+type MarshalError struct {
+	// Path of Go fields leading to the value, for example
+	// "Attachment[1].Name".
+	Path string
+	// Property is the IRI or keyword of the property being encoded.
+	Property string
+	Err      error
+}
+
+func (e *MarshalError) Error() string {
+	if e.Path == "" {
+		return "ld: " + e.Err.Error()
+	}
+	return fmt.Sprintf("ld: %s (%s): %s", e.Path, e.Property, e.Err)
+}
+
+func (e *MarshalError) Unwrap() error {
+	return e.Err
+}
+
+// Marshal encodes v into a list of nodes in expanded document form, ready to
+// be passed to [Processor.Compact].
+//
+// If v is a slice, every element is encoded as a node. Otherwise v is encoded
+// like [MarshalNode].
+func Marshal(v any) ([]Node, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Slice {
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Slice {
+		node, err := MarshalNode(v)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{node}, nil
+	}
+
+	if rv.Type().Elem() == nodeType {
+		return rv.Interface().([]Node), nil
+	}
+
+	res := make([]Node, 0, rv.Len())
+	for i := range rv.Len() {
+		node, err := encodeNode(rv.Index(i), fmt.Sprintf("[%d]", i))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, node)
+	}
+
+	return res, nil
+}
+
+// MarshalNode encodes v, a struct or a pointer to one, into a node.
+//
+// It uses the same ld struct tags as [UnmarshalNode], and a node encoded
+// with MarshalNode decodes back into an equal struct. The IRI in the tag can
+// be followed by comma-separated options:
+//
+//	type Note struct {
+//		ID        string            `ld:"@id"`
+//		Type      []string          `ld:"@type"`
+//		Content   map[string]string `ld:"https://www.w3.org/ns/activitystreams#content"`
+//		Summary   string            `ld:"https://www.w3.org/ns/activitystreams#summary,omitempty,lang=en"`
+//		InReplyTo string            `ld:"https://www.w3.org/ns/activitystreams#inReplyTo,omitempty,ref"`
+//		Duration  string            `ld:"https://www.w3.org/ns/activitystreams#duration,type=http://www.w3.org/2001/XMLSchema#duration"`
+//		Items     []Note            `ld:"https://www.w3.org/ns/activitystreams#items,list"`
+//	}
+//
+// The options are:
+//   - omitempty omits the property if the field has its zero value, or is
+//     an empty slice or map.
+//   - type=<IRI> sets the datatype of the value, turning it into a typed
+//     literal. The value is encoded in its string form. With type=@json the
+//     field is encoded as a JSON literal using [encoding/json].
+//   - lang=<tag> sets the language of a string value. When decoding, only
+//     values with that language are considered.
+//   - list encodes a slice as an @list, preserving its order. Without it a
+//     slice is encoded as an unordered set of values.
+//   - ref encodes a struct as a reference to the node, holding only its @id.
+//     A string is encoded as an @id reference instead of a value. Encoding a
+//     reference without an @id fails, so combine it with omitempty for
+//     optional references.
+//
+// Values are encoded based on the type of the field:
+//   - Strings, booleans, integers and floats are encoded as value objects
+//     holding their native JSON form.
+//   - A [time.Time] is encoded as an xsd:dateTime typed literal, or an
+//     xsd:date if the field has type=xsd:date.
+//   - A [json.RawMessage] is encoded as an @json literal.
+//   - A map[string]string is a language map. Each value is encoded with its
+//     key as @language, or without one for the empty string.
+//   - A struct is encoded as an embedded node object.
+//   - A [Node] is used as-is.
+//   - Nil pointers, slices and maps are omitted, as JSON-LD has no way to
+//     represent a null value.
+//
+// Errors are returned as a [MarshalError] holding the path to the field that
+// couldn't be encoded.
+func MarshalNode(v any) (Node, error) {
+	return encodeNode(reflect.ValueOf(v), "")
+}
+
+// encodeNode encodes a struct into a node.
+func encodeNode(rv reflect.Value, path string) (Node, error) {
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return Node{}, &MarshalError{Path: path, Err: errors.New("cannot encode nil as a node")}
+		}
+		rv = rv.Elem()
+	}
+
+	if !rv.IsValid() {
+		return Node{}, &MarshalError{Path: path, Err: errors.New("cannot encode nil as a node")}
+	}
+
+	if rv.Type() == nodeType {
+		return rv.Interface().(Node), nil
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return Node{}, &MarshalError{Path: path, Err: fmt.Errorf("cannot encode %s as a node", rv.Type())}
+	}
+
+	fields, err := fieldsOf(rv.Type())
+	if err != nil {
+		return Node{}, &MarshalError{Path: path, Err: err}
+	}
+
+	var node Node
+	for _, f := range fields {
+		fpath := f.name
+		if path != "" {
+			fpath = path + "." + f.name
+		}
+
+		fv, ok := fieldValue(rv, f.index)
+		if !ok {
+			continue
+		}
+
+		if err := encodeField(&node, f, fv, fpath); err != nil {
+			var merr *MarshalError
+			if errors.As(err, &merr) {
+				return Node{}, err
+			}
+			return Node{}, &MarshalError{Path: fpath, Property: f.iri, Err: err}
+		}
+	}
+
+	return node, nil
+}
+
+// fieldValue is like [reflect.Value.FieldByIndex] but reports false if the
+// field is in a nil embedded struct.
+func fieldValue(rv reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Pointer {
+			if rv.IsNil() {
+				return reflect.Value{}, false
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, true
+}
+
+// isEmpty reports whether a field is omitted by omitempty.
+func isEmpty(rv reflect.Value) bool {
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	default:
+		return rv.IsZero()
+	}
+}
+
+func encodeField(node *Node, f field, fv reflect.Value, path string) error {
+	if f.omitEmpty && isEmpty(fv) {
+		return nil
+	}
+
+	for fv.Kind() == reflect.Pointer {
+		if fv.IsNil() {
+			return nil
+		}
+		fv = fv.Elem()
+	}
+
+	switch f.iri {
+	case KeywordID:
+		if fv.Kind() != reflect.String {
+			return fmt.Errorf("cannot encode %s as %s", fv.Type(), f.iri)
+		}
+		node.ID = fv.String()
+		return nil
+	case KeywordIndex:
+		if fv.Kind() != reflect.String {
+			return fmt.Errorf("cannot encode %s as %s", fv.Type(), f.iri)
+		}
+		node.Index = fv.String()
+		return nil
+	case KeywordType:
+		switch {
+		case fv.Kind() == reflect.String:
+			if fv.String() != "" {
+				node.Type = append(node.Type, fv.String())
+			}
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+			for i := range fv.Len() {
+				node.Type = append(node.Type, fv.Index(i).String())
+			}
+		default:
+			return fmt.Errorf("cannot encode %s as %s", fv.Type(), f.iri)
+		}
+		return nil
+	}
+
+	values, err := encodeValues(fv, f, path)
+	if err != nil {
+		return err
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	if node.Properties == nil {
+		node.Properties = make(Properties, 1)
+	}
+	node.Properties[f.iri] = append(node.Properties[f.iri], values...)
+
+	return nil
+}
+
+// encodeValues encodes a field into the values of a property.
+func encodeValues(fv reflect.Value, f field, path string) ([]Node, error) {
+	typ := fv.Type()
+
+	switch {
+	case f.datatype == KeywordJSON:
+		data, err := json.Marshal(fv.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return []Node{{Value: data, Type: []string{KeywordJSON}}}, nil
+	case typ == rawMessageType:
+		if fv.Len() == 0 {
+			return nil, nil
+		}
+		value, err := encodeValue(fv, f, path)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{value}, nil
+	case typ.Kind() == reflect.Slice && typ.Elem() == nodeType:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		values := fv.Interface().([]Node)
+		if f.list {
+			return []Node{{List: slices.Clone(values)}}, nil
+		}
+		return slices.Clone(values), nil
+	case typ.Kind() == reflect.Slice:
+		if fv.IsNil() {
+			return nil, nil
+		}
+
+		values := make([]Node, 0, fv.Len())
+		for i := range fv.Len() {
+			ipath := fmt.Sprintf("%s[%d]", path, i)
+
+			ev := fv.Index(i)
+			if ev.Kind() == reflect.Pointer && ev.IsNil() {
+				continue
+			}
+
+			value, err := encodeValue(ev, f, ipath)
+			if err != nil {
+				var merr *MarshalError
+				if errors.As(err, &merr) {
+					return nil, err
+				}
+				return nil, &MarshalError{Path: ipath, Property: f.iri, Err: err}
+			}
+			values = append(values, value)
+		}
+
+		if f.list {
+			return []Node{{List: values}}, nil
+		}
+		return values, nil
+	case typ.Kind() == reflect.Map:
+		return encodeLanguageMap(fv)
+	default:
+		value, err := encodeValue(fv, f, path)
+		if err != nil {
+			return nil, err
+		}
+		return []Node{value}, nil
+	}
+}
+
+// encodeValue encodes a single value into a value object or node object.
+func encodeValue(rv reflect.Value, f field, path string) (Node, error) {
+	for rv.Kind() == reflect.Pointer {
+		rv = rv.Elem()
+	}
+
+	typ := rv.Type()
+
+	switch {
+	case typ == nodeType:
+		return rv.Interface().(Node), nil
+	case typ == rawMessageType:
+		if !json.Valid(rv.Bytes()) {
+			return Node{}, errors.New("invalid JSON in json.RawMessage")
+		}
+		return Node{Value: slices.Clone(rv.Bytes()), Type: []string{KeywordJSON}}, nil
+	case typ == timeType:
+		return encodeTime(rv.Interface().(time.Time), f.datatype), nil
+	case typ.Kind() == reflect.Struct:
+		node, err := encodeNode(rv, path)
+		if err != nil {
+			return Node{}, err
+		}
+		if !f.ref {
+			return node, nil
+		}
+		if node.ID == "" {
+			return Node{}, errors.New("cannot reference a node without an @id")
+		}
+		return Node{ID: node.ID}, nil
+	}
+
+	if f.language != "" && typ.Kind() != reflect.String {
+		return Node{}, fmt.Errorf("cannot encode %s with a language", typ)
+	}
+
+	var text string
+	switch typ.Kind() {
+	case reflect.String:
+		if f.ref {
+			if rv.String() == "" {
+				return Node{}, errors.New("cannot reference a node without an @id")
+			}
+			return Node{ID: rv.String()}, nil
+		}
+		text = rv.String()
+	case reflect.Bool:
+		text = strconv.FormatBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		text = strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		text = strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		fl := rv.Float()
+		if math.IsNaN(fl) || math.IsInf(fl, 0) {
+			return Node{}, fmt.Errorf("cannot encode %v", fl)
+		}
+		text = strconv.FormatFloat(fl, 'g', -1, typ.Bits())
+	default:
+		return Node{}, fmt.Errorf("cannot encode %s", typ)
+	}
+
+	if f.ref {
+		return Node{}, fmt.Errorf("cannot encode %s as a reference", typ)
+	}
+
+	value := Node{Language: f.language}
+
+	switch {
+	case f.datatype != "":
+		// typed literals hold their lexical form
+		value.Type = []string{f.datatype}
+		value.Value, _ = json.Marshal(text)
+	case typ.Kind() == reflect.String:
+		value.Value, _ = json.Marshal(text)
+	default:
+		value.Value = json.RawMessage(text)
+	}
+
+	return value, nil
+}
+
+func encodeTime(t time.Time, datatype string) Node {
+	switch datatype {
+	case "":
+		datatype = XSDDateTime
+	case XSDDate:
+		raw, _ := json.Marshal(t.Format(time.DateOnly))
+		return Node{Value: raw, Type: []string{datatype}}
+	}
+
+	raw, _ := json.Marshal(t.Format(time.RFC3339Nano))
+	return Node{Value: raw, Type: []string{datatype}}
+}
+
+// encodeLanguageMap encodes a map of language to string into values.
+func encodeLanguageMap(rv reflect.Value) ([]Node, error) {
+	typ := rv.Type()
+	if typ.Key().Kind() != reflect.String || typ.Elem().Kind() != reflect.String {
+		return nil, fmt.Errorf("cannot encode %s, only map[string]string is supported", typ)
+	}
+
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	slices.Sort(keys)
+
+	values := make([]Node, 0, len(keys))
+	for _, k := range keys {
+		raw, _ := json.Marshal(rv.MapIndex(reflect.ValueOf(k).Convert(typ.Key())).String())
+		values = append(values, Node{Value: raw, Language: k})
+	}
+
+	return values, nil
+}
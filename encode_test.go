@@ -0,0 +1,271 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+type testAttachment struct {
+	ID   string `ld:"@id,omitempty"`
+	Type string `ld:"@type"`
+	Name string `ld:"https://www.w3.org/ns/activitystreams#name"`
+}
+
+type testActivity struct {
+	ID        string            `ld:"@id"`
+	Type      []string          `ld:"@type"`
+	Actor     string            `ld:"https://www.w3.org/ns/activitystreams#actor,ref"`
+	Summary   string            `ld:"https://www.w3.org/ns/activitystreams#summary,omitempty,lang=en"`
+	SummaryNL string            `ld:"https://www.w3.org/ns/activitystreams#summary,omitempty,lang=nl"`
+	Content   map[string]string `ld:"https://www.w3.org/ns/activitystreams#content,omitempty"`
+	Sensitive bool              `ld:"https://www.w3.org/ns/activitystreams#sensitive"`
+	Published time.Time         `ld:"https://www.w3.org/ns/activitystreams#published"`
+	Updated   time.Time         `ld:"https://www.w3.org/ns/activitystreams#updated,omitempty"`
+	Duration  string            `ld:"https://www.w3.org/ns/activitystreams#duration,omitempty,type=http://www.w3.org/2001/XMLSchema#duration"`
+	Width     uint64            `ld:"https://www.w3.org/ns/activitystreams#width,type=http://www.w3.org/2001/XMLSchema#nonNegativeInteger"`
+	Items     []string          `ld:"https://www.w3.org/ns/activitystreams#items,list,ref"`
+	Tags      []string          `ld:"https://www.w3.org/ns/activitystreams#tag,omitempty,ref"`
+	Object    *testAttachment   `ld:"https://www.w3.org/ns/activitystreams#object,omitempty"`
+	Target    *testAttachment   `ld:"https://www.w3.org/ns/activitystreams#target,omitempty,ref"`
+	Extra     map[string]any    `ld:"https://example.org/extra,omitempty,type=@json"`
+}
+
+func TestMarshal(t *testing.T) {
+	published := time.Date(2024, 2, 29, 12, 30, 0, 0, time.UTC)
+	act := testActivity{
+		ID:        "https://example.com/create/1",
+		Type:      []string{"https://www.w3.org/ns/activitystreams#Create"},
+		Actor:     "https://example.com/actor/1",
+		Summary:   "A <summary>",
+		SummaryNL: "Een samenvatting",
+		Content:   map[string]string{"": "Content", "en": "Content"},
+		Published: published,
+		Width:     9007199254740993,
+		Items:     []string{"https://example.com/b", "https://example.com/a"},
+		Tags:      []string{"https://example.com/tag/1"},
+		Object: &testAttachment{
+			ID:   "https://example.com/object/1",
+			Type: "https://www.w3.org/ns/activitystreams#Note",
+			Name: "A note",
+		},
+		Target: &testAttachment{ID: "https://example.com/collection/1"},
+		Extra:  map[string]any{"a": true},
+	}
+
+	nodes, err := ld.Marshal(&act)
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	var dst bytes.Buffer
+	err = ld.NewProcessor(
+		ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+		ld.WithOrdered(true),
+	).Compact(t.Context(), &dst, json.RawMessage(`[
+		"https://www.w3.org/ns/activitystreams",
+		{"extra": {"@id": "https://example.org/extra", "@type": "@json"}}
+	]`), nodes, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	want := json.RawMessage(`{
+		"@context": [
+			"https://www.w3.org/ns/activitystreams",
+			{"extra": {"@id": "https://example.org/extra", "@type": "@json"}}
+		],
+		"actor": "https://example.com/actor/1",
+		"contentMap": {"en": "Content"},
+		"content": "Content",
+		"extra": {"a": true},
+		"id": "https://example.com/create/1",
+		"object": {
+			"id": "https://example.com/object/1",
+			"name": "A note",
+			"type": "Note"
+		},
+		"orderedItems": ["https://example.com/b", "https://example.com/a"],
+		"published": "2024-02-29T12:30:00Z",
+		"as:sensitive": false,
+		"summaryMap": {"en": "A <summary>", "nl": "Een samenvatting"},
+		"tag": "https://example.com/tag/1",
+		"target": "https://example.com/collection/1",
+		"type": "Create",
+		"width": "9007199254740993"
+	}`)
+
+	if diff := cmp.Diff(want, json.RawMessage(dst.Bytes()), JSONDiff()); diff != "" {
+		t.Errorf("compaction mismatch (-want +got):\n%s", diff)
+	}
+
+	var got testActivity
+	if err := ld.Unmarshal(nodes, &got); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(act, got); diff != "" {
+		t.Errorf("round-trip mismatch (-want +got):\n%s", diff)
+	}
+
+	expanded, err := ld.NewProcessor(
+		ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+	).Expand(t.Context(), bytes.NewReader(dst.Bytes()), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = testActivity{}
+	if err := ld.Unmarshal(expanded, &got); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(act, got); diff != "" {
+		t.Errorf("round-trip through compaction mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalValues(t *testing.T) {
+	type values struct {
+		Count  int             `ld:"https://example.org/count"`
+		Ratio  float64         `ld:"https://example.org/ratio"`
+		Flag   *bool           `ld:"https://example.org/flag"`
+		Date   time.Time       `ld:"https://example.org/date,type=http://www.w3.org/2001/XMLSchema#date"`
+		Data   json.RawMessage `ld:"https://example.org/data"`
+		Nodes  []ld.Node       `ld:"https://example.org/nodes,list"`
+		Empty  []string        `ld:"https://example.org/empty,list"`
+		Unset  []string        `ld:"https://example.org/unset"`
+		Skip   string          `ld:"-"`
+		Hidden string
+	}
+
+	got, err := ld.MarshalNode(values{
+		Count: 3,
+		Ratio: 0.5,
+		Date:  time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		Data:  json.RawMessage(`{"a":null}`),
+		Nodes: []ld.Node{{ID: "https://example.org/node"}},
+		Empty: []string{},
+		Skip:  "skip",
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	want := ld.Node{
+		Properties: ld.Properties{
+			"https://example.org/count": {{Value: json.RawMessage(`3`)}},
+			"https://example.org/ratio": {{Value: json.RawMessage(`0.5`)}},
+			"https://example.org/date": {{
+				Value: json.RawMessage(`"2024-02-29"`),
+				Type:  []string{ld.XSDDate},
+			}},
+			"https://example.org/data": {{
+				Value: json.RawMessage(`{"a":null}`),
+				Type:  []string{ld.KeywordJSON},
+			}},
+			"https://example.org/nodes": {{List: []ld.Node{{ID: "https://example.org/node"}}}},
+			"https://example.org/empty": {{List: []ld.Node{}}},
+		},
+	}
+
+	if diff := cmp.Diff(want, got, JSONDiff()); diff != "" {
+		t.Errorf("encoding mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// TestMarshalEmbeddedUnexportedPointer checks that the fields of a pointer to
+// an unexported struct can be encoded, including ones holding a [ld.Node] or a
+// [json.RawMessage]. A nil pointer is skipped.
+func TestMarshalEmbeddedUnexportedPointer(t *testing.T) {
+	got, err := ld.MarshalNode(testOuter{ID: "https://example.org/1"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(ld.Node{ID: "https://example.org/1"}, got, JSONDiff()); diff != "" {
+		t.Errorf("encoding mismatch (-want +got):\n%s", diff)
+	}
+
+	got, err = ld.MarshalNode(testOuter{
+		ID: "https://example.org/1",
+		testHidden: &testHidden{
+			Name:  "Alice",
+			Data:  json.RawMessage(`{"a":1}`),
+			Other: ld.Node{ID: "https://example.org/2"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	want := ld.Node{
+		ID: "https://example.org/1",
+		Properties: ld.Properties{
+			"https://example.org/name": {{Value: json.RawMessage(`"Alice"`)}},
+			"https://example.org/data": {{
+				Value: json.RawMessage(`{"a":1}`),
+				Type:  []string{ld.KeywordJSON},
+			}},
+			"https://example.org/other": {{ID: "https://example.org/2"}},
+		},
+	}
+
+	if diff := cmp.Diff(want, got, JSONDiff()); diff != "" {
+		t.Errorf("encoding mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		path string
+	}{
+		{name: "not a struct", v: "string"},
+		{name: "nil", v: (*testAttachment)(nil)},
+		{name: "unsupported type", v: struct {
+			C chan int `ld:"https://example.org/c"`
+		}{}, path: "C"},
+		{name: "empty reference", v: struct {
+			Ref string `ld:"https://example.org/ref,ref"`
+		}{}, path: "Ref"},
+		{name: "reference without id", v: struct {
+			Refs []testAttachment `ld:"https://example.org/ref,ref"`
+		}{Refs: []testAttachment{{ID: "https://example.org/1"}, {}}}, path: "Refs[1]"},
+		{name: "language on number", v: struct {
+			N int `ld:"https://example.org/n,lang=en"`
+		}{}, path: "N"},
+		{name: "unknown option", v: struct {
+			N int `ld:"https://example.org/n,ordered"`
+		}{}},
+		{name: "type and language", v: struct {
+			S string `ld:"https://example.org/s,lang=en,type=https://example.org/t"`
+		}{}},
+		{name: "option on keyword", v: struct {
+			ID string `ld:"@id,ref"`
+		}{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ld.MarshalNode(tc.v)
+
+			var merr *ld.MarshalError
+			if !errors.As(err, &merr) {
+				t.Fatalf("expected a MarshalError, got: %v", err)
+			}
+
+			if merr.Path != tc.path {
+				t.Errorf("expected path: %q, got: %q", tc.path, merr.Path)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@
 // Error types from the JSON-LD specification.
 var (
 	ErrCollidingKeywords           = errors.New("colliding keywords")
+	ErrConflictingIndexes          = errors.New("conflicting indexes")
 	ErrContextOverflow             = errors.New("context overflow")
 	ErrCyclicIRIMapping            = errors.New("cyclic IRI mapping")
 	ErrInvalidBaseDirection        = errors.New("invalid base direction")
@@ -13,11 +14,14 @@
 	ErrInvalidContextEntry         = errors.New("invalid context entry")
 	ErrInvalidContextNullificaton  = errors.New("invalid context nullification")
 	ErrInvalidDefaultLanguage      = errors.New("invalid default language")
+	ErrInvalidEmbedValue           = errors.New("invalid @embed value")
+	ErrInvalidFrame                = errors.New("invalid frame")
 	ErrInvalidIDValue              = errors.New("invalid @id value")
 	ErrInvalidImportValue          = errors.New("invalid @import value")
 	ErrInvalidIncludedValue        = errors.New("invalid @included value")
 	ErrInvalidIndexValue           = errors.New("invalid @index value")
 	ErrInvalidIRIMapping           = errors.New("invalid IRI mapping")
+	ErrInvalidJSONLiteral          = errors.New("invalid JSON literal")
 	ErrInvalidKeywordAlias         = errors.New("invalid keyword alias")
 	ErrInvalidLanguageMapping      = errors.New("invalid language mapping")
 	ErrInvalidLanguageMapValue     = errors.New("invalid language map value")
@@ -33,6 +37,7 @@
 	ErrInvalidReversePropertyMap   = errors.New("invalid reverse property map")
 	ErrInvalidReversePropertyValue = errors.New("invalid reverse property value")
 	ErrInvalidReverseValue         = errors.New("invalid @reverse value")
+	ErrInvalidScriptElement        = errors.New("invalid script element")
 	ErrInvalidScopedContext        = errors.New("invalid scoped context")
 	ErrInvalidSetOrListObject      = errors.New("invalid set or list object")
 	ErrInvalidTermDefinition       = errors.New("invalid term definition")
@@ -47,6 +52,7 @@
 	ErrKeywordRedefinition         = errors.New("keyword redefinition")
 	ErrLoadingDocument             = errors.New("loading document failed")
 	ErrLoadingRemoteContext        = errors.New("loading remote context failed")
+	ErrMultipleContextLinkHeaders  = errors.New("multiple context link headers")
 	ErrProcessingMode              = errors.New("processing mode conflict")
 	ErrProtectedTermRedefinition   = errors.New("protected term redefinition")
 	ErrRecursiveContextInclusion   = errors.New("recursive context inclusion")
@@ -54,8 +60,16 @@
 
 // Library-specific errors.
 var (
+	ErrInvalid           = errors.New("context validation failed")
+	ErrDisallowedKeyword = errors.New("disallowed keyword present in document")
+	ErrContextIntegrity  = errors.New("remote context does not match pinned digest")
+	ErrInvalidSnapshot   = errors.New("invalid context snapshot")
+
+	// Deprecated: frame expansion is supported by [Processor.Frame] and this
+	// error is no longer returned.
 	ErrFrameExpansionUnsupported = errors.New("frame expansion is not supported")
-	ErrPreserveUnsupported       = errors.New("@preserve is not supported")
-	ErrInvalid                   = errors.New("context validation failed")
-	ErrDisallowedKeyword         = errors.New("disallowed keyword present in document")
+
+	// Deprecated: @preserve is supported during compaction and this error is
+	// no longer returned.
+	ErrPreserveUnsupported = errors.New("@preserve is not supported")
 )
@@ -10,6 +10,7 @@
 	"iter"
 	"log/slog"
 	"maps"
+	"mime"
 	"slices"
 	"strings"
 
@@ -35,31 +36,59 @@ func (e expandOptions) withoutFromMap() expandOptions {
 func (p *Processor) Expand(
 	ctx context.Context,
 	document io.Reader, url string) ([]Node, error) {
-	opts := expandOptions{}
-	baseIRI := cmp.Or(p.baseIRI, url)
+	return p.expandDocument(ctx, nil, document, url, "")
+}
 
-	var ldCtx *Context
+// ExpandURL retrieves the document at url and transforms it into JSON-LD
+// expanded document form.
+//
+// The document is retrieved with the loader set by [WithRemoteDocumentLoader].
+// The URL the document was retrieved from is used as the base IRI, unless one
+// was set with [WithBaseIRI]. When the document is [ApplicationJSON] or another
+// media type with the +json suffix, the context linked to from
+// [RemoteDocument.ContextURL] is applied before expanding it. Other media
+// types result in [ErrLoadingDocument]. To expand HTML documents, wrap the
+// loader with the Loader of the [sourcery.dny.nu/longdistance/html] package.
+func (p *Processor) ExpandURL(ctx context.Context, url string) ([]Node, error) {
+	if p.documentLoader == nil {
+		return nil, fmt.Errorf("no loader %w", ErrLoadingDocument)
+	}
 
-	if p.expandContext == nil {
-		ldCtx = newContext(baseIRI)
-	} else {
-		var obj json.Object
-		if err := json.Unmarshal(p.expandContext, &obj); err != nil {
-			return nil, ErrInvalidLocalContext
-		}
-		var rawctx json.RawMessage
-		if v, ok := obj[KeywordContext]; ok {
-			rawctx = v
-		} else {
-			rawctx = p.expandContext
-		}
+	doc, err := p.documentLoader(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadingDocument, err)
+	}
 
-		var err error
-		dec := json.NewDecoder(bytes.NewReader(rawctx))
-		ldCtx, err = p.context(ctx, nil, dec, "", newCtxProcessingOpts())
-		if err != nil {
-			return nil, err
+	documentURL := cmp.Or(doc.DocumentURL, url)
+	mt, _, _ := mime.ParseMediaType(doc.ContentType)
+
+	switch mt {
+	case ApplicationLDJSON:
+		return p.expandDocument(ctx, nil, bytes.NewReader(doc.Document), documentURL, "")
+	default:
+		if !isJSONMediaType(mt) {
+			return nil, fmt.Errorf("%w: unsupported content type: %s", ErrLoadingDocument, doc.ContentType)
 		}
+		return p.expandDocument(ctx, nil, bytes.NewReader(doc.Document), documentURL, doc.ContextURL)
+	}
+}
+
+// expandDocument expands a document. The expandCtx is used as the expand
+// context if set, otherwise the one set with [WithExpandContext].
+func (p *Processor) expandDocument(
+	ctx context.Context,
+	expandCtx *PreparedContext,
+	document io.Reader,
+	url string,
+	contextURL string,
+) ([]Node, error) {
+	ctx = withRemoteContexts(ctx)
+
+	opts := expandOptions{}
+
+	ldCtx, err := p.documentContext(ctx, expandCtx, url, contextURL)
+	if err != nil {
+		return nil, err
 	}
 
 	dec := json.NewDecoder(document)
@@ -83,22 +112,79 @@ func (p *Processor) Expand(
 
 	result := make([]Node, 0, len(res))
 	for _, obj := range res {
-		if obj.IsZero() {
-			continue
+		if isTopLevelNode(obj) {
+			result = append(result, obj)
 		}
+	}
 
-		if obj.IsValue() {
-			continue
+	return result, nil
+}
+
+// documentContext returns the active context to expand a document with.
+// The expandCtx is used as the expand context if set, otherwise the one set
+// with [WithExpandContext]. The context linked to with contextURL is applied
+// on top of it.
+func (p *Processor) documentContext(
+	ctx context.Context,
+	expandCtx *PreparedContext,
+	url string,
+	contextURL string,
+) (*Context, error) {
+	baseIRI := cmp.Or(p.baseIRI, url)
+
+	if expandCtx == nil && p.expandContext != nil {
+		var err error
+		expandCtx, err = p.preparedExpandContext(ctx, baseIRI)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if obj.Has(KeywordID) && obj.Len() == 1 {
-			continue
+	var ldCtx *Context
+
+	if expandCtx == nil || expandCtx.ctx == nil {
+		ldCtx = newContext(baseIRI)
+	} else {
+		// the prepared context is shared, so work on a copy
+		ldCtx = expandCtx.ctx.clone()
+		if ldCtx.currentBaseIRI == ldCtx.originalBaseIRI {
+			ldCtx.currentBaseIRI = baseIRI
 		}
+		ldCtx.originalBaseIRI = baseIRI
+	}
 
-		result = append(result, obj)
+	if contextURL != "" {
+		local, err := json.Marshal(contextURL)
+		if err != nil {
+			return nil, err
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(local))
+		ldCtx, err = p.context(ctx, ldCtx, dec, contextURL, newCtxProcessingOpts())
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	return result, nil
+	return ldCtx, nil
+}
+
+// isTopLevelNode reports whether a node is kept in the top-level result of
+// expansion. Free-floating values and node references are dropped.
+func isTopLevelNode(obj Node) bool {
+	if obj.IsZero() {
+		return false
+	}
+
+	if obj.IsValue() {
+		return false
+	}
+
+	if obj.Has(KeywordID) && obj.Len() == 1 {
+		return false
+	}
+
+	return true
 }
 
 func (p *Processor) expand(
@@ -114,11 +200,6 @@ func (p *Processor) expand(
 		opts.frameExpansion = false
 	}
 
-	// bail out on frame expansion since we don't do that
-	if opts.frameExpansion {
-		return nil, ErrFrameExpansionUnsupported
-	}
-
 	termDef := activeCtx.defs[activeProp]
 
 	// 3)
@@ -322,6 +403,20 @@ func (p *Processor) expandObject(
 		return nil, err
 	}
 
+	return p.expandMap(ctx, activeCtx, activeProp, obj, baseURL, opts, termDef, propContext)
+}
+
+// expandMap expands an object of which all entries have been read.
+func (p *Processor) expandMap(
+	ctx context.Context,
+	activeCtx *Context,
+	activeProp string,
+	obj json.Object,
+	baseURL string,
+	opts expandOptions,
+	termDef Term,
+	propContext json.RawMessage,
+) ([]Node, error) {
 	// 7)
 	if activeCtx.previousCtx != nil && !opts.fromMap {
 		hasValue := p.expandsToKeyword(ctx, activeCtx, KeywordValue, maps.Keys(obj))
@@ -358,7 +453,7 @@ func (p *Processor) expandObject(
 
 	// 11) Find @type key and process type-scoped contexts
 	var typeVal json.RawMessage
-	for k, v := range obj {
+	for k, v := range entries(obj, p.ordered) {
 		u, err := p.expandIRI(ctx, activeCtx, k, false, true, nil, nil)
 		if err != nil {
 			continue
@@ -369,6 +464,12 @@ func (p *Processor) expandObject(
 		}
 	}
 
+	// a frame can match any type, or provide a default type, neither of
+	// which have a type-scoped context
+	if opts.frameExpansion && json.IsMap(typeVal) {
+		typeVal = nil
+	}
+
 	var stringTerms []string
 	if len(typeVal) > 0 {
 		if err := json.Unmarshal(json.MakeArray(typeVal), &stringTerms); err != nil {
@@ -471,8 +572,8 @@ func (p *Processor) expandObject(
 		return nil, nil
 	}
 
-	// 19)
-	if activeProp == "" || activeProp == KeywordGraph {
+	// 19) frames are kept as they are, they only match nodes
+	if !opts.frameExpansion && (activeProp == "" || activeProp == KeywordGraph) {
 		if result.Len() == 0 ||
 			result.Has(KeywordList) ||
 			result.Has(KeywordValue) ||
@@ -498,16 +599,21 @@ func (p *Processor) expandObjectKeys(
 ) error {
 	// 13)
 mainLoop:
-	for key, value := range obj {
+	for key, value := range entries(obj, p.ordered) {
 		// 13.1)
 		if key == KeywordContext {
 			continue
 		}
 
-		// 13.2)
-		expProp, err := p.expandIRI(ctx, activeCtx, key, false, true, nil, nil)
-		if err != nil {
-			return err
+		// 13.2) framing keywords can't be aliased
+		expProp := key
+		framing := opts.frameExpansion && isFramingKeyword(key)
+		if !framing {
+			var err error
+			expProp, err = p.expandIRI(ctx, activeCtx, key, false, true, nil, nil)
+			if err != nil {
+				return err
+			}
 		}
 
 		// 13.3)
@@ -515,12 +621,12 @@ func (p *Processor) expandObjectKeys(
 			continue
 		}
 
-		if !(isKeyword(expProp) || strings.Contains(expProp, ":")) {
+		if !(isKeyword(expProp) || framing || strings.Contains(expProp, ":")) {
 			continue
 		}
 
 		// 13.4)
-		if isKeyword(expProp) {
+		if isKeyword(expProp) || framing {
 			if _, ok := p.disallowedKeys[expProp]; ok {
 				return ErrDisallowedKeyword
 			}
@@ -531,13 +637,35 @@ func (p *Processor) expandObjectKeys(
 			}
 
 			// 13.4.2)
-			if result.Has(expProp) && (p.modeLD10 || (expProp != KeywordIncluded && expProp != KeywordType)) {
+			_, framed := result.Properties[expProp]
+			if (result.Has(expProp) || framed) && (p.modeLD10 || (expProp != KeywordIncluded && expProp != KeywordType)) {
 				return ErrCollidingKeywords
 			}
 
+			// 13.4.15)
+			if framing {
+				res, err := p.expandRaw(ctx, activeCtx, expProp, value, baseURL, opts.withoutFromMap())
+				if err != nil {
+					return err
+				}
+
+				result.Properties[expProp] = res
+				continue mainLoop
+			}
+
 			switch expProp {
 			case KeywordID:
 				// 13.4.3)
+				if opts.frameExpansion {
+					res, err := p.expandFramePattern(ctx, activeCtx, value, false)
+					if err != nil {
+						return errors.Join(ErrInvalidIDValue, err)
+					}
+
+					result.Properties[KeywordID] = res
+					continue mainLoop
+				}
+
 				if json.IsNull(value) {
 					return ErrInvalidIDValue
 				}
@@ -570,13 +698,21 @@ func (p *Processor) expandObjectKeys(
 				result.ID = iri
 			case KeywordType:
 				// 13.4.4)
+				if opts.frameExpansion {
+					res, err := p.expandFrameType(ctx, typContext, value)
+					if err != nil {
+						return errors.Join(ErrInvalidTypeValue, err)
+					}
+
+					result.Properties[KeywordType] = append(result.Properties[KeywordType], res...)
+					continue mainLoop
+				}
+
 				if !json.IsString(value) && !json.IsArray(value) {
 					// 13.4.4.1)
 					return ErrInvalidTypeValue
 				}
 
-				// 13.4.4.2) 13.4.4.3) skipped because frame expansion
-
 				// 13.4.4.4)
 				value = json.MakeArray(value)
 
@@ -650,6 +786,16 @@ func (p *Processor) expandObjectKeys(
 					continue mainLoop
 				}
 
+				if opts.frameExpansion {
+					res, err := expandFrameValues(value, false)
+					if err != nil {
+						return errors.Join(ErrInvalidValueObjectValue, err)
+					}
+
+					result.Properties[KeywordValue] = res
+					continue mainLoop
+				}
+
 				// 13.4.7.2)
 				if !json.IsScalar(value) && !json.IsNull(value) {
 					return ErrInvalidValueObjectValue
@@ -659,6 +805,16 @@ func (p *Processor) expandObjectKeys(
 				result.Value = value
 			case KeywordLanguage:
 				// 13.4.8)
+				if opts.frameExpansion {
+					res, err := expandFrameValues(value, true)
+					if err != nil {
+						return errors.Join(ErrInvalidLanguageTaggedString, err)
+					}
+
+					result.Properties[KeywordLanguage] = res
+					continue mainLoop
+				}
+
 				var l string
 				if err := json.Unmarshal(value, &l); err != nil {
 					// 13.4.8.1)
@@ -794,7 +950,6 @@ func (p *Processor) expandObjectKeys(
 				p.logger.Warn("unhandled property", slog.String("proprety", expProp))
 			}
 
-			// 13.4.15) skip because frame expansion
 			// 13.4.16) 13.4.17) we've already been doing this implicitly at each step
 			continue mainLoop
 		}
@@ -821,7 +976,7 @@ func (p *Processor) expandObjectKeys(
 			dir := cmp.Or(termDef.Direction, activeCtx.defaultDirection)
 
 			// 13.7.4)
-			for langKey, langValue := range langMap {
+			for langKey, langValue := range entries(langMap, p.ordered) {
 				// 13.7.4.1)
 				langValue = json.MakeArray(langValue)
 
@@ -879,7 +1034,7 @@ func (p *Processor) expandObjectKeys(
 			idxKey := cmp.Or(termDef.Index, KeywordIndex)
 
 			// 13.8.3)
-			for idx, idxVal := range objVal {
+			for idx, idxVal := range entries(objVal, p.ordered) {
 				// 13.8.3.1) 13.8.3.3)
 				mapCtx := activeCtx
 
@@ -1068,7 +1223,7 @@ func (p *Processor) expandObjectKeys(
 	}
 
 	// 14)
-	for k := range nests {
+	for k := range entries(nests, p.ordered) {
 		// 14.1)
 		nestData := json.MakeArray(obj[k])
 
@@ -1202,3 +1357,108 @@ func (p *Processor) expandValue(
 
 	return result, nil
 }
+
+// expandFramePattern expands the value of @id, or @type when vocab is set,
+// with the frame expansion flag set. The empty map, which matches any value,
+// is expanded to an empty node. Otherwise every IRI is expanded to a node
+// with that @id. An empty array matches no value.
+func (p *Processor) expandFramePattern(
+	ctx context.Context,
+	activeCtx *Context,
+	value json.RawMessage,
+	vocab bool,
+) ([]Node, error) {
+	if json.IsMap(value) {
+		var obj json.Object
+		if err := json.Unmarshal(value, &obj); err != nil || len(obj) != 0 {
+			return nil, ErrInvalidFrame
+		}
+
+		return []Node{{}}, nil
+	}
+
+	var values []string
+	if err := json.Unmarshal(json.MakeArray(value), &values); err != nil {
+		return nil, ErrInvalidFrame
+	}
+
+	result := make([]Node, 0, len(values))
+	for _, v := range values {
+		u, err := p.expandIRI(ctx, activeCtx, v, true, vocab, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, Node{ID: u})
+	}
+
+	return result, nil
+}
+
+// expandFrameType expands the value of @type with the frame expansion flag
+// set. On top of what [Processor.expandFramePattern] handles, it can be a
+// default object.
+func (p *Processor) expandFrameType(
+	ctx context.Context,
+	typContext *Context,
+	value json.RawMessage,
+) ([]Node, error) {
+	if json.IsMap(value) {
+		var def map[string]string
+		if err := json.Unmarshal(value, &def); err == nil && len(def) == 1 {
+			if dt, ok := def[KeywordDefault]; ok {
+				// 13.4.4.3)
+				u, err := p.expandIRI(ctx, typContext, dt, true, true, nil, nil)
+				if err != nil {
+					return nil, err
+				}
+
+				return []Node{{Properties: Properties{KeywordDefault: {{ID: u}}}}}, nil
+			}
+		}
+	}
+
+	// 13.4.4.2)
+	return p.expandFramePattern(ctx, typContext, value, true)
+}
+
+// expandFrameValues expands the value of @value, or @language when language
+// is set, with the frame expansion flag set. The empty map, which matches any
+// value, is expanded to an empty node. Otherwise every value is expanded to a
+// value object holding it.
+func expandFrameValues(value json.RawMessage, language bool) ([]Node, error) {
+	if json.IsMap(value) {
+		var obj json.Object
+		if err := json.Unmarshal(value, &obj); err != nil || len(obj) != 0 {
+			return nil, ErrInvalidFrame
+		}
+
+		return []Node{{}}, nil
+	}
+
+	var values json.Array
+	if err := json.Unmarshal(json.MakeArray(value), &values); err != nil {
+		return nil, ErrInvalidFrame
+	}
+
+	result := make([]Node, 0, len(values))
+	for _, v := range values {
+		if language {
+			var l string
+			if err := json.Unmarshal(v, &l); err != nil {
+				return nil, ErrInvalidFrame
+			}
+
+			result = append(result, Node{Language: strings.ToLower(l)})
+			continue
+		}
+
+		if !json.IsScalar(v) {
+			return nil, ErrInvalidFrame
+		}
+
+		result = append(result, Node{Value: v})
+	}
+
+	return result, nil
+}
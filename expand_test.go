@@ -2,6 +2,7 @@
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -614,3 +615,133 @@ func TestExpandCustom(t *testing.T) {
 		})
 	}
 }
+
+func TestExpandURL(t *testing.T) {
+	documents := map[string]ld.RemoteDocument{
+		"https://example.org/redirect": {
+			DocumentURL: "https://example.org/notes/1",
+			ContentType: ld.ApplicationLDJSON,
+			Document:    json.RawMessage(`{"@id": "1", "http://example.org/reply": {"@id": "2"}}`),
+		},
+		"https://example.org/json": {
+			DocumentURL: "https://example.org/json",
+			ContentType: ld.ApplicationJSON,
+			ContextURL:  "https://example.org/context.jsonld",
+			Document:    json.RawMessage(`{"id": "https://example.org/json", "name": "Alice"}`),
+		},
+		"https://example.org/activity": {
+			DocumentURL: "https://example.org/activity",
+			ContentType: "application/activity+json",
+			ContextURL:  "https://example.org/context.jsonld",
+			Document:    json.RawMessage(`{"id": "https://example.org/activity", "name": "Alice"}`),
+		},
+		"https://example.org/text": {
+			DocumentURL: "https://example.org/text",
+			ContentType: "text/plain",
+			ContextURL:  "https://example.org/context.jsonld",
+			Document:    json.RawMessage(`{"id": "https://example.org/text", "name": "Alice"}`),
+		},
+		"https://example.org/ld": {
+			DocumentURL: "https://example.org/ld",
+			ContentType: ld.ApplicationLDJSON,
+			ContextURL:  "https://example.org/context.jsonld",
+			Document:    json.RawMessage(`{"@id": "https://example.org/ld", "name": "Alice"}`),
+		},
+	}
+
+	docLoader := func(_ context.Context, url string) (ld.RemoteDocument, error) {
+		doc, ok := documents[url]
+		if !ok {
+			return ld.RemoteDocument{}, errors.New("not found")
+		}
+		return doc, nil
+	}
+
+	ctxLoader := func(_ context.Context, url string) (ld.Document, error) {
+		if url != "https://example.org/context.jsonld" {
+			return ld.Document{}, ld.ErrLoadingRemoteContext
+		}
+		return ld.Document{
+			URL:     url,
+			Context: json.RawMessage(`{"id": "@id", "name": "http://example.org/name"}`),
+		}, nil
+	}
+
+	proc := ld.NewProcessor(
+		ld.WithRemoteDocumentLoader(docLoader),
+		ld.WithRemoteContextLoader(ctxLoader),
+	)
+
+	tests := []struct {
+		name string
+		proc *ld.Processor
+		url  string
+		out  json.RawMessage
+		err  error
+	}{
+		{
+			name: "base IRI from final URL",
+			proc: proc,
+			url:  "https://example.org/redirect",
+			out:  json.RawMessage(`[{"@id": "https://example.org/notes/1", "http://example.org/reply": [{"@id": "https://example.org/notes/2"}]}]`),
+		},
+		{
+			name: "linked context for JSON",
+			proc: proc,
+			url:  "https://example.org/json",
+			out:  json.RawMessage(`[{"@id": "https://example.org/json", "http://example.org/name": [{"@value": "Alice"}]}]`),
+		},
+		{
+			name: "linked context for +json",
+			proc: proc,
+			url:  "https://example.org/activity",
+			out:  json.RawMessage(`[{"@id": "https://example.org/activity", "http://example.org/name": [{"@value": "Alice"}]}]`),
+		},
+		{
+			name: "unsupported content type",
+			proc: proc,
+			url:  "https://example.org/text",
+			err:  ld.ErrLoadingDocument,
+		},
+		{
+			name: "linked context ignored for JSON-LD",
+			proc: proc,
+			url:  "https://example.org/ld",
+			out:  json.RawMessage(`[]`),
+		},
+		{
+			name: "loader failure",
+			proc: proc,
+			url:  "https://example.org/missing",
+			err:  ld.ErrLoadingDocument,
+		},
+		{
+			name: "no loader",
+			proc: ld.NewProcessor(),
+			url:  "https://example.org/json",
+			err:  ld.ErrLoadingDocument,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := tc.proc.ExpandURL(t.Context(), tc.url)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+
+			if tc.err == nil {
+				got, err := json.Marshal(nodes)
+				if err != nil {
+					t.Fatalf("failed to marshal to expanded JSON: %s", err)
+				}
+				if diff := cmp.Diff(tc.out, json.RawMessage(got), JSONDiff()); diff != "" {
+					t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package longdistance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"strings"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// ExpandSeq is like [Processor.Expand], but yields the nodes of the document
+// as they're expanded instead of returning them all at once.
+//
+// The nodes of a top-level array, and of the @graph entry of an object that
+// has no other entries besides @context, are yielded one at a time. Only the
+// node being expanded is held in memory, making it possible to process large
+// collections. In any other case the document is expanded in full before its
+// nodes are yielded. Either way, the result is the same as [Processor.Expand].
+//
+// Whether an object can be streamed is only known once it has been read in
+// full, so its @graph is read ahead before any node is yielded. When the
+// document is an [io.ReadSeeker], such as an [os.File], the reader is moved
+// back to the @graph to expand it. Otherwise the @graph is kept in memory as
+// is, but its nodes are still expanded and yielded one at a time.
+//
+// The first error encountered is yielded, after which iteration stops.
+func (p *Processor) ExpandSeq(
+	ctx context.Context,
+	document io.Reader, url string) iter.Seq2[Node, error] {
+	return func(yield func(Node, error) bool) {
+		s := &expandStream{
+			p:     p,
+			yield: yield,
+		}
+
+		if err := s.run(ctx, document, url); err != nil && !s.stopped {
+			yield(Node{}, err)
+		}
+	}
+}
+
+type expandStream struct {
+	p     *Processor
+	yield func(Node, error) bool
+
+	// stopped is set when the consumer stops iterating
+	stopped bool
+
+	// seeker is set when the document can seek, base being the offset it
+	// started at
+	seeker io.ReadSeeker
+	base   int64
+}
+
+// emit yields a node if it's kept in the top-level result. It reports false
+// once the consumer has stopped iterating.
+func (s *expandStream) emit(node Node) bool {
+	if s.stopped {
+		return false
+	}
+
+	if !isTopLevelNode(node) {
+		return true
+	}
+
+	if !s.yield(node, nil) {
+		s.stopped = true
+	}
+
+	return !s.stopped
+}
+
+func (s *expandStream) run(ctx context.Context, document io.Reader, url string) error {
+	ctx = withRemoteContexts(ctx)
+
+	ldCtx, err := s.p.documentContext(ctx, nil, url, "")
+	if err != nil {
+		return err
+	}
+
+	if seeker, ok := document.(io.ReadSeeker); ok {
+		// not every io.Seeker can seek, like an os.File for a pipe
+		if base, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			s.seeker, s.base = seeker, base
+		}
+	}
+
+	dec := json.NewDecoder(document)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	var emit func() error
+
+	switch tok {
+	case json.Delim('['):
+		err = s.array(ctx, ldCtx, dec, url)
+	case json.Delim('{'):
+		emit, err = s.object(ctx, ldCtx, dec, url)
+	case json.Delim(']'), json.Delim('}'):
+		err = ErrInvalidLocalContext
+	}
+
+	if err != nil || s.stopped {
+		return err
+	}
+
+	if _, derr := dec.Token(); derr != io.EOF {
+		return fmt.Errorf("trailing garbage in JSON")
+	}
+
+	if emit != nil {
+		return emit()
+	}
+
+	return nil
+}
+
+// array streams the nodes of a top-level array.
+func (s *expandStream) array(
+	ctx context.Context,
+	activeCtx *Context,
+	dec *json.Decoder,
+	url string,
+) error {
+	// A single node with only @graph is replaced by its graph. We can only
+	// know that at the end, so hold on to the first node until there's a
+	// second one.
+	var (
+		first   Node
+		pending bool
+		count   int
+	)
+
+	err := s.items(ctx, activeCtx, "", dec, url, func(node Node) bool {
+		count++
+
+		switch count {
+		case 1:
+			first, pending = node, true
+			return true
+		case 2:
+			pending = false
+			if !s.emit(first) {
+				return false
+			}
+		}
+
+		return s.emit(node)
+	})
+	if err != nil || s.stopped {
+		return err
+	}
+
+	if !pending {
+		return nil
+	}
+
+	// 19)
+	if count == 1 && first.IsSimpleGraph() {
+		for _, node := range first.Graph {
+			if !s.emit(node) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	s.emit(first)
+	return nil
+}
+
+// items expands the items of an array that has been opened, calling fn for
+// every expanded node. It stops once fn returns false.
+func (s *expandStream) items(
+	ctx context.Context,
+	activeCtx *Context,
+	activeProp string,
+	dec *json.Decoder,
+	url string,
+	fn func(Node) bool,
+) error {
+	termDef := activeCtx.defs[activeProp]
+	opts := expandOptions{}
+
+	for dec.More() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		var res []Node
+		switch tok {
+		case json.Delim('{'):
+			res, err = s.p.expandObject(ctx, activeCtx, activeProp, dec, url, opts, termDef, termDef.Context)
+		case json.Delim('['):
+			res, err = s.p.expandArray(ctx, activeCtx, activeProp, dec, url, opts, termDef)
+		case json.Delim(']'), json.Delim('}'):
+			err = ErrInvalidLocalContext
+		default:
+			// free-floating scalars are dropped
+		}
+
+		if err != nil {
+			return err
+		}
+
+		for _, node := range res {
+			if !fn(node) {
+				return nil
+			}
+		}
+	}
+
+	_, err := dec.Token()
+	return err
+}
+
+// object expands a top-level object. The nodes of its @graph are streamed
+// if it has no other entries besides @context and entries that expand to
+// nothing, otherwise it's expanded in full.
+//
+// That can only be known once the whole object has been read, so the @graph
+// is read ahead and the nodes are emitted by the returned function. It's
+// called once the rest of the document has been checked, so that nothing is
+// yielded for a document that's going to be rejected.
+func (s *expandStream) object(
+	ctx context.Context,
+	ldCtx *Context,
+	dec *json.Decoder,
+	url string,
+) (func() error, error) {
+	obj := make(json.Object, 2)
+
+	// 7)
+	graphCtx := ldCtx
+	if graphCtx.previousCtx != nil {
+		graphCtx = graphCtx.previousCtx
+	}
+
+	var (
+		graphKey string
+		graph    *graphValue
+	)
+
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		key := tok.(string)
+
+		if graph == nil && key != KeywordContext {
+			prop, err := s.p.expandIRI(ctx, graphCtx, key, false, true, nil, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			if prop == KeywordGraph {
+				graph, err = s.readGraph(dec)
+				if err != nil {
+					return nil, err
+				}
+				graphKey = key
+				continue
+			}
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		obj[key] = value
+
+		// 9)
+		if key == KeywordContext {
+			nctx, err := s.p.context(ctx, graphCtx, json.NewDecoder(bytes.NewReader(value)), url, newCtxProcessingOpts())
+			if err != nil {
+				return nil, err
+			}
+			graphCtx = nctx
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	stream, err := s.streamable(ctx, graphCtx, obj, graphKey, graph)
+	if err != nil {
+		return nil, err
+	}
+
+	if stream {
+		return func() error {
+			r, done, err := graph.reader()
+			if err != nil {
+				return err
+			}
+			defer done()
+
+			return s.graph(ctx, graphCtx, json.NewDecoder(r), url)
+		}, nil
+	}
+
+	// not a graph we can stream, so expand it the regular way
+	if graph != nil {
+		value, err := graph.raw()
+		if err != nil {
+			return nil, err
+		}
+		obj[graphKey] = value
+	}
+
+	res, err := s.p.expandMap(ctx, ldCtx, "", obj, url, expandOptions{}, ldCtx.defs[""], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 19)
+	if len(res) == 1 && res[0].IsSimpleGraph() {
+		res = res[0].Graph
+	}
+
+	return func() error {
+		for _, node := range res {
+			if !s.emit(node) {
+				return nil
+			}
+		}
+		return nil
+	}, nil
+}
+
+// streamable reports if the @graph of a top-level object can be streamed.
+// The entries are looked at once the whole object has been read, as a
+// @context applies to the entries that come before it too.
+func (s *expandStream) streamable(
+	ctx context.Context,
+	activeCtx *Context,
+	obj json.Object,
+	graphKey string,
+	graph *graphValue,
+) (bool, error) {
+	if graph == nil {
+		return false, nil
+	}
+
+	if _, disallowed := s.p.disallowedKeys[KeywordGraph]; disallowed {
+		return false, nil
+	}
+
+	// the @context may have changed what the key expands to
+	prop, err := s.p.expandIRI(ctx, activeCtx, graphKey, false, true, nil, nil)
+	if err != nil {
+		return false, err
+	}
+
+	if prop != KeywordGraph {
+		return false, nil
+	}
+
+	for key := range obj {
+		if key == KeywordContext {
+			continue
+		}
+
+		// 13.3)
+		prop, err := s.p.expandIRI(ctx, activeCtx, key, false, true, nil, nil)
+		if err != nil {
+			return false, err
+		}
+
+		if prop != "" && (isKeyword(prop) || strings.Contains(prop, ":")) {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// readGraph reads ahead past the value of a @graph entry. When the document
+// can seek, only the position of the value is kept. Otherwise the value is
+// kept as is, without expanding it.
+func (s *expandStream) readGraph(dec *json.Decoder) (*graphValue, error) {
+	if s.seeker == nil {
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		return &graphValue{value: value}, nil
+	}
+
+	// the offset is right after the key, before the colon
+	start := s.base + dec.InputOffset()
+	if err := skipValue(dec); err != nil {
+		return nil, err
+	}
+
+	return &graphValue{
+		seeker: s.seeker,
+		start:  start,
+		end:    s.base + dec.InputOffset(),
+	}, nil
+}
+
+// skipValue reads past the next value without holding on to it.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch tok {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// graphValue is the value of a @graph entry that has been read ahead.
+type graphValue struct {
+	// value is set when the document can't seek
+	value json.RawMessage
+
+	seeker     io.ReadSeeker
+	start, end int64
+}
+
+// reader returns a reader for the value. The returned function puts the
+// document back where it was and must be called once done reading.
+func (g *graphValue) reader() (io.Reader, func(), error) {
+	if g.seeker == nil {
+		return bytes.NewReader(g.value), func() {}, nil
+	}
+
+	pos, err := g.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := g.seeker.Seek(g.start, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+
+	done := func() { _, _ = g.seeker.Seek(pos, io.SeekStart) }
+
+	r := bufio.NewReader(io.LimitReader(g.seeker, g.end-g.start))
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			done()
+			return nil, nil, err
+		}
+
+		if b == ':' {
+			return r, done, nil
+		}
+
+		if !isJSONSpace(b) {
+			done()
+			return nil, nil, fmt.Errorf("document changed while it was being read")
+		}
+	}
+}
+
+// raw returns the value in full.
+func (g *graphValue) raw() (json.RawMessage, error) {
+	if g.seeker == nil {
+		return g.value, nil
+	}
+
+	r, done, err := g.reader()
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	return io.ReadAll(r)
+}
+
+func isJSONSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// graph streams the value of the @graph entry of a top-level object.
+func (s *expandStream) graph(
+	ctx context.Context,
+	activeCtx *Context,
+	dec *json.Decoder,
+	url string,
+) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	termDef := activeCtx.defs[KeywordGraph]
+
+	switch tok {
+	case json.Delim('['):
+		return s.items(ctx, activeCtx, KeywordGraph, dec, url, s.emit)
+	case json.Delim('{'):
+		res, err := s.p.expandObject(ctx, activeCtx, KeywordGraph, dec, url, expandOptions{}, termDef, termDef.Context)
+		if err != nil {
+			return err
+		}
+
+		for _, node := range res {
+			if !s.emit(node) {
+				return nil
+			}
+		}
+		return nil
+	case json.Delim(']'), json.Delim('}'):
+		return ErrInvalidLocalContext
+	default:
+		// scalars and null expand to nothing
+		return nil
+	}
+}
@@ -0,0 +1,309 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// readers returns the ways a document is handed to ExpandSeq, one that can
+// seek and one that can't.
+func readers(doc []byte) map[string]func() io.Reader {
+	return map[string]func() io.Reader{
+		"seeker": func() io.Reader { return bytes.NewReader(doc) },
+		"reader": func() io.Reader { return struct{ io.Reader }{bytes.NewReader(doc)} },
+	}
+}
+
+func collect(t *testing.T, p *ld.Processor, doc io.Reader) ([]ld.Node, error) {
+	t.Helper()
+
+	res := []ld.Node{}
+	for node, err := range p.ExpandSeq(t.Context(), doc, "https://example.org/doc") {
+		if err != nil {
+			return res, err
+		}
+		res = append(res, node)
+	}
+
+	return res, nil
+}
+
+func TestExpandSeq(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{name: "array", doc: `[
+			{"@id": "https://example.org/1", "https://example.org/name": "one"},
+			"free-floating",
+			[{"@id": "https://example.org/2", "https://example.org/name": "two"}],
+			{"@id": "https://example.org/reference"},
+			{"@value": "free-floating"}
+		]`},
+		{name: "graph", doc: `{
+			"@context": {"@vocab": "https://example.org/", "items": "@graph"},
+			"items": [
+				{"@id": "https://example.org/1", "name": "one"},
+				{"@id": "https://example.org/2", "name": "two"},
+				{"@id": "https://example.org/reference"}
+			]
+		}`},
+		{name: "graph object", doc: `{
+			"@context": {"@vocab": "https://example.org/"},
+			"@graph": {"@id": "https://example.org/1", "name": "one"}
+		}`},
+		{name: "graph with ignored entry", doc: `{
+			"@context": {"@vocab": null},
+			"@graph": [{"@id": "https://example.org/1", "https://example.org/name": "one"}],
+			"ignored": true
+		}`},
+		{name: "single graph in array", doc: `[{
+			"@graph": [{"@id": "https://example.org/1", "https://example.org/name": "one"}]
+		}]`},
+		{name: "named graph", doc: `{
+			"@context": {"@vocab": "https://example.org/"},
+			"@id": "https://example.org/graph",
+			"@graph": [{"@id": "https://example.org/1", "name": "one"}]
+		}`},
+		{name: "node", doc: `{
+			"@context": {"@vocab": "https://example.org/"},
+			"@id": "https://example.org/1",
+			"name": "one"
+		}`},
+		{name: "graph before context", doc: `{
+			"@graph": [
+				{"@id": "https://example.org/1", "name": "one"},
+				{"@id": "https://example.org/2", "name": "two"}
+			],
+			"@context": {"@vocab": "https://example.org/"}
+		}`},
+		{name: "graph followed by entry", doc: `{
+			"@context": {"@vocab": "https://example.org/"},
+			"@graph": [{"@id": "https://example.org/1", "name": "one"}],
+			"@id": "https://example.org/graph"
+		}`},
+		{name: "graph followed by context", doc: `{
+			"items": [{"@id": "https://example.org/1", "name": "one"}],
+			"@context": {"@vocab": "https://example.org/", "items": "@graph"}
+		}`},
+		{name: "graph followed by property", doc: `{
+			"@context": {"@vocab": "https://example.org/"},
+			"@graph": [{"@id": "https://example.org/1", "name": "one"}],
+			"related": {"@id": "https://example.org/2"}
+		}`},
+		{name: "graph without context", doc: `{
+			"@graph": [{"@id": "https://example.org/1", "https://example.org/name": "one"}]
+		}`},
+		{name: "null graph", doc: `{"@graph": null}`},
+		{name: "empty array", doc: `[]`},
+		{name: "scalar", doc: `"string"`},
+	}
+
+	p := ld.NewProcessor(ld.WithOrdered(true))
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			want, err := p.Expand(t.Context(), strings.NewReader(tc.doc), "https://example.org/doc")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for name, r := range readers([]byte(tc.doc)) {
+				got, err := collect(t, p, r())
+				if err != nil {
+					t.Fatalf("%s: expected no error, got: %s", name, err)
+				}
+
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("%s: expansion mismatch (-want +got):\n%s", name, diff)
+				}
+			}
+		})
+	}
+}
+
+// TestExpandSeqW3C checks that streaming expansion gives the same result as
+// regular expansion for all inputs of the W3C expansion tests.
+func TestExpandSeqW3C(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "w3c", "expand", "*-in.jsonld"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := ld.NewProcessor(
+		ld.WithRemoteContextLoader(FileLoader(t)),
+		ld.WithOrdered(true),
+		ld.WithLogger(slog.New(slog.DiscardHandler)),
+	)
+
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			t.Parallel()
+
+			input, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			docIRI := "https://w3c.github.io/json-ld-api/tests/expand/" + filepath.Base(file)
+
+			want, wantErr := p.Expand(t.Context(), bytes.NewReader(input), docIRI)
+
+			for name, r := range readers(input) {
+				got := []ld.Node{}
+				var gotErr error
+				for node, err := range p.ExpandSeq(t.Context(), r(), docIRI) {
+					if err != nil {
+						gotErr = err
+						break
+					}
+					got = append(got, node)
+				}
+
+				if (wantErr == nil) != (gotErr == nil) {
+					t.Fatalf("%s: expected error: %v, got: %v", name, wantErr, gotErr)
+				}
+
+				if wantErr != nil {
+					continue
+				}
+
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("%s: expansion mismatch (-want +got):\n%s", name, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandSeqStreams(t *testing.T) {
+	pr, pw := io.Pipe()
+	received := make(chan struct{})
+
+	go func() {
+		// the first node of an array is held back until there's a second
+		pw.Write([]byte(`[{"@id": "https://example.org/1", "https://example.org/name": "one"},`))
+		pw.Write([]byte(`{"@id": "https://example.org/2", "https://example.org/name": "two"},`))
+
+		// the rest of the document isn't available until the first node
+		// has been received
+		<-received
+		pw.Write([]byte(`{"@id": "https://example.org/3", "https://example.org/name": "three"}]`))
+		pw.Close()
+	}()
+
+	var ids []string
+	for node, err := range ld.NewProcessor().ExpandSeq(t.Context(), pr, "") {
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		if len(ids) == 0 {
+			close(received)
+		}
+		ids = append(ids, node.ID)
+	}
+
+	if diff := cmp.Diff([]string{"https://example.org/1", "https://example.org/2", "https://example.org/3"}, ids); diff != "" {
+		t.Errorf("node mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExpandSeqSeekerOffset(t *testing.T) {
+	prefix := `{"header": true}`
+	r := strings.NewReader(prefix + `{
+		"@context": {"@vocab": "https://example.org/"},
+		"@graph": [{"@id": "https://example.org/1", "name": "one"}]
+	}`)
+
+	// the document starts after what has already been read
+	if _, err := r.Seek(int64(len(prefix)), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for node, err := range ld.NewProcessor().ExpandSeq(t.Context(), r, "") {
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+		ids = append(ids, node.ID)
+	}
+
+	if diff := cmp.Diff([]string{"https://example.org/1"}, ids); diff != "" {
+		t.Errorf("node mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExpandSeqErrors(t *testing.T) {
+	p := ld.NewProcessor()
+
+	t.Run("garbage after graph", func(t *testing.T) {
+		t.Parallel()
+
+		doc := []byte(`{
+			"@context": {"@vocab": "https://example.org/"},
+			"@graph": [{"@id": "https://example.org/1", "name": "one"}]
+		} {}`)
+
+		for name, r := range readers(doc) {
+			got, err := collect(t, p, r())
+			if err == nil {
+				t.Fatalf("%s: expected an error", name)
+			}
+
+			if len(got) != 0 {
+				t.Errorf("%s: expected no nodes before the error, got: %v", name, got)
+			}
+		}
+	})
+
+	t.Run("invalid node", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := collect(t, p, strings.NewReader(`[{"@id": "https://example.org/1"}, {"@id": true}]`))
+		if !errors.Is(err, ld.ErrInvalidIDValue) {
+			t.Fatalf("expected error: %s, got: %v", ld.ErrInvalidIDValue, err)
+		}
+	})
+
+	t.Run("trailing garbage", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := collect(t, p, strings.NewReader(`[] []`))
+		if err == nil {
+			t.Fatalf("expected an error")
+		}
+	})
+
+	t.Run("stop early", func(t *testing.T) {
+		t.Parallel()
+
+		count := 0
+		for _, err := range p.ExpandSeq(t.Context(), strings.NewReader(`[
+			{"@id": "https://example.org/1", "https://example.org/name": "one"},
+			{"@id": "https://example.org/2", "https://example.org/name": "two"},
+			{"@id": true}
+		]`), "") {
+			if err != nil {
+				t.Fatalf("expected no error, got: %s", err)
+			}
+			count++
+			break
+		}
+
+		if count != 1 {
+			t.Errorf("expected to stop after one node, got: %d", count)
+		}
+	})
+}
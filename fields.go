@@ -0,0 +1,198 @@
+package longdistance
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// field is a struct field with an ld struct tag.
+type field struct {
+	name  string
+	index []int
+	typ   reflect.Type
+	iri   string
+	term  string
+
+	omitEmpty bool
+	list      bool
+	ref       bool
+	datatype  string
+	language  string
+}
+
+// structFields caches the fields of struct types.
+var structFields sync.Map // map[reflect.Type]fieldsResult
+
+type fieldsResult struct {
+	fields []field
+	err    error
+}
+
+// fieldsOf returns the fields of a struct type that have an ld struct tag.
+//
+// The fields of embedded structs without a tag are included, like
+// encoding/json does.
+func fieldsOf(t reflect.Type) ([]field, error) {
+	if res, ok := structFields.Load(t); ok {
+		res := res.(fieldsResult)
+		return res.fields, res.err
+	}
+
+	fields, err := collectFields(t, nil, map[reflect.Type]struct{}{})
+	res, _ := structFields.LoadOrStore(t, fieldsResult{fields: fields, err: err})
+	return res.(fieldsResult).fields, res.(fieldsResult).err
+}
+
+// collectFields returns the fields of t, which is embedded at index. The
+// types being collected are kept in visiting, so a struct that embeds itself,
+// directly or through another struct, isn't followed again. encoding/json
+// ignores such fields too.
+func collectFields(t reflect.Type, index []int, visiting map[reflect.Type]struct{}) ([]field, error) {
+	visiting[t] = struct{}{}
+	defer delete(visiting, t)
+
+	var res []field
+
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		tag, hasTag := sf.Tag.Lookup("ld")
+		if tag == "-" {
+			continue
+		}
+
+		idx := append(append([]int(nil), index...), i)
+
+		if sf.Anonymous && !hasTag {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if _, ok := visiting[ft]; ok {
+				continue
+			}
+
+			if ft.Kind() == reflect.Struct {
+				nested, err := collectFields(ft, idx, visiting)
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, nested...)
+			}
+			continue
+		}
+
+		if !hasTag || !sf.IsExported() {
+			continue
+		}
+
+		f, err := parseField(sf, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		f.index = idx
+		res = append(res, f)
+	}
+
+	return res, nil
+}
+
+// parseField parses an ld struct tag. The tag holds the expanded IRI of the
+// property, or one of the keywords @id, @type and @index, followed by
+// comma-separated options:
+//   - omitempty omits the property when encoding a zero value.
+//   - list encodes a slice as an @list.
+//   - ref encodes a struct or string as a reference to a node.
+//   - type=<IRI> encodes a value as a typed literal, or @json.
+//   - lang=<tag> encodes a string with a language.
+func parseField(sf reflect.StructField, tag string) (field, error) {
+	iri, opts, _ := strings.Cut(tag, ",")
+	if iri == "" {
+		return field{}, fmt.Errorf("field %s: missing IRI in ld tag", sf.Name)
+	}
+
+	f := field{
+		name: sf.Name,
+		typ:  sf.Type,
+		iri:  iri,
+		term: termName(sf),
+	}
+
+	for opt := range strings.SplitSeq(opts, ",") {
+		name, value, _ := strings.Cut(opt, "=")
+		switch name {
+		case "":
+			if opts != "" {
+				return field{}, fmt.Errorf("field %s: empty option in ld tag %q", sf.Name, tag)
+			}
+		case "omitempty":
+			f.omitEmpty = true
+		case "list":
+			f.list = true
+		case "ref":
+			f.ref = true
+		case "type":
+			if value == "" || isKeyword(value) && value != KeywordJSON {
+				return field{}, fmt.Errorf("field %s: invalid type %q in ld tag", sf.Name, value)
+			}
+			f.datatype = value
+		case "lang":
+			if value == "" {
+				return field{}, fmt.Errorf("field %s: missing language in ld tag", sf.Name)
+			}
+			f.language = value
+		default:
+			return field{}, fmt.Errorf("field %s: unknown option %q in ld tag", sf.Name, opt)
+		}
+	}
+
+	if f.datatype != "" && f.language != "" {
+		return field{}, fmt.Errorf("field %s: type and lang can't be combined in ld tag", sf.Name)
+	}
+
+	if f.ref && (f.datatype != "" || f.language != "") {
+		return field{}, fmt.Errorf("field %s: ref can't be combined with type or lang in ld tag", sf.Name)
+	}
+
+	if isKeyword(iri) {
+		switch iri {
+		case KeywordID, KeywordType, KeywordIndex:
+		default:
+			return field{}, fmt.Errorf("field %s: unsupported keyword %s in ld tag", sf.Name, iri)
+		}
+
+		if f.list || f.ref || f.datatype != "" || f.language != "" {
+			return field{}, fmt.Errorf("field %s: only omitempty is supported for %s in ld tag", sf.Name, iri)
+		}
+	}
+
+	return f, nil
+}
+
+// termName returns the term used for a field in a generated context. It's
+// the name from the json struct tag if there is one, and the field name in
+// lower camel case otherwise.
+func termName(sf reflect.StructField) string {
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+
+	// lowercase the leading run of capitals, leaving the last one if it
+	// starts the next word, so ID becomes id and HTMLBody becomes htmlBody
+	r := []rune(sf.Name)
+	for i := range r {
+		if !unicode.IsUpper(r[i]) {
+			break
+		}
+		if i > 0 && i+1 < len(r) && unicode.IsLower(r[i+1]) {
+			break
+		}
+		r[i] = unicode.ToLower(r[i])
+	}
+
+	return string(r)
+}
@@ -0,0 +1,417 @@
+package longdistance
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// Flatten transforms a list of [Node] into JSON-LD flattened document form.
+//
+// All node objects are collected into a single, flat list. Nested node
+// objects are replaced by a reference to their @id and nodes that share an
+// @id are merged. Blank nodes are relabelled in the process. Nodes in named
+// graphs are collected in the @graph of the node named after the graph.
+//
+// When compactionCtx is nil, the result is written to dst in expanded
+// document form. Otherwise the result is compacted like [Processor.Compact]
+// does, but the nodes are always returned in a top-level @graph.
+func (p *Processor) Flatten(
+	ctx context.Context,
+	dst io.Writer,
+	compactionCtx json.RawMessage,
+	document []Node,
+	documentURL string,
+) error {
+	ctx = withRemoteContexts(ctx)
+
+	flattened, err := p.flatten(document)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(dst)
+
+	if compactionCtx == nil {
+		return enc.Encode(flattened)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(compactionCtx))
+	ldCtx, err := p.context(ctx, nil, dec, documentURL, newCtxProcessingOpts())
+	if err != nil {
+		return err
+	}
+
+	if ldCtx == nil {
+		return enc.Encode(flattened)
+	}
+
+	res, err := p.compactDocument(ctx, ldCtx, compactionCtx, flattened, true)
+	if err != nil {
+		return err
+	}
+
+	return enc.Encode(res)
+}
+
+// flatten implements the flattening algorithm.
+func (p *Processor) flatten(document []Node) ([]Node, error) {
+	// 1)
+	nm := newNodeMap()
+
+	// 2)
+	if err := nm.generate(document, KeywordDefault, "", nil, "", nil); err != nil {
+		return nil, err
+	}
+
+	// 3)
+	defaultGraph := nm.graphs[KeywordDefault]
+
+	// 4)
+	for _, graphName := range slices.Sorted(maps.Keys(nm.graphs)) {
+		if graphName == KeywordDefault {
+			continue
+		}
+
+		// 4.1)
+		entry, ok := defaultGraph[graphName]
+		if !ok {
+			entry = &Node{ID: graphName}
+			defaultGraph[graphName] = entry
+		}
+
+		// 4.2) 4.3) 4.4)
+		entry.Graph = nm.nodes(graphName)
+	}
+
+	// 5) 6) 7)
+	return nm.nodes(KeywordDefault), nil
+}
+
+// nodeMap holds the result of the node map generation algorithm.
+//
+// It's a map of graph name to a map of subject to node.
+type nodeMap struct {
+	graphs map[string]map[string]*Node
+	issuer *blankNodeIssuer
+}
+
+func newNodeMap() *nodeMap {
+	return &nodeMap{
+		graphs: map[string]map[string]*Node{
+			KeywordDefault: {},
+		},
+		issuer: newBlankNodeIssuer("_:b"),
+	}
+}
+
+// nodes returns the nodes in a graph ordered by @id, skipping any node that
+// only has an @id.
+func (nm *nodeMap) nodes(graphName string) []Node {
+	graph := nm.graphs[graphName]
+
+	res := make([]Node, 0, len(graph))
+	for _, id := range slices.Sorted(maps.Keys(graph)) {
+		node := graph[id]
+		if node.Len() == 1 && node.Has(KeywordID) {
+			continue
+		}
+		res = append(res, *node)
+	}
+
+	return res
+}
+
+func (nm *nodeMap) graph(name string) map[string]*Node {
+	graph, ok := nm.graphs[name]
+	if !ok {
+		graph = map[string]*Node{}
+		nm.graphs[name] = graph
+	}
+
+	return graph
+}
+
+// generate implements the node map generation algorithm.
+//
+// When reverseSubject is set, the element is the value of a reverse property
+// of reverseSubject and activeSubject is ignored.
+func (nm *nodeMap) generate(
+	elements []Node,
+	activeGraph string,
+	activeSubject string,
+	reverseSubject *Node,
+	activeProperty string,
+	list *Node,
+) error {
+	// 1)
+	for _, element := range elements {
+		if err := nm.generateNode(
+			element,
+			activeGraph,
+			activeSubject,
+			reverseSubject,
+			activeProperty,
+			list,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (nm *nodeMap) generateNode(
+	element Node,
+	activeGraph string,
+	activeSubject string,
+	reverseSubject *Node,
+	activeProperty string,
+	list *Node,
+) error {
+	// 2)
+	graph := nm.graph(activeGraph)
+
+	var subjectNode *Node
+	if activeSubject != "" {
+		subjectNode = graph[activeSubject]
+	}
+
+	// 3)
+	if element.Type != nil {
+		types := make([]string, 0, len(element.Type))
+		for _, t := range element.Type {
+			if strings.HasPrefix(t, BlankNode) {
+				t = nm.issuer.issue(t)
+			}
+			types = append(types, t)
+		}
+		element.Type = types
+	}
+
+	if element.Has(KeywordValue) {
+		// 4)
+		if list != nil {
+			// 4.2)
+			list.List = append(list.List, element)
+			return nil
+		}
+
+		// 4.1)
+		if subjectNode != nil {
+			subjectNode.addUniqueNode(activeProperty, element)
+		}
+
+		return nil
+	}
+
+	if element.Has(KeywordList) {
+		// 5.1)
+		result := &Node{List: []Node{}}
+
+		// 5.2)
+		if err := nm.generate(
+			element.List,
+			activeGraph,
+			activeSubject,
+			reverseSubject,
+			activeProperty,
+			result,
+		); err != nil {
+			return err
+		}
+
+		// 5.3)
+		if list != nil {
+			list.List = append(list.List, *result)
+		} else if subjectNode != nil {
+			subjectNode.addNode(activeProperty, *result)
+		}
+
+		return nil
+	}
+
+	// 6.1)
+	var id string
+	if element.Has(KeywordID) {
+		id = element.ID
+		if strings.HasPrefix(id, BlankNode) {
+			id = nm.issuer.issue(id)
+		}
+	} else {
+		id = nm.issuer.issue("")
+	}
+
+	// 6.2)
+	node, ok := graph[id]
+	if !ok {
+		node = &Node{ID: id}
+		graph[id] = node
+	}
+
+	if reverseSubject != nil {
+		// 6.4)
+		node.addUniqueNode(activeProperty, *reverseSubject)
+	} else if activeProperty != "" {
+		// 6.5.1)
+		reference := Node{ID: id}
+
+		// 6.5.2) 6.5.3)
+		if list != nil {
+			list.List = append(list.List, reference)
+		} else if subjectNode != nil {
+			subjectNode.addUniqueNode(activeProperty, reference)
+		}
+	}
+
+	// 6.6)
+	for _, t := range element.Type {
+		if !slices.Contains(node.Type, t) {
+			node.Type = append(node.Type, t)
+		}
+	}
+
+	// 6.7)
+	if element.Has(KeywordIndex) {
+		if node.Has(KeywordIndex) && node.Index != element.Index {
+			return ErrConflictingIndexes
+		}
+		node.Index = element.Index
+	}
+
+	// 6.8)
+	if element.Has(KeywordReverse) {
+		// 6.8.1)
+		referenced := &Node{ID: id}
+
+		// 6.8.2) 6.8.3)
+		for _, property := range slices.Sorted(maps.Keys(element.Reverse)) {
+			// 6.8.3.1)
+			if err := nm.generate(
+				element.Reverse[property],
+				activeGraph,
+				"",
+				referenced,
+				property,
+				nil,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 6.9)
+	if element.Has(KeywordGraph) {
+		nm.graph(id)
+		if err := nm.generate(element.Graph, id, "", nil, "", nil); err != nil {
+			return err
+		}
+	}
+
+	// 6.10)
+	if element.Has(KeywordIncluded) {
+		if err := nm.generate(element.Included, activeGraph, "", nil, "", nil); err != nil {
+			return err
+		}
+	}
+
+	// 6.11)
+	for _, property := range slices.Sorted(maps.Keys(element.Properties)) {
+		value := element.Properties[property]
+
+		// 6.11.1)
+		if strings.HasPrefix(property, BlankNode) {
+			property = nm.issuer.issue(property)
+		}
+
+		// 6.11.2)
+		if !node.Has(property) {
+			node.addNode(property)
+		}
+
+		// 6.11.3)
+		if err := nm.generate(value, activeGraph, id, nil, property, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// addNode appends nodes to a property, initialising the properties if
+// necessary.
+func (n *Node) addNode(property string, nodes ...Node) {
+	if n.Properties == nil {
+		n.Properties = make(Properties, 4)
+	}
+
+	if _, ok := n.Properties[property]; !ok {
+		n.Properties[property] = make([]Node, 0, len(nodes))
+	}
+
+	n.AddNodes(property, nodes...)
+}
+
+// addUniqueNode appends the node to a property, unless an equal node is
+// already present.
+func (n *Node) addUniqueNode(property string, node Node) {
+	if slices.ContainsFunc(n.Properties[property], node.equalValueOrReference) {
+		return
+	}
+
+	n.addNode(property, node)
+}
+
+// equalValueOrReference returns if two value objects or node references
+// are equal.
+func (n Node) equalValueOrReference(o Node) bool {
+	return n.ID == o.ID &&
+		bytes.Equal(n.Value, o.Value) &&
+		slices.Equal(n.Type, o.Type) &&
+		n.Language == o.Language &&
+		n.Direction == o.Direction &&
+		n.Index == o.Index &&
+		n.Len() == o.Len()
+}
+
+// blankNodeIssuer issues new blank node identifiers.
+//
+// It keeps track of the identifiers it has issued so that the same input
+// identifier always results in the same new identifier.
+type blankNodeIssuer struct {
+	prefix  string
+	counter int
+	issued  map[string]string
+}
+
+func newBlankNodeIssuer(prefix string) *blankNodeIssuer {
+	return &blankNodeIssuer{
+		prefix: prefix,
+		issued: make(map[string]string, 8),
+	}
+}
+
+// issue returns the new identifier for an existing identifier. If the
+// identifier is empty, a fresh identifier is always returned.
+func (b *blankNodeIssuer) issue(existing string) string {
+	if existing != "" {
+		if id, ok := b.issued[existing]; ok {
+			return id
+		}
+	}
+
+	id := b.prefix + strconv.Itoa(b.counter)
+	b.counter++
+
+	if existing != "" {
+		b.issued[existing] = id
+	}
+
+	return id
+}
@@ -0,0 +1,136 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// flattenSkip lists the W3C flattening tests that are skipped, with the reason
+// why.
+var flattenSkip = map[string]string{}
+
+// TestFlatten runs the W3C flattening tests.
+func TestFlatten(t *testing.T) {
+	RunManifest(t, "w3c/flatten-manifest.jsonld", flattenSkip, func(t *testing.T, tc ManifestTest) {
+		p := tc.Processor(t)
+
+		var compCtx json.RawMessage
+		if tc.Context != "" {
+			compCtx = tc.LoadContext(t, tc.Context)
+		}
+
+		var dst bytes.Buffer
+		nodes, err := p.Expand(t.Context(), bytes.NewReader(LoadData(t, tc.File(tc.Input))), tc.DocumentIRI())
+		if err == nil {
+			err = p.Flatten(t.Context(), &dst, compCtx, nodes, tc.DocumentIRI())
+		}
+
+		if tc.CheckError(t, err) {
+			return
+		}
+
+		if diff := cmp.Diff(LoadData(t, tc.File(tc.Expect)), json.RawMessage(dst.Bytes()), JSONDiff()); diff != "" {
+			if *dump {
+				t.Logf("flattened to: %s", dst.String())
+			}
+			t.Errorf("flattening mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// TestFlattenLocal covers flattening of documents outside of the W3C test
+// suite.
+func TestFlattenLocal(t *testing.T) {
+	tests := []struct {
+		name    string
+		proc    *ld.Processor
+		in      json.RawMessage
+		context json.RawMessage
+		out     json.RawMessage
+		err     error
+	}{
+		{
+			name: "values",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "w3c/expand/0002-in.jsonld"),
+			out:  LoadData(t, "w3c/expand/0002-out.jsonld"),
+		},
+		{
+			name: "merge nodes with the same @id",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "w3c/expand/0009-in.jsonld"),
+			out:  LoadData(t, "longdistance/flatten/merge/out.json"),
+		},
+		{
+			name: "named graph",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "w3c/expand/0020-in.jsonld"),
+			out:  LoadData(t, "longdistance/flatten/named-graph/out.json"),
+		},
+		{
+			name: "deduplicate sets but not lists",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "w3c/expand/0027-in.jsonld"),
+			out:  LoadData(t, "longdistance/flatten/set-list/out.json"),
+		},
+		{
+			name: "reverse properties",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "w3c/expand/0037-in.jsonld"),
+			out:  LoadData(t, "longdistance/flatten/reverse/out.json"),
+		},
+		{
+			name: "included blank nodes",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "w3c/expand/in01-in.jsonld"),
+			out:  LoadData(t, "longdistance/flatten/included/out.json"),
+		},
+		{
+			name: "conflicting indexes",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/flatten/conflicting-indexes/in.json"),
+			err:  ld.ErrConflictingIndexes,
+		},
+		{
+			name: "compacted activity",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:      LoadData(t, "longdistance/flatten/activity/in.json"),
+			context: json.RawMessage(`"https://www.w3.org/ns/activitystreams"`),
+			out:     LoadData(t, "longdistance/flatten/activity/out.json"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := tc.proc.Expand(t.Context(), bytes.NewReader(tc.in), "")
+			if err != nil {
+				t.Fatalf("expected successful expand, got: %s", err)
+			}
+
+			var dst bytes.Buffer
+			err = tc.proc.Flatten(t.Context(), &dst, tc.context, nodes, "")
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+
+			if tc.err == nil {
+				if diff := cmp.Diff(tc.out, json.RawMessage(dst.Bytes()), JSONDiff()); diff != "" {
+					if *dump {
+						t.Logf("flattened to: %s", dst.String())
+					}
+					t.Errorf("flattening mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,929 @@
+package longdistance
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"sourcery.dny.nu/longdistance/internal/iri"
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// mergedGraph is the name of the graph that holds all nodes of all graphs
+// merged together.
+const mergedGraph = "@merged"
+
+// Frame transforms a list of [Node] into a tree shaped like the frame using
+// the JSON-LD 1.1 framing algorithm.
+//
+// The document is flattened first. Every node that matches the frame on @id,
+// @type or its properties becomes a top-level result. Nodes referenced by a
+// result are embedded into it according to the frame, or the @embed,
+// @explicit, @omitDefault and @requireAll flags. Properties in the frame that
+// are missing from a result are added with their @default value, or null.
+//
+// The result is compacted with the @context of the frame and written to dst.
+// When the framing results in a single node it is not wrapped in a top-level
+// @graph, unless the processing mode is JSON-LD 1.0. Change this with
+// [WithFrameOmitGraph].
+func (p *Processor) Frame(
+	ctx context.Context,
+	dst io.Writer,
+	frame json.RawMessage,
+	document []Node,
+	documentURL string,
+) error {
+	ctx = withRemoteContexts(ctx)
+
+	baseIRI := cmp.Or(p.baseIRI, documentURL)
+
+	var obj json.Object
+	if json.IsArray(frame) {
+		var arr json.Array
+		if err := json.Unmarshal(frame, &arr); err != nil {
+			return ErrInvalidFrame
+		}
+
+		if len(arr) != 1 {
+			return ErrInvalidFrame
+		}
+		frame = arr[0]
+	}
+
+	if !json.IsMap(frame) {
+		return ErrInvalidFrame
+	}
+
+	if err := json.Unmarshal(frame, &obj); err != nil {
+		return ErrInvalidFrame
+	}
+
+	compactionCtx, ok := obj[KeywordContext]
+	if !ok || json.IsNull(compactionCtx) {
+		compactionCtx = json.RawMessage(`{}`)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(compactionCtx))
+	ldCtx, err := p.context(ctx, nil, dec, baseIRI, newCtxProcessingOpts())
+	if err != nil {
+		return err
+	}
+
+	if ldCtx == nil {
+		ldCtx = newContext(baseIRI)
+	}
+
+	expanded, err := p.expandRaw(ctx, newContext(baseIRI), "", frame, baseIRI, expandOptions{frameExpansion: true})
+	if err != nil {
+		return err
+	}
+
+	if len(expanded) != 1 {
+		return ErrInvalidFrame
+	}
+
+	fr, err := newFrame(expanded[0])
+	if err != nil {
+		return err
+	}
+
+	framed, err := p.frame(document, fr)
+	if err != nil {
+		return err
+	}
+
+	omitGraph := !p.modeLD10
+	if p.frameOmitGraph != nil {
+		omitGraph = *p.frameOmitGraph
+	}
+
+	res, err := p.compactDocument(
+		ctx,
+		ldCtx,
+		compactionCtx,
+		framed,
+		!omitGraph || len(framed) != 1,
+	)
+	if err != nil {
+		return err
+	}
+
+	for key, value := range res {
+		if key == KeywordContext {
+			continue
+		}
+		res[key] = cleanupPreserve(value)
+	}
+
+	return json.NewEncoder(dst).Encode(res)
+}
+
+// frame implements the framing algorithm, returning the framed nodes in
+// expanded document form.
+func (p *Processor) frame(document []Node, fr *frame) ([]Node, error) {
+	nm := newNodeMap()
+	if err := nm.generate(document, KeywordDefault, "", nil, "", nil); err != nil {
+		return nil, err
+	}
+
+	f := &framer{
+		graphs: nm.graphs,
+		graph:  KeywordDefault,
+		flags:  p.frameFlags,
+		bnodes: map[string]int{},
+	}
+
+	// if the frame only holds a @graph, frame the default graph with it.
+	// Otherwise the frame applies to all graphs merged together.
+	if fr.graphOnly {
+		fr = firstFrame(fr.graph)
+	} else if fr.graph == nil {
+		f.graphs[mergedGraph] = mergeGraphs(nm.graphs)
+		f.graph = mergedGraph
+	}
+
+	result := f.frame(slices.Sorted(maps.Keys(f.graphs[f.graph])), fr, f.flags, true)
+
+	if !p.modeLD10 {
+		prune := make(map[string]struct{}, len(f.bnodes))
+		for id, count := range f.bnodes {
+			if count == 1 {
+				prune[id] = struct{}{}
+			}
+		}
+
+		for i := range result {
+			pruneBlankNodes(&result[i], prune)
+		}
+	}
+
+	return result, nil
+}
+
+// pattern holds the values of an @id, @type, @value or @language entry in a
+// frame.
+type pattern struct {
+	set      bool     // the entry is present in the frame
+	wildcard bool     // the entry is the empty map
+	values   []string // the entry matches any of these values
+}
+
+// empty returns if the pattern can only match the absence of a value.
+func (p pattern) empty() bool {
+	return !p.wildcard && len(p.values) == 0
+}
+
+// matches returns if the pattern matches a value, where the empty string
+// represents the absence of a value.
+func (p pattern) matches(value string) bool {
+	if value == "" {
+		return p.empty()
+	}
+
+	return p.wildcard || slices.Contains(p.values, value)
+}
+
+// frame is an expanded frame.
+type frame struct {
+	id          pattern
+	typ         pattern
+	defaultType []string
+	value       pattern
+	language    pattern
+
+	embed       string
+	explicit    null[bool]
+	omitDefault null[bool]
+	requireAll  null[bool]
+
+	hasDefault bool
+	defaults   []Node
+
+	graph      []*frame
+	graphOnly  bool
+	included   []*frame
+	list       []*frame
+	reverse    map[string][]*frame
+	properties map[string][]*frame
+}
+
+// firstFrame returns the first frame, or an empty frame that matches
+// anything.
+func firstFrame(frames []*frame) *frame {
+	if len(frames) == 0 {
+		return &frame{}
+	}
+
+	return frames[0]
+}
+
+// isValuePattern returns if the frame matches value objects.
+func (f *frame) isValuePattern() bool {
+	return f.value.set || f.language.set
+}
+
+// isWildcard returns if the frame matches any node.
+func (f *frame) isWildcard() bool {
+	return !f.id.set && !f.typ.set && !f.isValuePattern() &&
+		f.list == nil && len(f.properties) == 0
+}
+
+// frameFlags holds the resolved framing flags.
+type frameFlags struct {
+	embed       string
+	explicit    bool
+	omitDefault bool
+	requireAll  bool
+}
+
+// flags returns the flags for the frame, falling back to the flags of the
+// parent for anything the frame doesn't set.
+//
+// The parent of an implicit frame is the frame it was created for. For any
+// other frame they're the default flags.
+func (f *frame) flags(parent frameFlags) frameFlags {
+	res := frameFlags{
+		embed:       cmp.Or(f.embed, parent.embed),
+		explicit:    parent.explicit,
+		omitDefault: parent.omitDefault,
+		requireAll:  parent.requireAll,
+	}
+
+	if f.explicit.Set {
+		res.explicit = f.explicit.Value
+	}
+
+	if f.omitDefault.Set {
+		res.omitDefault = f.omitDefault.Value
+	}
+
+	if f.requireAll.Set {
+		res.requireAll = f.requireAll.Value
+	}
+
+	return res
+}
+
+// newFrame turns a frame that has been expanded with the frame expansion
+// flag set into a [frame] that nodes can be matched against.
+func newFrame(node Node) (*frame, error) {
+	result := &frame{}
+
+	// a scalar in a frame expands to a node reference or a value object
+	// that has to be matched exactly
+	if node.Has(KeywordID) {
+		result.id = pattern{set: true, values: []string{node.ID}}
+		return result, nil
+	}
+
+	if node.Has(KeywordValue) {
+		result.value = pattern{set: true, values: []string{normaliseJSON(node.Value)}}
+
+		if node.Type != nil {
+			result.typ = pattern{set: true, values: node.Type}
+		}
+
+		if node.Language != "" {
+			result.language = pattern{set: true, values: []string{strings.ToLower(node.Language)}}
+		}
+
+		return result, nil
+	}
+
+	var err error
+
+	for key, values := range entries(node.Properties, true) {
+		switch key {
+		case KeywordID:
+			result.id = newPattern(values, func(n Node) string { return n.ID })
+			if !validFrameIRIs(result.id.values) {
+				return nil, ErrInvalidFrame
+			}
+		case KeywordType:
+			if len(values) == 1 && values[0].Has(KeywordDefault) {
+				defaults := values[0].Properties[KeywordDefault]
+				result.typ = pattern{set: true}
+				result.defaultType = []string{defaults[0].ID}
+			} else {
+				result.typ = newPattern(values, func(n Node) string { return n.ID })
+			}
+
+			if !validFrameIRIs(result.typ.values) || !validFrameIRIs(result.defaultType) {
+				return nil, ErrInvalidFrame
+			}
+		case KeywordValue:
+			result.value = newPattern(values, func(n Node) string { return normaliseJSON(n.Value) })
+		case KeywordLanguage:
+			result.language = newPattern(values, func(n Node) string { return n.Language })
+		case KeywordDefault:
+			result.hasDefault = true
+			result.defaults = values
+		case KeywordEmbed:
+			if result.embed, err = expandEmbed(values); err != nil {
+				return nil, err
+			}
+		case KeywordExplicit:
+			if err := expandFrameFlag(values, &result.explicit); err != nil {
+				return nil, err
+			}
+		case KeywordOmitDefault:
+			if err := expandFrameFlag(values, &result.omitDefault); err != nil {
+				return nil, err
+			}
+		case KeywordRequireAll:
+			if err := expandFrameFlag(values, &result.requireAll); err != nil {
+				return nil, err
+			}
+		default:
+			if result.properties == nil {
+				result.properties = make(map[string][]*frame, len(node.Properties))
+			}
+
+			if result.properties[key], err = newFrames(values); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if node.Graph != nil {
+		if result.graph, err = newFrames(node.Graph); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.Included != nil {
+		if result.included, err = newFrames(node.Included); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.List != nil {
+		if result.list, err = newFrames(node.List); err != nil {
+			return nil, err
+		}
+	}
+
+	if node.Reverse != nil {
+		result.reverse = make(map[string][]*frame, len(node.Reverse))
+		for prop, values := range entries(node.Reverse, true) {
+			if result.reverse[prop], err = newFrames(values); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	result.graphOnly = node.Graph != nil && len(node.propsWithout(
+		KeywordGraph, KeywordEmbed, KeywordExplicit, KeywordOmitDefault, KeywordRequireAll,
+	)) == 0
+
+	return result, nil
+}
+
+// newFrames turns each of the expanded frames into a [frame].
+func newFrames(nodes []Node) ([]*frame, error) {
+	result := make([]*frame, 0, len(nodes))
+	for _, node := range nodes {
+		fr, err := newFrame(node)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, fr)
+	}
+
+	return result, nil
+}
+
+// newPattern turns the expanded value of @id, @type, @value or @language
+// into a pattern. An empty node is the wildcard.
+func newPattern(values []Node, value func(Node) string) pattern {
+	if len(values) == 1 && values[0].IsZero() {
+		return pattern{set: true, wildcard: true}
+	}
+
+	result := pattern{set: true, values: make([]string, 0, len(values))}
+	for _, v := range values {
+		result.values = append(result.values, value(v))
+	}
+
+	return result
+}
+
+func expandEmbed(values []Node) (string, error) {
+	if len(values) != 1 {
+		return "", ErrInvalidEmbedValue
+	}
+
+	var embed any
+	if err := json.Unmarshal(values[0].Value, &embed); err != nil {
+		return "", ErrInvalidEmbedValue
+	}
+
+	switch v := embed.(type) {
+	case bool:
+		if v {
+			return KeywordOnce, nil
+		}
+		return KeywordNever, nil
+	case string:
+		switch v {
+		case KeywordAlways, KeywordOnce, KeywordNever:
+			return v, nil
+		}
+	}
+
+	return "", ErrInvalidEmbedValue
+}
+
+func expandFrameFlag(values []Node, flag *null[bool]) error {
+	if len(values) != 1 {
+		return ErrInvalidFrame
+	}
+
+	var b bool
+	if err := json.Unmarshal(values[0].Value, &b); err != nil {
+		return ErrInvalidFrame
+	}
+
+	flag.Set = true
+	flag.Valid = true
+	flag.Value = b
+
+	return nil
+}
+
+// validFrameIRIs returns if all values are absolute IRIs. Blank node
+// identifiers can't be matched on since they're relabelled during framing.
+func validFrameIRIs(values []string) bool {
+	for _, value := range values {
+		if strings.HasPrefix(value, BlankNode) || !iri.IsAbsolute(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func normaliseJSON(raw json.RawMessage) string {
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return string(raw)
+	}
+
+	res, _ := json.Marshal(value)
+	return string(res)
+}
+
+// framer holds the state of the framing algorithm.
+type framer struct {
+	graphs map[string]map[string]*Node
+	graph  string
+	// flags are the flags used for any frame that doesn't set them.
+	flags frameFlags
+
+	// embedded tracks the nodes that have been embedded per graph, for the
+	// current top-level node.
+	embedded map[string]map[string]struct{}
+	// stack holds the nodes that are being framed, in order to detect
+	// circular references.
+	stack []frameSubject
+	// bnodes tracks how often a blank node identifier occurs in the output.
+	bnodes map[string]int
+}
+
+type frameSubject struct {
+	id    string
+	graph string
+}
+
+func (f *framer) frame(
+	subjects []string,
+	fr *frame,
+	parent frameFlags,
+	top bool,
+) []Node {
+	flags := fr.flags(parent)
+	graph := f.graphs[f.graph]
+
+	result := []Node{}
+
+	for _, id := range subjects {
+		subject, ok := graph[id]
+		if !ok || !f.filter(subject, fr, flags.requireAll) {
+			continue
+		}
+
+		if top {
+			f.embedded = map[string]map[string]struct{}{}
+		}
+
+		embedded, ok := f.embedded[f.graph]
+		if !ok {
+			embedded = map[string]struct{}{}
+			f.embedded[f.graph] = embedded
+		}
+
+		output := Node{ID: id}
+
+		if strings.HasPrefix(id, BlankNode) {
+			f.bnodes[id]++
+		}
+
+		if flags.embed == KeywordNever || f.circular(id) {
+			result = append(result, output)
+			continue
+		}
+
+		if _, ok := embedded[id]; ok && flags.embed == KeywordOnce {
+			result = append(result, output)
+			continue
+		}
+
+		embedded[id] = struct{}{}
+		f.stack = append(f.stack, frameSubject{id: id, graph: f.graph})
+
+		// the subject is also the name of a graph
+		if nodes, ok := f.graphs[id]; ok {
+			recurse := f.graph != mergedGraph
+			subframe := &frame{}
+
+			if fr.graph != nil {
+				recurse = id != mergedGraph && id != KeywordDefault
+				subframe = firstFrame(fr.graph)
+			}
+
+			if recurse {
+				previous := f.graph
+				f.graph = id
+
+				res := f.frame(slices.Sorted(maps.Keys(nodes)), subframe, f.flags, false)
+				if len(res) > 0 {
+					output.Graph = res
+				}
+
+				f.graph = previous
+			}
+		}
+
+		if fr.included != nil {
+			res := f.frame(subjects, firstFrame(fr.included), f.flags, false)
+			if len(res) > 0 {
+				output.Included = res
+			}
+		}
+
+		if subject.Type != nil {
+			output.Type = slices.Clone(subject.Type)
+			for _, t := range subject.Type {
+				if strings.HasPrefix(t, BlankNode) {
+					f.bnodes[t]++
+				}
+			}
+		}
+
+		output.Index = subject.Index
+
+		for _, prop := range slices.Sorted(maps.Keys(subject.Properties)) {
+			subframes, inFrame := fr.properties[prop]
+			if flags.explicit && !inFrame {
+				continue
+			}
+
+			subframe, subflags := firstFrame(subframes), f.flags
+			if !inFrame {
+				subframe, subflags = &frame{}, flags
+			}
+
+			for _, o := range subject.Properties[prop] {
+				switch {
+				case o.IsList():
+					listFrame, listFlags := &frame{}, flags
+					if subframe.list != nil {
+						listFrame, listFlags = firstFrame(subframe.list), f.flags
+					}
+
+					list := Node{List: []Node{}}
+					for _, item := range o.List {
+						if item.IsSubjectReference() {
+							list.List = append(list.List, f.frame([]string{item.ID}, listFrame, listFlags, false)...)
+						} else {
+							list.List = append(list.List, item)
+						}
+					}
+
+					output.addNode(prop, list)
+				case o.IsSubjectReference():
+					if res := f.frame([]string{o.ID}, subframe, subflags, false); len(res) > 0 {
+						output.addNode(prop, res...)
+					}
+				default:
+					if valueMatch(subframe, o) {
+						output.addNode(prop, o)
+					}
+				}
+			}
+		}
+
+		// add defaults for the properties that are missing
+		for _, prop := range slices.Sorted(maps.Keys(fr.properties)) {
+			next := firstFrame(fr.properties[prop])
+			if next.flags(flags).omitDefault || output.Has(prop) {
+				continue
+			}
+
+			preserve := next.defaults
+			if preserve == nil {
+				preserve = []Node{{Value: json.RawMessage(`"` + KeywordNull + `"`)}}
+			}
+
+			output.addNode(prop, Node{
+				Properties: Properties{KeywordPreserve: preserve},
+			})
+		}
+
+		if fr.defaultType != nil && output.Type == nil {
+			output.Type = slices.Clone(fr.defaultType)
+		}
+
+		// embed the nodes that have this subject as the value of the reverse
+		// property
+		for _, prop := range slices.Sorted(maps.Keys(fr.reverse)) {
+			subframe := firstFrame(fr.reverse[prop])
+
+			for _, sid := range slices.Sorted(maps.Keys(graph)) {
+				if !slices.ContainsFunc(graph[sid].Properties[prop], func(n Node) bool {
+					return n.ID == id
+				}) {
+					continue
+				}
+
+				if output.Reverse == nil {
+					output.Reverse = make(Properties, len(fr.reverse))
+				}
+
+				output.Reverse[prop] = append(output.Reverse[prop], f.frame([]string{sid}, subframe, f.flags, false)...)
+			}
+		}
+
+		result = append(result, output)
+		f.stack = f.stack[:len(f.stack)-1]
+	}
+
+	return result
+}
+
+// circular returns if framing the node would result in a circular reference.
+func (f *framer) circular(id string) bool {
+	for _, s := range slices.Backward(f.stack) {
+		if s.graph == f.graph && s.id == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filter returns if the node matches the fr.
+func (f *framer) filter(node *Node, fr *frame, requireAll bool) bool {
+	wildcard := true
+	matchesSome := false
+
+	if fr.id.set {
+		match := fr.id.wildcard || slices.Contains(fr.id.values, node.ID)
+		if !requireAll || !match {
+			return match
+		}
+		matchesSome = true
+	}
+
+	if fr.typ.set {
+		wildcard = false
+
+		var match bool
+		switch {
+		case fr.typ.wildcard:
+			match = len(node.Type) > 0
+		case fr.typ.empty() && fr.defaultType == nil:
+			if len(node.Type) > 0 {
+				return false
+			}
+			match = true
+		default:
+			match = fr.defaultType != nil ||
+				slices.ContainsFunc(fr.typ.values, func(t string) bool {
+					return slices.Contains(node.Type, t)
+				})
+
+			if !requireAll {
+				return match
+			}
+		}
+
+		if !match && requireAll {
+			return false
+		}
+		matchesSome = matchesSome || match
+	}
+
+	for _, prop := range slices.Sorted(maps.Keys(fr.properties)) {
+		subframes := fr.properties[prop]
+		values := node.Properties[prop]
+		wildcard = false
+
+		if len(values) == 0 && len(subframes) > 0 && subframes[0].hasDefault {
+			continue
+		}
+
+		// an empty array matches the absence of the property
+		if len(subframes) == 0 {
+			if len(values) > 0 {
+				return false
+			}
+
+			matchesSome = true
+			continue
+		}
+
+		subframe := subframes[0]
+
+		var match bool
+		switch {
+		case subframe.list != nil:
+			if len(values) > 0 && values[0].IsList() {
+				listFrame := firstFrame(subframe.list)
+				match = listFrame.isWildcard() ||
+					slices.ContainsFunc(values[0].List, func(item Node) bool {
+						return f.match(listFrame, item, requireAll)
+					})
+			}
+		case subframe.isWildcard():
+			match = len(values) > 0
+		default:
+			match = slices.ContainsFunc(values, func(value Node) bool {
+				return f.match(subframe, value, requireAll)
+			})
+		}
+
+		if !match && requireAll {
+			return false
+		}
+		matchesSome = matchesSome || match
+	}
+
+	return wildcard || matchesSome
+}
+
+// match returns if a value of a property matches the frame, either as a
+// value pattern or as a node pattern.
+func (f *framer) match(fr *frame, value Node, requireAll bool) bool {
+	if fr.isValuePattern() {
+		return valueMatch(fr, value)
+	}
+
+	if value.ID == "" {
+		return false
+	}
+
+	node, ok := f.graphs[f.graph][value.ID]
+	return ok && f.filter(node, fr, requireAll)
+}
+
+// valueMatch returns if a value object matches the value pattern in the
+// fr.
+func valueMatch(fr *frame, value Node) bool {
+	if !value.IsValue() {
+		return false
+	}
+
+	if fr.value.empty() && fr.typ.empty() && fr.language.empty() {
+		return true
+	}
+
+	if !fr.value.wildcard &&
+		!slices.Contains(fr.value.values, normaliseJSON(value.Value)) {
+		return false
+	}
+
+	var typ string
+	if len(value.Type) > 0 {
+		typ = value.Type[0]
+	}
+
+	return fr.typ.matches(typ) &&
+		fr.language.matches(strings.ToLower(value.Language))
+}
+
+// mergeGraphs merges the nodes of all graphs into a single graph.
+func mergeGraphs(graphs map[string]map[string]*Node) map[string]*Node {
+	result := map[string]*Node{}
+
+	for _, name := range slices.Sorted(maps.Keys(graphs)) {
+		graph := graphs[name]
+
+		for _, id := range slices.Sorted(maps.Keys(graph)) {
+			node := graph[id]
+
+			merged, ok := result[id]
+			if !ok {
+				merged = &Node{ID: id}
+				result[id] = merged
+			}
+
+			for _, t := range node.Type {
+				if !slices.Contains(merged.Type, t) {
+					merged.Type = append(merged.Type, t)
+				}
+			}
+
+			if node.Index != "" {
+				merged.Index = node.Index
+			}
+
+			for _, prop := range slices.Sorted(maps.Keys(node.Properties)) {
+				values := node.Properties[prop]
+				if !merged.Has(prop) {
+					merged.addNode(prop)
+				}
+
+				for _, value := range values {
+					if value.IsList() {
+						merged.addNode(prop, value)
+					} else {
+						merged.addUniqueNode(prop, value)
+					}
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// pruneBlankNodes removes the blank node identifiers that are in prune.
+func pruneBlankNodes(node *Node, prune map[string]struct{}) {
+	if _, ok := prune[node.ID]; ok {
+		node.ID = ""
+	}
+
+	for _, nodes := range [][]Node{node.Graph, node.Included, node.List} {
+		for i := range nodes {
+			pruneBlankNodes(&nodes[i], prune)
+		}
+	}
+
+	for _, props := range []Properties{node.Reverse, node.Properties} {
+		for _, nodes := range props {
+			for i := range nodes {
+				pruneBlankNodes(&nodes[i], prune)
+			}
+		}
+	}
+}
+
+// cleanupPreserve replaces maps holding a @preserve entry with its value and
+// replaces @null with null. Any null in an array is removed.
+func cleanupPreserve(value any) any {
+	switch v := value.(type) {
+	case []any:
+		result := make([]any, 0, len(v))
+		for _, elem := range v {
+			m, _ := elem.(map[string]any)
+			_, preserved := m[KeywordPreserve]
+
+			switch e := cleanupPreserve(elem).(type) {
+			case nil:
+			case []any:
+				if preserved {
+					result = append(result, e...)
+				} else {
+					result = append(result, e)
+				}
+			default:
+				result = append(result, e)
+			}
+		}
+		return result
+	case map[string]any:
+		if preserve, ok := v[KeywordPreserve]; ok {
+			return cleanupPreserve(preserve)
+		}
+
+		for key, elem := range v {
+			v[key] = cleanupPreserve(elem)
+		}
+		return v
+	case json.RawMessage:
+		if string(v) == `"`+KeywordNull+`"` {
+			return nil
+		}
+	case string:
+		if v == KeywordNull {
+			return nil
+		}
+	}
+
+	return value
+}
@@ -0,0 +1,282 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// frameSkip lists the W3C framing tests that are skipped, with the reason why.
+var frameSkip = map[string]string{}
+
+// TestFrame runs the W3C framing tests.
+func TestFrame(t *testing.T) {
+	loader := ld.NewFSLoader(
+		os.DirFS(filepath.Join("testdata", "w3c-framing")),
+		ld.WithFSPrefix("https://w3c.github.io/json-ld-framing/tests/", "."),
+	)
+
+	RunManifest(t, "w3c-framing/frame-manifest.jsonld", frameSkip, func(t *testing.T, tc ManifestTest) {
+		opts := []ld.ProcessorOption{ld.WithRemoteContextLoader(loader)}
+		if tc.Option.OmitGraph != nil {
+			opts = append(opts, ld.WithFrameOmitGraph(*tc.Option.OmitGraph))
+		}
+		if tc.Option.Embed != "" {
+			opts = append(opts, ld.WithFrameEmbed(tc.Option.Embed))
+		}
+		if tc.Option.Explicit != nil {
+			opts = append(opts, ld.WithFrameExplicit(*tc.Option.Explicit))
+		}
+		if tc.Option.OmitDefault != nil {
+			opts = append(opts, ld.WithFrameOmitDefault(*tc.Option.OmitDefault))
+		}
+		if tc.Option.RequireAll != nil {
+			opts = append(opts, ld.WithFrameRequireAll(*tc.Option.RequireAll))
+		}
+		p := tc.Processor(t, opts...)
+
+		var dst bytes.Buffer
+		nodes, err := p.Expand(t.Context(), bytes.NewReader(LoadData(t, tc.File(tc.Input))), tc.DocumentIRI())
+		if err == nil {
+			err = p.Frame(t.Context(), &dst, LoadData(t, tc.File(tc.Frame)), nodes, tc.DocumentIRI())
+		}
+
+		if tc.CheckError(t, err) {
+			return
+		}
+
+		if diff := cmp.Diff(LoadData(t, tc.File(tc.Expect)), json.RawMessage(dst.Bytes()), JSONDiff()); diff != "" {
+			if *dump {
+				t.Logf("framed to: %s", dst.String())
+			}
+			t.Errorf("framing mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// TestFrameLocal covers framing of documents outside of the W3C test suite.
+func TestFrameLocal(t *testing.T) {
+	tests := []struct {
+		name  string
+		proc  *ld.Processor
+		in    json.RawMessage
+		frame json.RawMessage
+		out   json.RawMessage
+		err   error
+	}{
+		{
+			name:  "library",
+			proc:  ld.NewProcessor(),
+			in:    LoadData(t, "longdistance/frame/library/in.json"),
+			frame: LoadData(t, "longdistance/frame/library/frame.json"),
+			out:   LoadData(t, "longdistance/frame/library/out.json"),
+		},
+		{
+			name: "activity with embedded object",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:    LoadData(t, "longdistance/frame/activity/in.json"),
+			frame: LoadData(t, "longdistance/frame/activity/frame.json"),
+			out:   LoadData(t, "longdistance/frame/activity/out.json"),
+		},
+		{
+			name: "embed never",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:    LoadData(t, "longdistance/frame/activity/in.json"),
+			frame: LoadData(t, "longdistance/frame/embed-never/frame.json"),
+			out:   LoadData(t, "longdistance/frame/embed-never/out.json"),
+		},
+		{
+			name: "embed always",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:    LoadData(t, "longdistance/frame/activity/in.json"),
+			frame: LoadData(t, "longdistance/frame/embed-always/frame.json"),
+			out:   LoadData(t, "longdistance/frame/embed-always/out.json"),
+		},
+		{
+			name: "explicit",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:    LoadData(t, "longdistance/frame/activity/in.json"),
+			frame: LoadData(t, "longdistance/frame/explicit/frame.json"),
+			out:   LoadData(t, "longdistance/frame/explicit/out.json"),
+		},
+		{
+			name: "default values",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:    LoadData(t, "longdistance/frame/activity/in.json"),
+			frame: LoadData(t, "longdistance/frame/default/frame.json"),
+			out:   LoadData(t, "longdistance/frame/default/out.json"),
+		},
+		{
+			name: "require all",
+			proc: ld.NewProcessor(
+				ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+			),
+			in:    LoadData(t, "longdistance/frame/activity/in.json"),
+			frame: LoadData(t, "longdistance/frame/require-all/frame.json"),
+			out:   LoadData(t, "longdistance/frame/require-all/out.json"),
+		},
+		{
+			name:  "omit graph disabled",
+			proc:  ld.NewProcessor(ld.WithFrameOmitGraph(false)),
+			in:    LoadData(t, "longdistance/frame/library/in.json"),
+			frame: LoadData(t, "longdistance/frame/library/frame.json"),
+			out:   LoadData(t, "longdistance/frame/omit-graph/out.json"),
+		},
+		{
+			name: "invalid embed value",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"contains": {"@embed": "@last"}
+			}`),
+			err: ld.ErrInvalidEmbedValue,
+		},
+		{
+			name:  "blank node @id",
+			proc:  ld.NewProcessor(),
+			in:    LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`{"@id": "_:b0"}`),
+			err:   ld.ErrInvalidFrame,
+		},
+		{
+			name: "omit default is inherited",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@type": "Library",
+				"@omitDefault": true,
+				"publisher": {}
+			}`),
+			out: LoadData(t, "longdistance/frame/library/out.json"),
+		},
+		{
+			name: "omit default option",
+			proc: ld.NewProcessor(ld.WithFrameOmitDefault(true)),
+			in:   LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@type": "Library",
+				"publisher": {}
+			}`),
+			out: LoadData(t, "longdistance/frame/library/out.json"),
+		},
+		{
+			name: "omit default option overridden by the frame",
+			proc: ld.NewProcessor(ld.WithFrameOmitDefault(true)),
+			in:   LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@type": "Library",
+				"publisher": {"@omitDefault": false}
+			}`),
+			out: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@id": "http://example.org/library",
+				"@type": "Library",
+				"contains": {
+					"@id": "http://example.org/library/the-republic",
+					"@type": "Book",
+					"contains": {
+						"@id": "http://example.org/library/the-republic#introduction",
+						"@type": "Chapter",
+						"description": "An introductory chapter on The Republic.",
+						"title": "The Introduction"
+					},
+					"creator": "Plato",
+					"title": "The Republic"
+				},
+				"location": "Athens",
+				"publisher": null
+			}`),
+		},
+		{
+			name:  "explicit option",
+			proc:  ld.NewProcessor(ld.WithFrameExplicit(true)),
+			in:    LoadData(t, "longdistance/frame/library/in.json"),
+			frame: LoadData(t, "longdistance/frame/library/frame.json"),
+			out: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@id": "http://example.org/library",
+				"@type": "Library",
+				"contains": {
+					"@id": "http://example.org/library/the-republic",
+					"@type": "Book",
+					"contains": {
+						"@id": "http://example.org/library/the-republic#introduction",
+						"@type": "Chapter"
+					}
+				}
+			}`),
+		},
+		{
+			name: "embed option",
+			proc: ld.NewProcessor(ld.WithFrameEmbed(ld.KeywordNever)),
+			in:   LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@type": "Library",
+				"@embed": "@once",
+				"contains": {}
+			}`),
+			out: json.RawMessage(`{
+				"@context": {"@vocab": "http://example.org/"},
+				"@id": "http://example.org/library",
+				"@type": "Library",
+				"contains": {"@id": "http://example.org/library/the-republic"},
+				"location": "Athens"
+			}`),
+		},
+		{
+			name:  "multiple frames",
+			proc:  ld.NewProcessor(),
+			in:    LoadData(t, "longdistance/frame/library/in.json"),
+			frame: json.RawMessage(`[{}, {}]`),
+			err:   ld.ErrInvalidFrame,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := tc.proc.Expand(t.Context(), bytes.NewReader(tc.in), "")
+			if err != nil {
+				t.Fatalf("expected successful expand, got: %s", err)
+			}
+
+			var dst bytes.Buffer
+			err = tc.proc.Frame(t.Context(), &dst, tc.frame, nodes, "")
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+
+			if tc.err == nil {
+				if diff := cmp.Diff(tc.out, json.RawMessage(dst.Bytes()), JSONDiff()); diff != "" {
+					if *dump {
+						t.Logf("framed to: %s", dst.String())
+					}
+					t.Errorf("framing mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
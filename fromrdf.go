@@ -0,0 +1,383 @@
+package longdistance
+
+import (
+	"bytes"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// rdfUsage points to a value of a property of a node.
+type rdfUsage struct {
+	node     *Node
+	property string
+	index    int
+}
+
+func (u rdfUsage) value() *Node {
+	return &u.node.Properties[u.property][u.index]
+}
+
+// FromRDF transforms an RDF [Dataset] into a list of [Node] in expanded
+// document form.
+//
+// Named graphs are returned as the @graph of the node named after the graph.
+// Well-formed rdf:first/rdf:rest chains of blank nodes are turned into a
+// @list.
+//
+// By default literals are kept as strings with an @type. With
+// [WithUseNativeTypes] xsd:string, xsd:boolean, xsd:integer and xsd:double
+// literals are converted to native JSON values instead. rdf:type is turned
+// into @type unless [WithUseRDFType] is set.
+func (p *Processor) FromRDF(dataset Dataset) ([]Node, error) {
+	// 1)
+	defaultGraph := map[string]*Node{}
+
+	// 2)
+	graphMap := map[string]map[string]*Node{
+		KeywordDefault: defaultGraph,
+	}
+
+	// 3)
+	referencedOnce := map[string]*rdfUsage{}
+	nilUsages := map[string][]rdfUsage{}
+
+	// 5)
+	for _, quad := range dataset {
+		// 5.1)
+		name := KeywordDefault
+		if !quad.Graph.IsZero() {
+			name = quad.Graph.Value
+		}
+
+		// 5.2)
+		nodeMap, ok := graphMap[name]
+		if !ok {
+			nodeMap = map[string]*Node{}
+			graphMap[name] = nodeMap
+		}
+
+		// 5.4)
+		if name != KeywordDefault {
+			if _, ok := defaultGraph[name]; !ok {
+				defaultGraph[name] = &Node{ID: name}
+			}
+		}
+
+		subject := quad.Subject.Value
+		object := quad.Object
+
+		// 5.7.1) 5.7.2)
+		node, ok := nodeMap[subject]
+		if !ok {
+			node = &Node{ID: subject}
+			nodeMap[subject] = node
+		}
+
+		// 5.7.4)
+		if !object.IsLiteral() {
+			if _, ok := nodeMap[object.Value]; !ok {
+				nodeMap[object.Value] = &Node{ID: object.Value}
+			}
+		}
+
+		// 5.7.5)
+		if quad.Predicate.Value == RDFType && !p.useRDFType && !object.IsLiteral() {
+			if !slices.Contains(node.Type, object.Value) {
+				node.Type = append(node.Type, object.Value)
+			}
+			continue
+		}
+
+		// 5.7.6)
+		value, err := p.rdfToObject(object)
+		if err != nil {
+			return nil, err
+		}
+
+		// 5.7.7)
+		property := quad.Predicate.Value
+		index := slices.IndexFunc(node.Properties[property], value.equalValueOrReference)
+		if index < 0 {
+			node.addNode(property, value)
+			index = len(node.Properties[property]) - 1
+		}
+
+		usage := rdfUsage{node: node, property: property, index: index}
+
+		if object.IsIRI() && object.Value == RDFNil {
+			// 5.7.9)
+			nilUsages[name] = append(nilUsages[name], usage)
+		} else if _, ok := referencedOnce[object.Value]; ok {
+			// 5.7.10)
+			referencedOnce[object.Value] = nil
+		} else if object.IsBlankNode() {
+			// 5.7.11)
+			referencedOnce[object.Value] = &usage
+		}
+	}
+
+	// 6)
+	//
+	// List items are tracked as usages and only resolved once all lists are
+	// known, since an item can be the head of another list.
+	lists := map[rdfUsage][]rdfUsage{}
+
+	for name, graphObject := range graphMap {
+		// 6.1) 6.4)
+		for _, usage := range nilUsages[name] {
+			// 6.4.1)
+			node := usage.node
+			property := usage.property
+			head := usage
+
+			// 6.4.2)
+			var list []rdfUsage
+			var listNodes []string
+
+			// 6.4.3)
+			for property == RDFRest && isWellFormedListNode(node, referencedOnce) {
+				// 6.4.3.1)
+				list = append(list, rdfUsage{node: node, property: RDFFirst})
+
+				// 6.4.3.2)
+				listNodes = append(listNodes, node.ID)
+
+				// 6.4.3.3)
+				nodeUsage := referencedOnce[node.ID]
+
+				// 6.4.3.4)
+				node = nodeUsage.node
+				property = nodeUsage.property
+				head = *nodeUsage
+
+				// 6.4.3.5)
+				if !strings.HasPrefix(node.ID, BlankNode) {
+					break
+				}
+			}
+
+			// 6.4.4) 6.4.5) 6.4.6)
+			slices.Reverse(list)
+			lists[head] = list
+
+			// 6.4.7)
+			for _, id := range listNodes {
+				delete(graphObject, id)
+			}
+		}
+	}
+
+	var resolve func(rdfUsage) Node
+	resolve = func(usage rdfUsage) Node {
+		items, ok := lists[usage]
+		if !ok {
+			return *usage.value()
+		}
+
+		list := make([]Node, 0, len(items))
+		for _, item := range items {
+			list = append(list, resolve(item))
+		}
+
+		return Node{List: list}
+	}
+
+	for head := range lists {
+		*head.value() = resolve(head)
+	}
+
+	// 7)
+	result := []Node{}
+
+	// 8)
+	for _, subject := range slices.Sorted(maps.Keys(defaultGraph)) {
+		node := defaultGraph[subject]
+
+		// 8.1)
+		if graph, ok := graphMap[subject]; ok && subject != KeywordDefault {
+			// 8.1.1)
+			node.Graph = []Node{}
+
+			// 8.1.2)
+			for _, id := range slices.Sorted(maps.Keys(graph)) {
+				n := graph[id]
+				if n.Len() == 1 && n.Has(KeywordID) {
+					continue
+				}
+				node.Graph = append(node.Graph, *n)
+			}
+		}
+
+		// 8.2)
+		if node.Len() == 1 && node.Has(KeywordID) {
+			continue
+		}
+
+		result = append(result, *node)
+	}
+
+	// 9)
+	return result, nil
+}
+
+// isWellFormedListNode returns if the node is a blank node that's referenced
+// only once, has exactly one rdf:first and rdf:rest and no other properties
+// besides an optional @type of rdf:List.
+func isWellFormedListNode(node *Node, referencedOnce map[string]*rdfUsage) bool {
+	if !strings.HasPrefix(node.ID, BlankNode) || referencedOnce[node.ID] == nil {
+		return false
+	}
+
+	if len(node.Properties) != 2 ||
+		len(node.Properties[RDFFirst]) != 1 ||
+		len(node.Properties[RDFRest]) != 1 {
+		return false
+	}
+
+	if node.Type != nil && !slices.Equal(node.Type, []string{RDFList}) {
+		return false
+	}
+
+	return len(node.propsWithout(KeywordID, KeywordType, RDFFirst, RDFRest)) == 0
+}
+
+// rdfToObject implements the RDF to object conversion algorithm.
+func (p *Processor) rdfToObject(term RDFTerm) (Node, error) {
+	// 1)
+	if !term.IsLiteral() {
+		return Node{ID: term.Value}, nil
+	}
+
+	// 2.1)
+	result := Node{}
+
+	// 2.2)
+	var converted json.RawMessage
+
+	// 2.3)
+	var typ string
+
+	switch {
+	case p.useNativeTypes && nativeDatatype(term.Datatype):
+		// 2.4)
+		converted, typ = nativeValue(term)
+	case term.Datatype == RDFJSON && !p.modeLD10:
+		// 2.6)
+		if !json.Valid([]byte(term.Value)) {
+			return Node{}, ErrInvalidJSONLiteral
+		}
+
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(term.Value)); err != nil {
+			return Node{}, ErrInvalidJSONLiteral
+		}
+
+		converted = buf.Bytes()
+		typ = KeywordJSON
+	case term.Language != "":
+		// 2.7)
+		result.Language = term.Language
+	case term.Datatype != XSDString && term.Datatype != RDFLangString:
+		// 2.8)
+		typ = term.Datatype
+	}
+
+	if converted == nil {
+		converted, _ = json.Marshal(term.Value)
+	}
+
+	// 2.9)
+	result.Value = converted
+
+	// 2.10)
+	if typ != "" {
+		result.Type = []string{typ}
+	}
+
+	// 2.11)
+	return result, nil
+}
+
+func nativeDatatype(datatype string) bool {
+	switch datatype {
+	case XSDString, XSDBoolean, XSDInteger, XSDDouble:
+		return true
+	default:
+		return false
+	}
+}
+
+// nativeValue converts a literal into a native JSON value. If the lexical form
+// is not valid for the datatype, the literal is returned as a string together
+// with its datatype.
+func nativeValue(term RDFTerm) (json.RawMessage, string) {
+	switch term.Datatype {
+	case XSDString:
+		// 2.4.1)
+		raw, _ := json.Marshal(term.Value)
+		return raw, ""
+	case XSDBoolean:
+		// 2.4.2)
+		if term.Value == "true" || term.Value == "false" {
+			return json.RawMessage(term.Value), ""
+		}
+	case XSDInteger, XSDDouble:
+		// 2.4.3)
+		if validNumber(term.Value, term.Datatype == XSDInteger) {
+			f, err := strconv.ParseFloat(term.Value, 64)
+			if err == nil && !math.IsInf(f, 0) {
+				return json.RawMessage(json.FormatNumber(f)), ""
+			}
+		}
+	}
+
+	raw, _ := json.Marshal(term.Value)
+	return raw, term.Datatype
+}
+
+// validNumber checks the lexical form of an xsd:integer or xsd:double.
+func validNumber(s string, integer bool) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+
+	digits := func() int {
+		n := 0
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		s = s[n:]
+		return n
+	}
+
+	mantissa := digits()
+	if integer {
+		return mantissa > 0 && s == ""
+	}
+
+	if s != "" && s[0] == '.' {
+		s = s[1:]
+		mantissa += digits()
+	}
+
+	if mantissa == 0 {
+		return false
+	}
+
+	if s != "" && (s[0] == 'e' || s[0] == 'E') {
+		s = s[1:]
+		if s != "" && (s[0] == '+' || s[0] == '-') {
+			s = s[1:]
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+
+	return s == ""
+}
@@ -0,0 +1,194 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/nquads"
+)
+
+// fromRDFSkip lists the W3C RDF deserialisation tests that are skipped, with
+// the reason why. Entries that set rdfDirection are skipped by RunManifest.
+//
+// The suite hasn't been imported yet, see testdata/w3c/README.md, so no
+// failures have been recorded here.
+var fromRDFSkip = map[string]string{}
+
+// TestFromRDF runs the W3C RDF deserialisation tests.
+func TestFromRDF(t *testing.T) {
+	RunManifest(t, "w3c/fromRdf-manifest.jsonld", fromRDFSkip, func(t *testing.T, tc ManifestTest) {
+		p := tc.Processor(t)
+
+		f, err := os.Open(filepath.Join("testdata", tc.File(tc.Input)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		var nodes []ld.Node
+		dataset, err := nquads.NewReader(f).ReadAll()
+		if err == nil {
+			nodes, err = p.FromRDF(dataset)
+		}
+
+		if tc.CheckError(t, err) {
+			return
+		}
+
+		res, err := json.Marshal(nodes)
+		if err != nil {
+			t.Fatalf("failed to marshal nodes: %s", err)
+		}
+
+		if diff := cmp.Diff(LoadData(t, tc.File(tc.Expect)), json.RawMessage(res), JSONDiff()); diff != "" {
+			if *dump {
+				t.Logf("converted to: %s", res)
+			}
+			t.Errorf("conversion mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// TestFromRDFLocal covers deserialisation from RDF of datasets outside of the
+// W3C test suite.
+func TestFromRDFLocal(t *testing.T) {
+	subject := ld.NewIRI("http://example.org/s")
+
+	tests := []struct {
+		name    string
+		proc    *ld.Processor
+		in      json.RawMessage
+		dataset ld.Dataset
+		out     json.RawMessage
+		err     error
+	}{
+		{
+			name: "lists",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/list/in.json"),
+			out:  LoadData(t, "longdistance/fromrdf/list/out.json"),
+		},
+		{
+			name: "named graph",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/named-graph/in.json"),
+			out:  LoadData(t, "longdistance/fromrdf/named-graph/out.json"),
+		},
+		{
+			name: "typed literals",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/numbers/in.json"),
+			out:  LoadData(t, "longdistance/fromrdf/numbers/out.json"),
+		},
+		{
+			name: "native types",
+			proc: ld.NewProcessor(ld.WithUseNativeTypes(true)),
+			in:   LoadData(t, "longdistance/tordf/numbers/in.json"),
+			out:  LoadData(t, "longdistance/fromrdf/native/out.json"),
+		},
+		{
+			name: "JSON literal",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/json/in.json"),
+			out:  LoadData(t, "longdistance/fromrdf/json/out.json"),
+		},
+		{
+			name: "rdf:type",
+			proc: ld.NewProcessor(),
+			dataset: ld.Dataset{
+				{Subject: subject, Predicate: ld.NewIRI(ld.RDFType), Object: ld.NewIRI("http://example.org/T")},
+				{Subject: subject, Predicate: ld.NewIRI(ld.RDFType), Object: ld.NewLiteral("T", "", "")},
+			},
+			out: json.RawMessage(`[{
+				"@id": "http://example.org/s",
+				"@type": ["http://example.org/T"],
+				"http://www.w3.org/1999/02/22-rdf-syntax-ns#type": [{"@value": "T"}]
+			}]`),
+		},
+		{
+			name: "use rdf:type",
+			proc: ld.NewProcessor(ld.WithUseRDFType(true)),
+			dataset: ld.Dataset{
+				{Subject: subject, Predicate: ld.NewIRI(ld.RDFType), Object: ld.NewIRI("http://example.org/T")},
+			},
+			out: json.RawMessage(`[{
+				"@id": "http://example.org/s",
+				"http://www.w3.org/1999/02/22-rdf-syntax-ns#type": [{"@id": "http://example.org/T"}]
+			}]`),
+		},
+		{
+			name: "list node referenced twice",
+			proc: ld.NewProcessor(),
+			dataset: ld.Dataset{
+				{Subject: subject, Predicate: ld.NewIRI("http://example.org/p"), Object: ld.NewBlankNode("_:l")},
+				{Subject: subject, Predicate: ld.NewIRI("http://example.org/q"), Object: ld.NewBlankNode("_:l")},
+				{Subject: ld.NewBlankNode("_:l"), Predicate: ld.NewIRI(ld.RDFFirst), Object: ld.NewLiteral("a", "", "")},
+				{Subject: ld.NewBlankNode("_:l"), Predicate: ld.NewIRI(ld.RDFRest), Object: ld.NewIRI(ld.RDFNil)},
+			},
+			out: json.RawMessage(`[
+				{
+					"@id": "_:l",
+					"http://www.w3.org/1999/02/22-rdf-syntax-ns#first": [{"@value": "a"}],
+					"http://www.w3.org/1999/02/22-rdf-syntax-ns#rest": [{"@list": []}]
+				},
+				{
+					"@id": "http://example.org/s",
+					"http://example.org/p": [{"@id": "_:l"}],
+					"http://example.org/q": [{"@id": "_:l"}]
+				}
+			]`),
+		},
+		{
+			name: "invalid JSON literal",
+			proc: ld.NewProcessor(),
+			dataset: ld.Dataset{
+				{Subject: subject, Predicate: ld.NewIRI("http://example.org/p"), Object: ld.NewLiteral("{", ld.RDFJSON, "")},
+			},
+			err: ld.ErrInvalidJSONLiteral,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dataset := tc.dataset
+			if dataset == nil {
+				nodes, err := tc.proc.Expand(t.Context(), bytes.NewReader(tc.in), "")
+				if err != nil {
+					t.Fatalf("expected successful expand, got: %s", err)
+				}
+
+				dataset, err = tc.proc.ToRDF(nodes)
+				if err != nil {
+					t.Fatalf("expected successful conversion to RDF, got: %s", err)
+				}
+			}
+
+			nodes, err := tc.proc.FromRDF(dataset)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+
+			if tc.err == nil {
+				res, err := json.Marshal(nodes)
+				if err != nil {
+					t.Fatalf("failed to marshal nodes: %s", err)
+				}
+
+				if diff := cmp.Diff(tc.out, json.RawMessage(res), JSONDiff()); diff != "" {
+					if *dump {
+						t.Logf("converted to: %s", res)
+					}
+					t.Errorf("conversion mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
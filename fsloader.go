@@ -0,0 +1,191 @@
+package longdistance
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// FSLoaderOption can be used to configure the loader returned by
+// [NewFSLoader].
+type FSLoaderOption func(*fsLoader)
+
+// WithFSPrefix maps IRIs starting with prefix to files in dir.
+//
+// The remainder of the IRI after the prefix is used as the path of the file
+// relative to dir. For example, with the prefix "https://example.org/ns/" and
+// dir "contexts", "https://example.org/ns/v1" maps to "contexts/v1". Use "."
+// for the root of the file system.
+//
+// When multiple prefixes match an IRI, the longest one is used.
+func WithFSPrefix(prefix, dir string) FSLoaderOption {
+	return func(l *fsLoader) {
+		l.prefixes = append(l.prefixes, fsPrefix{prefix: prefix, dir: dir})
+	}
+}
+
+// WithFSExtensions sets the file extensions to try, in order, when there's no
+// file matching the path of an IRI exactly.
+//
+// This is useful for contexts published without an extension, like
+// "https://www.w3.org/ns/activitystreams", stored as "activitystreams.jsonld".
+func WithFSExtensions(ext ...string) FSLoaderOption {
+	return func(l *fsLoader) {
+		l.extensions = ext
+	}
+}
+
+// NewFSLoader returns a [RemoteContextLoaderFunc] that serves contexts from
+// fsys. This can be any [fs.FS], like an [embed.FS] to build contexts into
+// your application or [os.DirFS] to read them from disk.
+//
+// IRIs are mapped to files using the prefixes set with [WithFSPrefix]. Each
+// file must hold a JSON object, the value of its [KeywordContext] entry
+// becomes [Document.Context]. [Document.URL] is the IRI that was requested.
+//
+// Inline contexts in data: URIs (RFC 2397) are decoded without consulting
+// fsys. Their media type must be absent, [ApplicationLDJSON] or another JSON
+// media type.
+//
+// Errors wrap [ErrLoadingRemoteContext] when no file matches the IRI, or
+// [ErrInvalidRemoteContext] if the file is not a JSON object.
+func NewFSLoader(fsys fs.FS, opts ...FSLoaderOption) RemoteContextLoaderFunc {
+	l := &fsLoader{fsys: fsys}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l.load
+}
+
+type fsLoader struct {
+	fsys       fs.FS
+	prefixes   []fsPrefix
+	extensions []string
+}
+
+type fsPrefix struct {
+	prefix string
+	dir    string
+}
+
+func (l *fsLoader) load(_ context.Context, iri string) (Document, error) {
+	if isDataURI(iri) {
+		return loadDataURI(iri)
+	}
+
+	name, err := l.path(iri)
+	if err != nil {
+		return Document{}, err
+	}
+
+	candidates := []string{name}
+	for _, ext := range l.extensions {
+		candidates = append(candidates, name+ext)
+	}
+
+	for _, name := range candidates {
+		data, err := fs.ReadFile(l.fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Document{}, fmt.Errorf("%w: %s: %w", ErrLoadingRemoteContext, iri, err)
+		}
+
+		lctx, err := extractContext(data)
+		if err != nil {
+			return Document{}, fmt.Errorf("%w: %s", err, iri)
+		}
+
+		return Document{URL: iri, Context: lctx}, nil
+	}
+
+	return Document{}, fmt.Errorf("%w: %s: %w", ErrLoadingRemoteContext, iri, fs.ErrNotExist)
+}
+
+// path maps an IRI to the path of a file using the longest matching prefix.
+func (l *fsLoader) path(iri string) (string, error) {
+	var match *fsPrefix
+	for i, p := range l.prefixes {
+		if !strings.HasPrefix(iri, p.prefix) {
+			continue
+		}
+
+		if match == nil || len(p.prefix) > len(match.prefix) {
+			match = &l.prefixes[i]
+		}
+	}
+
+	if match == nil {
+		return "", fmt.Errorf("%w: %s: no matching prefix", ErrLoadingRemoteContext, iri)
+	}
+
+	rest, _, _ := strings.Cut(iri[len(match.prefix):], "#")
+	rest, err := url.PathUnescape(rest)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %w", ErrLoadingRemoteContext, iri, err)
+	}
+
+	// this also rejects .. elements, so an IRI can't escape its directory
+	if !fs.ValidPath(rest) || rest == "." {
+		return "", fmt.Errorf("%w: %s: invalid path", ErrLoadingRemoteContext, iri)
+	}
+
+	return path.Join(match.dir, rest), nil
+}
+
+// isDataURI returns true if iri has the data scheme.
+func isDataURI(iri string) bool {
+	return len(iri) >= 5 && strings.EqualFold(iri[:5], "data:")
+}
+
+// loadDataURI decodes the context embedded in a data: URI.
+func loadDataURI(iri string) (Document, error) {
+	meta, data, ok := strings.Cut(iri[5:], ",")
+	if !ok {
+		return Document{}, fmt.Errorf("%w: malformed data URI", ErrLoadingRemoteContext)
+	}
+
+	meta, isBase64 := strings.CutSuffix(meta, ";base64")
+	if meta != "" {
+		mt, _, err := mime.ParseMediaType(meta)
+		if err != nil {
+			return Document{}, fmt.Errorf("%w: malformed data URI: %w", ErrLoadingRemoteContext, err)
+		}
+
+		if mt != ApplicationLDJSON && !isJSONMediaType(mt) {
+			return Document{}, fmt.Errorf("%w: unsupported media type in data URI: %s", ErrLoadingRemoteContext, mt)
+		}
+	}
+
+	data, err := url.PathUnescape(data)
+	if err != nil {
+		return Document{}, fmt.Errorf("%w: malformed data URI: %w", ErrLoadingRemoteContext, err)
+	}
+
+	body := []byte(data)
+	if isBase64 {
+		body, err = base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			body, err = base64.RawStdEncoding.DecodeString(data)
+		}
+		if err != nil {
+			return Document{}, fmt.Errorf("%w: malformed data URI: %w", ErrLoadingRemoteContext, err)
+		}
+	}
+
+	lctx, err := extractContext(body)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return Document{URL: iri, Context: lctx}, nil
+}
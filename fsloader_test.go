@@ -0,0 +1,148 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestFSLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"ns/v1.jsonld":              {Data: []byte(`{"@context": {"name": "https://example.org/name"}}`)},
+		"ns/extra/v1.jsonld":        {Data: []byte(`{"@context": {"name": "https://example.org/extra"}}`)},
+		"ns/activitystreams.jsonld": {Data: []byte(`{"@context": {"name": "https://www.w3.org/ns/activitystreams#name"}}`)},
+		"ns/empty.jsonld":           {Data: []byte(`{}`)},
+		"ns/array.jsonld":           {Data: []byte(`[{"@context": {}}]`)},
+		"ns/invalid.jsonld":         {Data: []byte(`{`)},
+		"secret.jsonld":             {Data: []byte(`{"@context": {}}`)},
+	}
+
+	loader := ld.NewFSLoader(fsys,
+		ld.WithFSPrefix("https://example.org/ns/", "ns"),
+		ld.WithFSPrefix("https://example.org/ns/extra/", "ns/extra"),
+		ld.WithFSPrefix("https://www.w3.org/ns/", "ns"),
+		ld.WithFSExtensions(".jsonld"),
+	)
+
+	inline := `{"@context": {"name": "https://example.org/inline"}}`
+
+	tests := []struct {
+		name string
+		iri  string
+		want json.RawMessage
+		err  error
+	}{
+		{
+			name: "file",
+			iri:  "https://example.org/ns/v1.jsonld",
+			want: json.RawMessage(`{"name": "https://example.org/name"}`),
+		},
+		{
+			name: "longest prefix",
+			iri:  "https://example.org/ns/extra/v1.jsonld",
+			want: json.RawMessage(`{"name": "https://example.org/extra"}`),
+		},
+		{
+			name: "extension",
+			iri:  "https://www.w3.org/ns/activitystreams",
+			want: json.RawMessage(`{"name": "https://www.w3.org/ns/activitystreams#name"}`),
+		},
+		{
+			name: "fragment",
+			iri:  "https://example.org/ns/v1.jsonld#frag",
+			want: json.RawMessage(`{"name": "https://example.org/name"}`),
+		},
+		{
+			name: "without context",
+			iri:  "https://example.org/ns/empty.jsonld",
+			want: json.RawMessage(`{}`),
+		},
+		{
+			name: "data URI",
+			iri:  "data:application/ld+json," + url.PathEscape(inline),
+			want: json.RawMessage(`{"name": "https://example.org/inline"}`),
+		},
+		{
+			name: "base64 data URI",
+			iri:  "data:application/ld+json;base64," + base64.StdEncoding.EncodeToString([]byte(inline)),
+			want: json.RawMessage(`{"name": "https://example.org/inline"}`),
+		},
+		{name: "data URI media type", iri: "data:text/plain," + url.PathEscape(inline), err: ld.ErrLoadingRemoteContext},
+		{name: "data URI not an object", iri: "data:application/json,%5B%5D", err: ld.ErrInvalidRemoteContext},
+		{name: "missing", iri: "https://example.org/ns/v2.jsonld", err: ld.ErrLoadingRemoteContext},
+		{name: "no prefix", iri: "https://example.com/ns/v1.jsonld", err: ld.ErrLoadingRemoteContext},
+		{name: "traversal", iri: "https://example.org/ns/../secret.jsonld", err: ld.ErrLoadingRemoteContext},
+		{name: "directory", iri: "https://example.org/ns/", err: ld.ErrLoadingRemoteContext},
+		{name: "array", iri: "https://example.org/ns/array.jsonld", err: ld.ErrInvalidRemoteContext},
+		{name: "invalid JSON", iri: "https://example.org/ns/invalid.jsonld", err: ld.ErrInvalidRemoteContext},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := loader(t.Context(), tc.iri)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error: %s, got: %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected no error, got: %s", err)
+			}
+
+			if doc.URL != tc.iri {
+				t.Errorf("expected URL: %s, got: %s", tc.iri, doc.URL)
+			}
+
+			if diff := cmp.Diff(tc.want, doc.Context, JSONDiff()); diff != "" {
+				t.Errorf("context mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFSLoaderExpand(t *testing.T) {
+	fsys := fstest.MapFS{
+		"v1.jsonld": {Data: []byte(`{"@context": {"name": "https://example.org/name"}}`)},
+	}
+
+	proc := ld.NewProcessor(ld.WithRemoteContextLoader(
+		ld.NewFSLoader(fsys, ld.WithFSPrefix("https://example.org/ns/", ".")),
+	))
+
+	inline := url.PathEscape(`{"@context": {"title": "https://example.org/title"}}`)
+	doc := []byte(`{
+		"@context": ["https://example.org/ns/v1.jsonld", "data:application/ld+json,` + inline + `"],
+		"name": "Alice",
+		"title": "Dr."
+	}`)
+
+	nodes, err := proc.Expand(t.Context(), bytes.NewReader(doc), "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	got, err := json.Marshal(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := json.RawMessage(`[{
+		"https://example.org/name": [{"@value": "Alice"}],
+		"https://example.org/title": [{"@value": "Dr."}]
+	}]`)
+
+	if diff := cmp.Diff(want, json.RawMessage(got), JSONDiff()); diff != "" {
+		t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -1,5 +1,8 @@
 module sourcery.dny.nu/longdistance
 
-go 1.24
+go 1.24.0
 
-require github.com/google/go-cmp v0.7.0
+require (
+	github.com/google/go-cmp v0.7.0
+	golang.org/x/net v0.50.0
+)
@@ -4,10 +4,13 @@
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
-	"net/url"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -17,6 +20,8 @@
 
 var dump = flag.Bool("dump", false, "dump the compacted or expanded JSON on test failure")
 
+var requireW3C = flag.Bool("w3c", false, "fail instead of skip when a W3C test manifest hasn't been imported")
+
 const ASURL = "https://www.w3.org/ns/activitystreams"
 
 func ProcessContext(tb testing.TB, lctx json.RawMessage, iri string) *ld.Context {
@@ -50,31 +55,10 @@ func StaticLoader(tb testing.TB, file string) ld.RemoteContextLoaderFunc {
 func FileLoader(tb testing.TB) ld.RemoteContextLoaderFunc {
 	tb.Helper()
 
-	return func(_ context.Context, s string) (ld.Document, error) {
-		u, err := url.Parse(s)
-		if err != nil {
-			return ld.Document{}, err
-		}
-
-		if u.Scheme != "http" && u.Scheme != "https" {
-			return ld.Document{}, ld.ErrLoadingRemoteContext
-		}
-
-		data := LoadData(tb, filepath.Join(
-			"w3c",
-			filepath.Join(strings.Split(u.Path, "/")[3:]...),
-		))
-
-		var obj map[string]json.RawMessage
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return ld.Document{}, ld.ErrInvalidRemoteContext
-		}
-
-		return ld.Document{
-			URL:     s,
-			Context: obj[ld.KeywordContext],
-		}, nil
-	}
+	return ld.NewFSLoader(
+		os.DirFS(filepath.Join("testdata", "w3c")),
+		ld.WithFSPrefix("https://w3c.github.io/json-ld-api/tests/", "."),
+	)
 }
 
 func LoadData(t testing.TB, file string) json.RawMessage {
@@ -215,3 +199,185 @@ func equalUnordered(xv, yv []any) bool {
 
 	return true
 }
+
+// LoadQuads loads an N-Quads file and returns its lines in sorted order.
+func LoadQuads(t testing.TB, file string) []string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("failed to load %s: %s", file, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	slices.Sort(lines)
+
+	return lines
+}
+
+// ManifestTest is an entry of a W3C JSON-LD test manifest.
+type ManifestTest struct {
+	ID              string          `json:"@id"`
+	Type            []string        `json:"@type"`
+	Name            string          `json:"name"`
+	Input           string          `json:"input"`
+	Context         string          `json:"context"`
+	Frame           string          `json:"frame"`
+	Expect          string          `json:"expect"`
+	ExpectErrorCode string          `json:"expectErrorCode"`
+	Option          ManifestOptions `json:"option"`
+
+	// Dir is the directory of the manifest, relative to testdata.
+	Dir string `json:"-"`
+	// BaseIRI is the IRI the files of the manifest are published under.
+	BaseIRI string `json:"-"`
+}
+
+// ManifestOptions are the JsonLdOptions set by a manifest entry.
+type ManifestOptions struct {
+	SpecVersion           string `json:"specVersion"`
+	ProcessingMode        string `json:"processingMode"`
+	Base                  string `json:"base"`
+	ExpandContext         string `json:"expandContext"`
+	CompactArrays         *bool  `json:"compactArrays"`
+	Ordered               bool   `json:"ordered"`
+	OmitGraph             *bool  `json:"omitGraph"`
+	ProduceGeneralizedRDF bool   `json:"produceGeneralizedRdf"`
+	UseNativeTypes        bool   `json:"useNativeTypes"`
+	UseRDFType            bool   `json:"useRdfType"`
+	RDFDirection          string `json:"rdfDirection"`
+	Embed                 string `json:"embed"`
+	Explicit              *bool  `json:"explicit"`
+	OmitDefault           *bool  `json:"omitDefault"`
+	RequireAll            *bool  `json:"requireAll"`
+}
+
+// Positive reports if the entry expects a result instead of an error.
+func (tc ManifestTest) Positive() bool {
+	return tc.ExpectErrorCode == ""
+}
+
+// File returns the path of a file of the entry, relative to testdata.
+func (tc ManifestTest) File(name string) string {
+	return filepath.Join(tc.Dir, name)
+}
+
+// DocumentIRI returns the IRI the input of the entry is published at.
+func (tc ManifestTest) DocumentIRI() string {
+	return tc.BaseIRI + tc.Input
+}
+
+// LoadContext returns the value of @context of a file of the entry.
+func (tc ManifestTest) LoadContext(tb testing.TB, name string) json.RawMessage {
+	tb.Helper()
+
+	var doc struct {
+		Context json.RawMessage `json:"@context"`
+	}
+	if err := json.Unmarshal(LoadData(tb, tc.File(name)), &doc); err != nil {
+		tb.Fatalf("invalid context in %s: %s", name, err)
+	}
+
+	return doc.Context
+}
+
+// Processor returns a processor configured with the options of the entry.
+func (tc ManifestTest) Processor(tb testing.TB, options ...ld.ProcessorOption) *ld.Processor {
+	tb.Helper()
+
+	opts := []ld.ProcessorOption{
+		ld.WithRemoteContextLoader(FileLoader(tb)),
+		ld.WithLogger(slog.New(slog.DiscardHandler)),
+		ld.WithBaseIRI(tc.Option.Base),
+		ld.WithOrdered(true),
+		ld.With10Processing(tc.Option.ProcessingMode == "json-ld-1.0" || tc.Option.SpecVersion == "json-ld1.0"),
+		ld.WithProduceGeneralizedRDF(tc.Option.ProduceGeneralizedRDF),
+		ld.WithUseNativeTypes(tc.Option.UseNativeTypes),
+		ld.WithUseRDFType(tc.Option.UseRDFType),
+	}
+
+	if tc.Option.CompactArrays != nil {
+		opts = append(opts, ld.WithCompactArrays(*tc.Option.CompactArrays))
+	}
+
+	if tc.Option.ExpandContext != "" {
+		opts = append(opts, ld.WithExpandContext(LoadData(tb, tc.File(tc.Option.ExpandContext))))
+	}
+
+	return ld.NewProcessor(append(opts, options...)...)
+}
+
+// CheckError reports whether err matches the error the entry expects. It
+// fails the test if an error was expected and got is nil, or the other way
+// around, and returns true if the test shouldn't continue.
+func (tc ManifestTest) CheckError(t *testing.T, err error) bool {
+	t.Helper()
+
+	switch {
+	case tc.Positive() && err != nil:
+		t.Fatalf("expected no error, got: %s", err)
+	case !tc.Positive() && err == nil:
+		t.Fatalf("expected error: %s, got nil", tc.ExpectErrorCode)
+	case !tc.Positive():
+		if !strings.Contains(err.Error(), tc.ExpectErrorCode) {
+			t.Fatalf("expected error: %s, got: %s", tc.ExpectErrorCode, err)
+		}
+		return true
+	}
+
+	return false
+}
+
+// RunManifest runs fn for every entry of a W3C test manifest in testdata.
+//
+// Entries in skip are skipped with the reason given. Entries that need
+// features the processor doesn't have, like rdfDirection, are skipped too.
+// When the manifest hasn't been imported into testdata, the test is skipped,
+// or fails when run with -w3c. See testdata/w3c/README.md for where they come
+// from.
+func RunManifest(
+	t *testing.T,
+	manifest string,
+	skip map[string]string,
+	fn func(t *testing.T, tc ManifestTest),
+) {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", manifest))
+	if errors.Is(err, fs.ErrNotExist) {
+		if *requireW3C {
+			t.Fatalf("manifest %s has not been imported", manifest)
+		}
+		t.Skipf("manifest %s has not been imported", manifest)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m struct {
+		BaseIRI  string         `json:"baseIri"`
+		Sequence []ManifestTest `json:"sequence"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("invalid manifest %s: %s", manifest, err)
+	}
+
+	for _, tc := range m.Sequence {
+		tc.Dir = filepath.Dir(manifest)
+		tc.BaseIRI = m.BaseIRI
+
+		t.Run(strings.TrimPrefix(tc.ID, "#")+"-"+tc.Name, func(t *testing.T) {
+			t.Parallel()
+
+			if reason, ok := skip[tc.ID]; ok {
+				t.Skip(reason)
+			}
+
+			if tc.Option.RDFDirection != "" {
+				t.Skip("rdfDirection is not supported")
+			}
+
+			fn(t, tc)
+		})
+	}
+}
@@ -0,0 +1,292 @@
+// Package html extracts JSON-LD from the script elements of HTML documents.
+//
+// It's kept apart from the longdistance package as it depends on the HTML
+// parser from golang.org/x/net/html, so only programs that handle HTML pull it
+// in.
+//
+// To expand an HTML document you already have, use [Expand]. To expand HTML
+// documents retrieved with [ld.Processor.ExpandURL], wrap the document loader
+// with [Loader]:
+//
+//	p := ld.NewProcessor(
+//		ld.WithRemoteDocumentLoader(html.Loader(ld.NewHTTPDocumentLoader(nil))),
+//	)
+//
+// See https://www.w3.org/TR/json-ld11-api/#process-html.
+package html
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+
+	nethtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/internal/iri"
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// Option configures the extraction.
+type Option func(*extractor)
+
+// WithExtractAllScripts sets whether all JSON-LD script elements of a
+// document are extracted, instead of only the first one.
+func WithExtractAllScripts(b bool) Option {
+	return func(e *extractor) {
+		e.extractAllScripts = b
+	}
+}
+
+type extractor struct {
+	extractAllScripts bool
+}
+
+// Extract returns the JSON-LD of the script elements of an HTML document,
+// along with the base IRI to expand it with.
+//
+// If the document was retrieved from a URL, pass it as the second argument.
+// Otherwise an empty string. When the URL has a fragment, the script element
+// with that id is used. Otherwise the first script element of type
+// [ld.ApplicationLDJSON] is used, or all of them when [WithExtractAllScripts]
+// is set.
+//
+// The href of the base element, if present, is used as the base IRI.
+func Extract(
+	document io.Reader,
+	documentURL string,
+	opts ...Option,
+) (json.RawMessage, string, error) {
+	e := &extractor{}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e.extract(document, documentURL)
+}
+
+// Expand extracts JSON-LD from the script elements of an HTML document, as
+// described by [Extract], and transforms it into JSON-LD expanded document
+// form using p.
+func Expand(
+	ctx context.Context,
+	p *ld.Processor,
+	document io.Reader,
+	url string,
+	opts ...Option,
+) ([]ld.Node, error) {
+	data, baseIRI, err := Extract(document, url, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Expand(ctx, bytes.NewReader(data), baseIRI)
+}
+
+// Loader wraps a document loader so the HTML documents it returns are
+// replaced by the JSON-LD extracted from them, as described by [Extract]. The
+// fragment of the requested URL selects the script element.
+//
+// [ld.RemoteDocument.DocumentURL] is set to the base IRI of the document and
+// [ld.RemoteDocument.ContentType] to [ld.ApplicationLDJSON]. Documents of any
+// other media type are returned as is.
+func Loader(
+	next ld.RemoteDocumentLoaderFunc,
+	opts ...Option,
+) ld.RemoteDocumentLoaderFunc {
+	e := &extractor{}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return func(ctx context.Context, ref string) (ld.RemoteDocument, error) {
+		doc, err := next(ctx, ref)
+		if err != nil {
+			return doc, err
+		}
+
+		mt, _, _ := mime.ParseMediaType(doc.ContentType)
+		if mt != ld.TextHTML && mt != ld.ApplicationXHTML {
+			return doc, nil
+		}
+
+		documentURL := doc.DocumentURL
+		if documentURL == "" {
+			documentURL = ref
+		}
+
+		// the fragment selects the script element
+		if _, fragment, ok := strings.Cut(ref, "#"); ok && !strings.Contains(documentURL, "#") {
+			documentURL += "#" + fragment
+		}
+
+		data, baseIRI, err := e.extract(bytes.NewReader(doc.Document), documentURL)
+		if err != nil {
+			return ld.RemoteDocument{}, err
+		}
+
+		return ld.RemoteDocument{
+			DocumentURL: baseIRI,
+			ContentType: ld.ApplicationLDJSON,
+			Profile:     doc.Profile,
+			Document:    data,
+		}, nil
+	}
+}
+
+// script is a JSON-LD script element.
+type script struct {
+	id      string
+	jsonld  bool
+	content string
+}
+
+// extract implements the extraction of JSON-LD from HTML. It returns the JSON
+// to expand and the base IRI of the document.
+func (e *extractor) extract(
+	document io.Reader,
+	documentURL string,
+) (json.RawMessage, string, error) {
+	var fragment string
+	if u, err := url.Parse(documentURL); err == nil {
+		fragment = u.Fragment
+	}
+
+	scripts, base, err := parseHTML(document)
+	if err != nil {
+		return nil, "", err
+	}
+
+	baseIRI := documentURL
+	if base != "" {
+		resolved, err := iri.Resolve(documentURL, base)
+		if err != nil {
+			return nil, "", ld.ErrLoadingDocument
+		}
+		baseIRI = resolved
+	}
+
+	// a specific script element was requested
+	if fragment != "" {
+		for _, s := range scripts {
+			if s.id != fragment {
+				continue
+			}
+
+			if !s.jsonld {
+				return nil, "", ld.ErrLoadingDocument
+			}
+
+			data, err := scriptJSON(s)
+			return data, baseIRI, err
+		}
+
+		return nil, "", ld.ErrLoadingDocument
+	}
+
+	if !e.extractAllScripts {
+		for _, s := range scripts {
+			if s.jsonld {
+				data, err := scriptJSON(s)
+				return data, baseIRI, err
+			}
+		}
+
+		return nil, "", ld.ErrLoadingDocument
+	}
+
+	result := json.Array{}
+	for _, s := range scripts {
+		if !s.jsonld {
+			continue
+		}
+
+		data, err := scriptJSON(s)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if json.IsArray(data) {
+			var elems json.Array
+			if err := json.Unmarshal(data, &elems); err != nil {
+				return nil, "", ld.ErrInvalidScriptElement
+			}
+			result = append(result, elems...)
+		} else {
+			result = append(result, data)
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, baseIRI, nil
+}
+
+// parseHTML returns all script elements as well as the href of the first base
+// element.
+func parseHTML(document io.Reader) ([]script, string, error) {
+	var scripts []script
+	var base string
+	current := -1
+
+	z := nethtml.NewTokenizer(document)
+	for {
+		switch z.Next() {
+		case nethtml.ErrorToken:
+			if err := z.Err(); err != io.EOF {
+				return nil, "", ld.ErrLoadingDocument
+			}
+			return scripts, base, nil
+		case nethtml.StartTagToken, nethtml.SelfClosingTagToken:
+			tok := z.Token()
+
+			switch tok.DataAtom {
+			case atom.Base:
+				if base != "" {
+					continue
+				}
+				for _, attr := range tok.Attr {
+					if attr.Key == "href" {
+						base = attr.Val
+					}
+				}
+			case atom.Script:
+				s := script{}
+				for _, attr := range tok.Attr {
+					switch attr.Key {
+					case "id":
+						s.id = attr.Val
+					case "type":
+						mt, _, _ := mime.ParseMediaType(attr.Val)
+						s.jsonld = mt == ld.ApplicationLDJSON
+					}
+				}
+				scripts = append(scripts, s)
+				current = len(scripts) - 1
+			}
+		case nethtml.TextToken:
+			if current >= 0 {
+				scripts[current].content += string(z.Text())
+			}
+		case nethtml.EndTagToken:
+			current = -1
+		}
+	}
+}
+
+// scriptJSON returns the content of a script element as JSON.
+func scriptJSON(s script) (json.RawMessage, error) {
+	content := strings.TrimSpace(s.content)
+	if !json.Valid([]byte(content)) {
+		return nil, ld.ErrInvalidScriptElement
+	}
+
+	return json.RawMessage(content), nil
+}
@@ -0,0 +1,208 @@
+package html_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/html"
+)
+
+// diffJSON compares the expanded nodes to the expected JSON, ignoring the
+// order of object keys.
+func diffJSON(t *testing.T, want json.RawMessage, nodes []ld.Node) string {
+	t.Helper()
+
+	got, err := json.Marshal(nodes)
+	if err != nil {
+		t.Fatalf("failed to marshal to expanded JSON: %s", err)
+	}
+
+	var wantv, gotv any
+	if err := json.Unmarshal(want, &wantv); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(got, &gotv); err != nil {
+		t.Fatal(err)
+	}
+
+	return cmp.Diff(wantv, gotv)
+}
+
+func TestExpand(t *testing.T) {
+	profile, err := os.ReadFile(filepath.Join("testdata", "profile.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := `{
+		"@id": "https://example.org/people/alice",
+		"@type": ["http://schema.org/Person"],
+		"http://schema.org/name": [{"@value": "Alice & Bob's friend"}]
+	}`
+
+	posts := `{
+		"@id": "https://example.org/people/alice/posts/1",
+		"http://schema.org/headline": [{"@value": "Hello"}]
+	}, {
+		"@id": "https://example.org/people/alice/posts/2",
+		"http://schema.org/headline": [{"@value": "</p>"}]
+	}`
+
+	tests := []struct {
+		name string
+		opts []html.Option
+		in   []byte
+		url  string
+		out  json.RawMessage
+		err  error
+	}{
+		{
+			name: "first script",
+			in:   profile,
+			url:  "https://example.org/alice.html",
+			out:  json.RawMessage(`[` + alice + `]`),
+		},
+		{
+			name: "script selected by fragment",
+			in:   profile,
+			url:  "https://example.org/alice.html#posts",
+			out:  json.RawMessage(`[` + posts + `]`),
+		},
+		{
+			name: "extract all scripts",
+			opts: []html.Option{html.WithExtractAllScripts(true)},
+			in:   profile,
+			url:  "https://example.org/alice.html",
+			out:  json.RawMessage(`[` + alice + `,` + posts + `]`),
+		},
+		{
+			name: "fragment selecting a non JSON-LD script",
+			in:   profile,
+			url:  "https://example.org/alice.html#app",
+			err:  ld.ErrLoadingDocument,
+		},
+		{
+			name: "fragment selecting a missing script",
+			in:   profile,
+			url:  "https://example.org/alice.html#missing",
+			err:  ld.ErrLoadingDocument,
+		},
+		{
+			name: "no script",
+			in:   []byte(`<html><body><p>Nothing here</p></body></html>`),
+			url:  "https://example.org/",
+			err:  ld.ErrLoadingDocument,
+		},
+		{
+			name: "no script with extract all scripts",
+			opts: []html.Option{html.WithExtractAllScripts(true)},
+			in:   []byte(`<html><body><p>Nothing here</p></body></html>`),
+			url:  "https://example.org/",
+			out:  json.RawMessage(`[]`),
+		},
+		{
+			name: "invalid JSON",
+			in:   []byte(`<script type="application/ld+json">{"@id": </script>`),
+			url:  "https://example.org/",
+			err:  ld.ErrInvalidScriptElement,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := html.Expand(t.Context(), ld.NewProcessor(), bytes.NewReader(tc.in), tc.url, tc.opts...)
+
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+
+			if tc.err == nil {
+				if diff := diffJSON(t, tc.out, nodes); diff != "" {
+					t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestLoader(t *testing.T) {
+	profile, err := os.ReadFile(filepath.Join("testdata", "profile.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := `{
+		"@id": "https://example.org/people/alice/posts/1",
+		"http://schema.org/headline": [{"@value": "Hello"}]
+	}, {
+		"@id": "https://example.org/people/alice/posts/2",
+		"http://schema.org/headline": [{"@value": "</p>"}]
+	}`
+
+	loader := func(_ context.Context, url string) (ld.RemoteDocument, error) {
+		if url == "https://example.org/data.json" {
+			return ld.RemoteDocument{
+				DocumentURL: url,
+				ContentType: ld.ApplicationLDJSON,
+				Document:    json.RawMessage(`{"@id": "https://example.org/data", "http://schema.org/name": "data"}`),
+			}, nil
+		}
+
+		return ld.RemoteDocument{
+			DocumentURL: "https://example.org/alice.html",
+			ContentType: "text/html; charset=utf-8",
+			Document:    profile,
+		}, nil
+	}
+
+	t.Run("fragment", func(t *testing.T) {
+		t.Parallel()
+
+		proc := ld.NewProcessor(ld.WithRemoteDocumentLoader(html.Loader(loader)))
+
+		nodes, err := proc.ExpandURL(t.Context(), "https://example.org/@alice#posts")
+		if err != nil {
+			t.Fatalf("expected successful expand, got: %s", err)
+		}
+
+		if diff := diffJSON(t, json.RawMessage(`[`+posts+`]`), nodes); diff != "" {
+			t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("other media type", func(t *testing.T) {
+		t.Parallel()
+
+		proc := ld.NewProcessor(ld.WithRemoteDocumentLoader(html.Loader(loader)))
+
+		nodes, err := proc.ExpandURL(t.Context(), "https://example.org/data.json")
+		if err != nil {
+			t.Fatalf("expected successful expand, got: %s", err)
+		}
+
+		want := json.RawMessage(`[{"@id": "https://example.org/data", "http://schema.org/name": [{"@value": "data"}]}]`)
+		if diff := diffJSON(t, want, nodes); diff != "" {
+			t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("without loader", func(t *testing.T) {
+		t.Parallel()
+
+		proc := ld.NewProcessor(ld.WithRemoteDocumentLoader(loader))
+
+		_, err := proc.ExpandURL(t.Context(), "https://example.org/@alice#posts")
+		if !errors.Is(err, ld.ErrLoadingDocument) {
+			t.Fatalf("expected error: %s, got: %v", ld.ErrLoadingDocument, err)
+		}
+	})
+}
@@ -0,0 +1,499 @@
+package longdistance
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxAlternateLinks is the number of alternate links a document loader
+// follows before giving up.
+const maxAlternateLinks = 10
+
+// documentAccept is the Accept header sent when retrieving a document.
+const documentAccept = ApplicationLDJSON + ", " + ApplicationJSON + ";q=0.9, " +
+	TextHTML + ";q=0.8, " + ApplicationXHTML + ";q=0.8"
+
+// NewHTTPDocumentLoader returns a [RemoteDocumentLoaderFunc] that retrieves
+// documents over HTTP using client. If client is nil, [http.DefaultClient] is
+// used.
+//
+// It implements the LoadDocumentCallback of the JSON-LD 1.1 API:
+//   - [ApplicationLDJSON] is requested, followed by [ApplicationJSON] and HTML
+//     with a lower preference.
+//   - For [ApplicationJSON] and other JSON media types, [RemoteDocument.ContextURL]
+//     is set to the target of a Link header with the [LinkRelContext] relation.
+//     More than one such link results in [ErrMultipleContextLinkHeaders]. The
+//     header is ignored for [ApplicationLDJSON].
+//   - For other media types, a Link header with the alternate relation and
+//     type [ApplicationLDJSON] is followed. [RemoteDocument.DocumentURL] remains
+//     the URL of the original document.
+//   - Any other media type results in [ErrLoadingDocument].
+//
+// HTML documents are returned as is. Wrap the loader with the Loader of the
+// [sourcery.dny.nu/longdistance/html] package to extract JSON-LD from them.
+//
+// The response body is read up to the size set with [WithDocumentMaxBodySize].
+// Set a timeout on the client, or on the context, to bound how long retrieval
+// can take.
+func NewHTTPDocumentLoader(
+	client *http.Client,
+	opts ...HTTPDocumentLoaderOption,
+) RemoteDocumentLoaderFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	l := &httpDocumentLoader{
+		maxBodySize: DefaultDocumentMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return func(ctx context.Context, url string) (RemoteDocument, error) {
+		var documentURL string
+
+		for range maxAlternateLinks {
+			doc, alternate, err := loadDocument(ctx, client, url, l.maxBodySize)
+			if err != nil {
+				return RemoteDocument{}, err
+			}
+
+			if alternate == "" {
+				if documentURL != "" {
+					doc.DocumentURL = documentURL
+				}
+				return doc, nil
+			}
+
+			if documentURL == "" {
+				documentURL = doc.DocumentURL
+			}
+			url = alternate
+		}
+
+		return RemoteDocument{}, fmt.Errorf("%w: too many alternate links", ErrLoadingDocument)
+	}
+}
+
+// DefaultDocumentMaxBodySize is the default for [WithDocumentMaxBodySize].
+const DefaultDocumentMaxBodySize = 10 << 20
+
+// HTTPDocumentLoaderOption can be used to configure the loader returned by
+// [NewHTTPDocumentLoader].
+type HTTPDocumentLoaderOption func(*httpDocumentLoader)
+
+// WithDocumentMaxBodySize sets the maximum size of a response body in bytes.
+// Larger responses result in [ErrLoadingDocument]. A size below 1 disables it.
+//
+// Defaults to [DefaultDocumentMaxBodySize].
+func WithDocumentMaxBodySize(n int64) HTTPDocumentLoaderOption {
+	return func(l *httpDocumentLoader) {
+		l.maxBodySize = n
+	}
+}
+
+type httpDocumentLoader struct {
+	maxBodySize int64
+}
+
+// loadDocument retrieves a single document. If the document links to an
+// alternate JSON-LD representation that has to be followed, its URL is
+// returned instead of the document body.
+func loadDocument(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	maxBodySize int64,
+) (RemoteDocument, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return RemoteDocument{}, "", fmt.Errorf("%w: %w", ErrLoadingDocument, err)
+	}
+	req.Header.Set("Accept", documentAccept)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return RemoteDocument{}, "", fmt.Errorf("%w: %w", ErrLoadingDocument, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return RemoteDocument{}, "", fmt.Errorf("%w: unexpected status: %s", ErrLoadingDocument, resp.Status)
+	}
+
+	doc := RemoteDocument{
+		DocumentURL: resp.Request.URL.String(),
+	}
+
+	mt, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return RemoteDocument{}, "", fmt.Errorf("%w: invalid content type: %w", ErrLoadingDocument, err)
+	}
+	doc.ContentType = mt
+	doc.Profile = params["profile"]
+
+	links := parseLinkHeader(resp.Header.Values("Link"), doc.DocumentURL)
+
+	switch {
+	case mt == ApplicationLDJSON:
+	case isJSONMediaType(mt):
+		var contexts []link
+		for _, l := range links {
+			if slices.Contains(l.rel, LinkRelContext) {
+				contexts = append(contexts, l)
+			}
+		}
+
+		if len(contexts) > 1 {
+			return RemoteDocument{}, "", ErrMultipleContextLinkHeaders
+		}
+
+		if len(contexts) == 1 {
+			doc.ContextURL = contexts[0].target
+		}
+	default:
+		for _, l := range links {
+			if slices.Contains(l.rel, "alternate") && l.typ == ApplicationLDJSON {
+				return doc, l.target, nil
+			}
+		}
+
+		if mt != TextHTML && mt != ApplicationXHTML {
+			return RemoteDocument{}, "", fmt.Errorf("%w: unsupported content type: %s", ErrLoadingDocument, mt)
+		}
+	}
+
+	var r io.Reader = resp.Body
+	if maxBodySize > 0 {
+		r = io.LimitReader(resp.Body, maxBodySize+1)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return RemoteDocument{}, "", fmt.Errorf("%w: %w", ErrLoadingDocument, err)
+	}
+
+	if maxBodySize > 0 && int64(len(body)) > maxBodySize {
+		return RemoteDocument{}, "", fmt.Errorf("%w: response exceeds %d bytes", ErrLoadingDocument, maxBodySize)
+	}
+	doc.Document = body
+
+	return doc, "", nil
+}
+
+// isJSONMediaType returns true for [ApplicationJSON] and media types with the
+// +json suffix.
+func isJSONMediaType(mt string) bool {
+	return mt == ApplicationJSON || strings.HasSuffix(mt, "+json")
+}
+
+// DefaultLoaderCacheSize is the default for [WithLoaderCacheSize].
+const DefaultLoaderCacheSize = 256
+
+// DefaultLoaderTimeout is the default for [WithLoaderTimeout].
+const DefaultLoaderTimeout = 30 * time.Second
+
+// DefaultLoaderMaxBodySize is the default for [WithLoaderMaxBodySize].
+const DefaultLoaderMaxBodySize = 1 << 20
+
+// HTTPContextLoaderOption can be used to configure the loader returned by
+// [NewHTTPContextLoader].
+type HTTPContextLoaderOption func(*httpContextLoader)
+
+// WithLoaderCacheSize sets the number of contexts that are cached.
+//
+// When the cache is full, the least recently used context is evicted. A size
+// below 1 disables caching.
+//
+// Defaults to [DefaultLoaderCacheSize].
+func WithLoaderCacheSize(n int) HTTPContextLoaderOption {
+	return func(l *httpContextLoader) {
+		l.cacheSize = n
+	}
+}
+
+// WithLoaderTimeout sets the maximum duration of a single fetch, regardless
+// of how many callers are waiting on it. A duration below 1 disables it.
+//
+// Defaults to [DefaultLoaderTimeout].
+func WithLoaderTimeout(d time.Duration) HTTPContextLoaderOption {
+	return func(l *httpContextLoader) {
+		l.timeout = d
+	}
+}
+
+// WithLoaderMaxBodySize sets the maximum size of a response body in bytes.
+// Larger responses result in a [PolicyError]. A size below 1 disables it.
+//
+// Defaults to [DefaultLoaderMaxBodySize].
+func WithLoaderMaxBodySize(n int64) HTTPContextLoaderOption {
+	return func(l *httpContextLoader) {
+		l.maxBodySize = n
+	}
+}
+
+// NewHTTPContextLoader returns a [RemoteContextLoaderFunc] that retrieves
+// contexts over HTTP using client. If client is nil, [http.DefaultClient] is
+// used.
+//
+// Contexts are requested as [ApplicationLDJSON] with the [ProfileContext]
+// profile. Redirects are followed by the client and [Document.URL] is set to
+// the final URL. Unless the client has a CheckRedirect function of its own,
+// [CheckRedirect] applies the loader policies to every redirect. The value of
+// the [KeywordContext] entry of the response becomes [Document.Context].
+// Responses that aren't [ApplicationLDJSON], [ApplicationJSON] or another
+// +json media type are rejected.
+//
+// Concurrent requests for the same URL result in a single fetch. Successfully
+// retrieved contexts are kept in a bounded cache, see [WithLoaderCacheSize].
+// Failures are not cached.
+//
+// The caching headers of the response are returned in [Document.Cache].
+// Conditional requests, see [WithConditionalRequest], bypass the cache.
+//
+// Every caller stops waiting once its context is done. The fetch itself is
+// cancelled when no callers are left waiting on it, or when it exceeds the
+// timeout set with [WithLoaderTimeout].
+//
+// Errors wrap [ErrLoadingRemoteContext], or [ErrInvalidRemoteContext] if the
+// response is not a JSON object.
+func NewHTTPContextLoader(
+	client *http.Client,
+	opts ...HTTPContextLoaderOption,
+) RemoteContextLoaderFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if client.CheckRedirect == nil {
+		c := *client
+		c.CheckRedirect = CheckRedirect
+		client = &c
+	}
+
+	l := &httpContextLoader{
+		client:      client,
+		cacheSize:   DefaultLoaderCacheSize,
+		timeout:     DefaultLoaderTimeout,
+		maxBodySize: DefaultLoaderMaxBodySize,
+		calls:       make(map[string]*contextCall),
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	l.cache = newLRU[string, Document](l.cacheSize)
+
+	return l.load
+}
+
+// contextAccept is the Accept header sent when retrieving a context.
+var contextAccept = mime.FormatMediaType(
+	ApplicationLDJSON,
+	map[string]string{"profile": ProfileContext},
+) + ", " + ApplicationLDJSON + ";q=0.9, " + ApplicationJSON + ";q=0.8"
+
+type httpContextLoader struct {
+	client      *http.Client
+	cacheSize   int
+	timeout     time.Duration
+	maxBodySize int64
+
+	mu    sync.Mutex
+	cache *lru[string, Document]
+	calls map[string]*contextCall
+}
+
+// contextCall is a fetch in progress.
+type contextCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+
+	doc Document
+	err error
+}
+
+func (l *httpContextLoader) load(ctx context.Context, url string) (Document, error) {
+	// conditional requests always go to the origin, and are only shared
+	// with requests using the same validators
+	key := url
+	cond, conditional := ConditionalRequest(ctx)
+	if conditional {
+		key = url + "\x00" + cond.ETag + "\x00" + cond.LastModified
+	}
+
+	l.mu.Lock()
+
+	if !conditional {
+		if doc, ok := l.cache.get(url); ok {
+			l.mu.Unlock()
+			return doc, nil
+		}
+	}
+
+	call, ok := l.calls[key]
+	if !ok {
+		// the fetch outlives the caller that started it, as long as
+		// others are waiting on it
+		fetchCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		stop := context.CancelFunc(func() {})
+		if l.timeout > 0 {
+			fetchCtx, stop = context.WithTimeout(fetchCtx, l.timeout)
+		}
+
+		call = &contextCall{
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		l.calls[key] = call
+
+		go l.fetch(fetchCtx, stop, url, key, call)
+	}
+
+	call.waiters++
+	l.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.doc, call.err
+	case <-ctx.Done():
+		l.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.cancel()
+			if l.calls[key] == call {
+				delete(l.calls, key)
+			}
+		}
+		l.mu.Unlock()
+
+		return Document{}, fmt.Errorf("%w: %w", ErrLoadingRemoteContext, ctx.Err())
+	}
+}
+
+func (l *httpContextLoader) fetch(
+	ctx context.Context,
+	stop context.CancelFunc,
+	url string,
+	key string,
+	call *contextCall,
+) {
+	defer call.cancel()
+	defer stop()
+
+	doc, err := fetchContext(ctx, l.client, url, l.maxBodySize)
+
+	l.mu.Lock()
+	if l.calls[key] == call {
+		delete(l.calls, key)
+	}
+	if err == nil && !doc.Cache.NotModified {
+		l.cache.add(url, doc)
+	}
+	call.doc, call.err = doc, err
+	l.mu.Unlock()
+
+	close(call.done)
+}
+
+// fetchContext retrieves a single context.
+func fetchContext(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	maxBodySize int64,
+) (Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Document{}, fmt.Errorf("%w: %w", ErrLoadingRemoteContext, err)
+	}
+	req.Header.Set("Accept", contextAccept)
+
+	cond, conditional := ConditionalRequest(ctx)
+	if conditional {
+		if cond.ETag != "" {
+			req.Header.Set("If-None-Match", cond.ETag)
+		}
+		if cond.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cond.LastModified)
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Document{}, fmt.Errorf("%w: %w", ErrLoadingRemoteContext, err)
+	}
+	defer resp.Body.Close()
+
+	info := CacheInfo{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		CacheControl: resp.Header.Get("Cache-Control"),
+	}
+
+	if conditional && resp.StatusCode == http.StatusNotModified {
+		return Document{
+			URL: resp.Request.URL.String(),
+			Cache: CacheInfo{
+				ETag:         cmp.Or(info.ETag, cond.ETag),
+				LastModified: cmp.Or(info.LastModified, cond.LastModified),
+				CacheControl: cmp.Or(info.CacheControl, cond.CacheControl),
+				NotModified:  true,
+			},
+		}, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Document{}, fmt.Errorf("%w: unexpected status: %s", ErrLoadingRemoteContext, resp.Status)
+	}
+
+	mt, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return Document{}, fmt.Errorf("%w: invalid content type: %w", ErrLoadingRemoteContext, err)
+	}
+
+	if !isJSONMediaType(mt) {
+		return Document{}, fmt.Errorf("%w: unsupported content type: %s", ErrLoadingRemoteContext, mt)
+	}
+
+	var r io.Reader = resp.Body
+	if maxBodySize > 0 {
+		r = io.LimitReader(resp.Body, maxBodySize+1)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, fmt.Errorf("%w: %w", ErrLoadingRemoteContext, err)
+	}
+
+	if maxBodySize > 0 && int64(len(body)) > maxBodySize {
+		return Document{}, &PolicyError{
+			Policy: PolicySize,
+			URL:    url,
+			Reason: fmt.Sprintf("response exceeds %d bytes", maxBodySize),
+		}
+	}
+
+	lctx, err := extractContext(body)
+	if err != nil {
+		return Document{}, err
+	}
+
+	return Document{
+		URL:     resp.Request.URL.String(),
+		Context: lctx,
+		Cache:   info,
+	}, nil
+}
@@ -0,0 +1,545 @@
+package longdistance_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestHTTPDocumentLoader(t *testing.T) {
+	const ctxLink = `<context.jsonld>; rel="http://www.w3.org/ns/json-ld#context"`
+
+	mux := http.NewServeMux()
+	serve := func(path, contentType string, links []string, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Accept"); got == "" {
+				t.Errorf("missing Accept header for %s", path)
+			}
+			w.Header().Set("Content-Type", contentType)
+			for _, l := range links {
+				w.Header().Add("Link", l)
+			}
+			w.Write([]byte(body))
+		})
+	}
+
+	serve("/ld", ld.ApplicationLDJSON+`; profile="http://www.w3.org/ns/json-ld#expanded"`, []string{ctxLink}, `{"@id": "1"}`)
+	serve("/json", ld.ApplicationJSON, []string{ctxLink}, `{"id": "1"}`)
+	serve("/activity", "application/activity+json", []string{
+		`<https://example.org/other>; rel="next", ` + ctxLink,
+	}, `{"id": "1"}`)
+	serve("/plain-json", ld.ApplicationJSON, nil, `{"id": "1"}`)
+	serve("/multiple", ld.ApplicationJSON, []string{ctxLink + ", " + ctxLink}, `{}`)
+	serve("/multiple-headers", ld.ApplicationJSON, []string{ctxLink, `<other.jsonld>; title="a, b"; rel="http://www.w3.org/ns/json-ld#context"`}, `{}`)
+	serve("/html-alternate", ld.TextHTML, []string{`<alternate>; rel="alternate"; type="application/ld+json"`}, `<html></html>`)
+	serve("/text-alternate", "text/plain", []string{`<alternate>; rel=alternate; type="application/ld+json"`}, `hello`)
+	serve("/html", ld.TextHTML, []string{`<alternate>; rel="alternate"; type="application/json"`}, `<html></html>`)
+	serve("/text", "text/plain", nil, `hello`)
+	serve("/alternate", ld.ApplicationLDJSON, nil, `{"@id": "alternate"}`)
+	mux.Handle("/redirect", http.RedirectHandler("/ld", http.StatusFound))
+	mux.Handle("/missing", http.NotFoundHandler())
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	loader := ld.NewHTTPDocumentLoader(srv.Client())
+
+	tests := []struct {
+		name string
+		path string
+		out  ld.RemoteDocument
+		err  error
+	}{
+		{
+			name: "JSON-LD ignores context link",
+			path: "/ld",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/ld",
+				ContentType: ld.ApplicationLDJSON,
+				Profile:     ld.ProfileExpanded,
+				Document:    json.RawMessage(`{"@id": "1"}`),
+			},
+		},
+		{
+			name: "JSON with context link",
+			path: "/json",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/json",
+				ContentType: ld.ApplicationJSON,
+				ContextURL:  srv.URL + "/context.jsonld",
+				Document:    json.RawMessage(`{"id": "1"}`),
+			},
+		},
+		{
+			name: "+json with context link among others",
+			path: "/activity",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/activity",
+				ContentType: "application/activity+json",
+				ContextURL:  srv.URL + "/context.jsonld",
+				Document:    json.RawMessage(`{"id": "1"}`),
+			},
+		},
+		{
+			name: "JSON without context link",
+			path: "/plain-json",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/plain-json",
+				ContentType: ld.ApplicationJSON,
+				Document:    json.RawMessage(`{"id": "1"}`),
+			},
+		},
+		{
+			name: "multiple context links",
+			path: "/multiple",
+			err:  ld.ErrMultipleContextLinkHeaders,
+		},
+		{
+			name: "multiple context link headers",
+			path: "/multiple-headers",
+			err:  ld.ErrMultipleContextLinkHeaders,
+		},
+		{
+			name: "HTML with alternate link",
+			path: "/html-alternate",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/html-alternate",
+				ContentType: ld.ApplicationLDJSON,
+				Document:    json.RawMessage(`{"@id": "alternate"}`),
+			},
+		},
+		{
+			name: "text with alternate link",
+			path: "/text-alternate",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/text-alternate",
+				ContentType: ld.ApplicationLDJSON,
+				Document:    json.RawMessage(`{"@id": "alternate"}`),
+			},
+		},
+		{
+			name: "HTML without JSON-LD alternate link",
+			path: "/html",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/html",
+				ContentType: ld.TextHTML,
+				Document:    json.RawMessage(`<html></html>`),
+			},
+		},
+		{
+			name: "redirect",
+			path: "/redirect",
+			out: ld.RemoteDocument{
+				DocumentURL: srv.URL + "/ld",
+				ContentType: ld.ApplicationLDJSON,
+				Profile:     ld.ProfileExpanded,
+				Document:    json.RawMessage(`{"@id": "1"}`),
+			},
+		},
+		{
+			name: "unsupported content type",
+			path: "/text",
+			err:  ld.ErrLoadingDocument,
+		},
+		{
+			name: "not found",
+			path: "/missing",
+			err:  ld.ErrLoadingDocument,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := loader(t.Context(), srv.URL+tc.path)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error: %v, got: %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected successful load, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.out, doc); diff != "" {
+				t.Errorf("document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPDocumentLoaderMaxBodySize(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@id": "https://example.org/1"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name string
+		size int64
+		err  error
+	}{
+		{name: "too large", size: 16, err: ld.ErrLoadingDocument},
+		{name: "exact size", size: 32},
+		{name: "disabled", size: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			loader := ld.NewHTTPDocumentLoader(srv.Client(), ld.WithDocumentMaxBodySize(tc.size))
+
+			_, err := loader(t.Context(), srv.URL)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error: %v, got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestHTTPDocumentLoaderExpand(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/note", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.ApplicationJSON)
+		w.Header().Set("Link", `</context.jsonld>; rel="http://www.w3.org/ns/json-ld#context"; type="application/ld+json"`)
+		w.Write([]byte(`{"id": "note", "content": "Hello", "inReplyTo": "1"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ctxLoader := func(_ context.Context, url string) (ld.Document, error) {
+		if url != srv.URL+"/context.jsonld" {
+			return ld.Document{}, ld.ErrLoadingRemoteContext
+		}
+		return ld.Document{
+			URL: url,
+			Context: json.RawMessage(`{
+				"id": "@id",
+				"content": "https://example.org/content",
+				"inReplyTo": {"@id": "https://example.org/inReplyTo", "@type": "@id"}
+			}`),
+		}, nil
+	}
+
+	proc := ld.NewProcessor(
+		ld.WithRemoteDocumentLoader(ld.NewHTTPDocumentLoader(srv.Client())),
+		ld.WithRemoteContextLoader(ctxLoader),
+	)
+
+	nodes, err := proc.ExpandURL(t.Context(), srv.URL+"/note")
+	if err != nil {
+		t.Fatalf("expected successful expansion, got: %s", err)
+	}
+
+	got, err := json.Marshal(nodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := json.RawMessage(`[{
+		"@id": "` + srv.URL + `/note",
+		"https://example.org/content": [{"@value": "Hello"}],
+		"https://example.org/inReplyTo": [{"@id": "` + srv.URL + `/1"}]
+	}]`)
+
+	if diff := cmp.Diff(want, json.RawMessage(got), JSONDiff()); diff != "" {
+		t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHTTPContextLoader(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/context.jsonld", func(w http.ResponseWriter, r *http.Request) {
+		mt, params, err := mime.ParseMediaType(strings.Split(r.Header.Get("Accept"), ",")[0])
+		if err != nil || mt != ld.ApplicationLDJSON || params["profile"] != ld.ProfileContext {
+			t.Errorf("unexpected Accept header: %s", r.Header.Get("Accept"))
+		}
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@context": {"name": "https://example.org/name"}, "name": "ignored"}`))
+	})
+	mux.HandleFunc("/no-context.jsonld", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.ApplicationJSON)
+		w.Write([]byte(`{"name": "https://example.org/name"}`))
+	})
+	mux.HandleFunc("/array.jsonld", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`[{"@context": {}}]`))
+	})
+	mux.HandleFunc("/profile.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/activity+json; charset=utf-8")
+		w.Write([]byte(`{"@context": {"name": "https://example.org/name"}}`))
+	})
+	mux.HandleFunc("/error.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.TextHTML)
+		w.Write([]byte(`<html><body>{"@context": {}}</body></html>`))
+	})
+	mux.HandleFunc("/plain.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(`{"@context": {"name": "https://example.org/name"}}`))
+	})
+	mux.Handle("/redirect", http.RedirectHandler("/context.jsonld", http.StatusMovedPermanently))
+	mux.Handle("/missing", http.NotFoundHandler())
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	loader := ld.NewHTTPContextLoader(srv.Client())
+
+	tests := []struct {
+		name string
+		path string
+		out  ld.Document
+		err  error
+	}{
+		{
+			name: "context",
+			path: "/context.jsonld",
+			out: ld.Document{
+				URL:     srv.URL + "/context.jsonld",
+				Context: json.RawMessage(`{"name": "https://example.org/name"}`),
+			},
+		},
+		{
+			name: "redirect",
+			path: "/redirect",
+			out: ld.Document{
+				URL:     srv.URL + "/context.jsonld",
+				Context: json.RawMessage(`{"name": "https://example.org/name"}`),
+			},
+		},
+		{
+			name: "no context entry",
+			path: "/no-context.jsonld",
+			out: ld.Document{
+				URL:     srv.URL + "/no-context.jsonld",
+				Context: json.RawMessage(`{}`),
+			},
+		},
+		{
+			name: "json media type",
+			path: "/profile.json",
+			out: ld.Document{
+				URL:     srv.URL + "/profile.json",
+				Context: json.RawMessage(`{"name": "https://example.org/name"}`),
+			},
+		},
+		{
+			name: "not an object",
+			path: "/array.jsonld",
+			err:  ld.ErrInvalidRemoteContext,
+		},
+		{
+			name: "html",
+			path: "/error.html",
+			err:  ld.ErrLoadingRemoteContext,
+		},
+		{
+			name: "plain text",
+			path: "/plain.txt",
+			err:  ld.ErrLoadingRemoteContext,
+		},
+		{
+			name: "not found",
+			path: "/missing",
+			err:  ld.ErrLoadingRemoteContext,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			doc, err := loader(t.Context(), srv.URL+tc.path)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Fatalf("expected error: %v, got: %v", tc.err, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected successful load, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.out, doc, JSONDiff()); diff != "" {
+				t.Errorf("document mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPContextLoaderDeduplication(t *testing.T) {
+	var hits atomic.Int32
+	release := make(chan struct{})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		<-release
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@context": {}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	loader := ld.NewHTTPContextLoader(srv.Client())
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := loader(t.Context(), srv.URL)
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected successful load, got: %s", err)
+		}
+	}
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("expected 1 request, got: %d", n)
+	}
+}
+
+func TestHTTPContextLoaderCache(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "nope", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@context": {}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	tests := []struct {
+		name  string
+		size  int
+		paths []string
+		hits  int32
+	}{
+		{name: "cached", size: 2, paths: []string{"/a", "/b", "/a", "/b"}, hits: 2},
+		{name: "evicted", size: 1, paths: []string{"/a", "/b", "/a"}, hits: 3},
+		{name: "recently used kept", size: 2, paths: []string{"/a", "/b", "/a", "/c", "/a"}, hits: 3},
+		{name: "disabled", size: 0, paths: []string{"/a", "/a"}, hits: 2},
+		{name: "failures not cached", size: 2, paths: []string{"/fail", "/fail"}, hits: 2},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hits.Store(0)
+			loader := ld.NewHTTPContextLoader(srv.Client(), ld.WithLoaderCacheSize(tc.size))
+
+			for _, path := range tc.paths {
+				loader(t.Context(), srv.URL+path)
+			}
+
+			if n := hits.Load(); n != tc.hits {
+				t.Errorf("expected %d requests, got: %d", tc.hits, n)
+			}
+		})
+	}
+}
+
+func TestHTTPContextLoaderTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Run("caller", func(t *testing.T) {
+		loader := ld.NewHTTPContextLoader(srv.Client())
+
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := loader(ctx, srv.URL)
+		if !errors.Is(err, ld.ErrLoadingRemoteContext) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected a deadline exceeded error, got: %v", err)
+		}
+
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected the fetch to be cancelled")
+		}
+	})
+
+	t.Run("fetch", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+		}))
+		t.Cleanup(srv.Close)
+
+		loader := ld.NewHTTPContextLoader(srv.Client(), ld.WithLoaderTimeout(50*time.Millisecond))
+
+		_, err := loader(t.Context(), srv.URL)
+		if !errors.Is(err, ld.ErrLoadingRemoteContext) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected a deadline exceeded error, got: %v", err)
+		}
+	})
+}
+
+func TestHTTPContextLoaderSharedFetch(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@context": {}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	loader := ld.NewHTTPContextLoader(srv.Client())
+
+	result := make(chan error)
+	go func() {
+		_, err := loader(t.Context(), srv.URL)
+		result <- err
+	}()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := loader(ctx, srv.URL); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancellation error, got: %v", err)
+	}
+
+	close(release)
+
+	if err := <-result; err != nil {
+		t.Fatalf("expected the remaining caller to succeed, got: %s", err)
+	}
+}
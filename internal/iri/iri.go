@@ -106,5 +106,13 @@ func Resolve(base string, val string) (string, error) {
 		return "", err
 	}
 
-	return u.ResolveReference(r).String(), nil
+	res := u.ResolveReference(r).String()
+
+	// an empty fragment can't be represented by a url.URL, but is
+	// significant in a vocabulary mapping like "#"
+	if strings.HasSuffix(val, "#") && !strings.HasSuffix(res, "#") {
+		res += "#"
+	}
+
+	return res, nil
 }
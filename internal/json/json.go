@@ -16,6 +16,8 @@
 var NewEncoder = json.NewEncoder
 var Marshal = json.Marshal
 var Unmarshal = json.Unmarshal
+var Valid = json.Valid
+var Compact = json.Compact
 
 var (
 	beginArray  = byte('[')
@@ -8,6 +8,29 @@
 	"sourcery.dny.nu/longdistance/internal/iri"
 )
 
+// ExpandIRI expands value to an IRI using the active context activeCtx.
+//
+// With vocab set, value is expanded like a key in a document: terms and
+// compact IRIs are expanded and @vocab applies. Values that can't be expanded
+// are returned as-is, they're not IRIs. Without vocab, value is expanded like
+// the value of @id: compact IRIs are expanded and relative IRIs are resolved
+// against the base IRI.
+//
+// Keywords are returned as-is. Values that look like a keyword result in the
+// empty string.
+func (p *Processor) ExpandIRI(
+	ctx context.Context,
+	activeCtx *Context,
+	value string,
+	vocab bool,
+) (string, error) {
+	if activeCtx == nil {
+		activeCtx = newContext(p.baseIRI)
+	}
+
+	return p.expandIRI(ctx, activeCtx, value, !vocab, vocab, nil, nil)
+}
+
 func (p *Processor) expandIRI(
 	ctx context.Context,
 	activeCtx *Context,
@@ -31,6 +31,17 @@
 	KeywordVocab     = "@vocab"
 )
 
+// JSON-LD framing keywords.
+const (
+	KeywordAlways      = "@always"
+	KeywordEmbed       = "@embed"
+	KeywordExplicit    = "@explicit"
+	KeywordNever       = "@never"
+	KeywordOmitDefault = "@omitDefault"
+	KeywordOnce        = "@once"
+	KeywordRequireAll  = "@requireAll"
+)
+
 // isKeyword returns if the string matches a known JSON-LD keyword.
 func isKeyword(s string) bool {
 	switch s {
@@ -65,6 +76,21 @@ func isKeyword(s string) bool {
 	}
 }
 
+// isFramingKeyword returns if the string is a keyword that is only valid in
+// a frame.
+func isFramingKeyword(s string) bool {
+	switch s {
+	case KeywordDefault,
+		KeywordEmbed,
+		KeywordExplicit,
+		KeywordOmitDefault,
+		KeywordRequireAll:
+		return true
+	default:
+		return false
+	}
+}
+
 // looksLikeKeyword determines if a string has the general shape of a JSON-LD
 // keyword.
 //
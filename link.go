@@ -0,0 +1,119 @@
+package longdistance
+
+import (
+	"strings"
+
+	"sourcery.dny.nu/longdistance/internal/iri"
+)
+
+// link is a link from a Link header, as described in RFC 8288.
+type link struct {
+	target string
+	rel    []string
+	typ    string
+}
+
+// parseLinkHeader parses the values of the Link headers of a response. Link
+// targets are resolved against base. Malformed links are skipped.
+func parseLinkHeader(values []string, base string) []link {
+	var links []link
+
+	for _, value := range values {
+		s := value
+
+		for {
+			s = strings.TrimLeft(s, " \t,")
+			if s == "" || s[0] != '<' {
+				break
+			}
+
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				break
+			}
+
+			l := link{target: s[1:end]}
+			s = s[end+1:]
+
+			params, rest := parseLinkParams(s)
+			s = rest
+
+			for _, rel := range strings.Fields(params["rel"]) {
+				l.rel = append(l.rel, strings.ToLower(rel))
+			}
+			l.typ = strings.ToLower(params["type"])
+
+			if base != "" {
+				resolved, err := iri.Resolve(base, l.target)
+				if err != nil {
+					continue
+				}
+				l.target = resolved
+			}
+
+			links = append(links, l)
+		}
+	}
+
+	return links
+}
+
+// parseLinkParams parses the parameters of a single link, up to the comma
+// that separates it from the next link. Only the first occurrence of a
+// parameter is kept.
+func parseLinkParams(s string) (map[string]string, string) {
+	params := map[string]string{}
+
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" || s[0] != ';' {
+			return params, s
+		}
+		s = strings.TrimLeft(s[1:], " \t")
+
+		end := strings.IndexAny(s, "=;,")
+		if end < 0 {
+			end = len(s)
+		}
+
+		name := strings.ToLower(strings.TrimSpace(s[:end]))
+		s = s[end:]
+
+		var value string
+		if s != "" && s[0] == '=' {
+			value, s = parseLinkParamValue(strings.TrimLeft(s[1:], " \t"))
+		}
+
+		if _, ok := params[name]; !ok && name != "" {
+			params[name] = value
+		}
+	}
+}
+
+// parseLinkParamValue parses a token or a quoted string.
+func parseLinkParamValue(s string) (string, string) {
+	if s == "" || s[0] != '"' {
+		end := strings.IndexAny(s, ";,")
+		if end < 0 {
+			end = len(s)
+		}
+		return strings.TrimSpace(s[:end]), s[end:]
+	}
+
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), ""
+}
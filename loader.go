@@ -20,6 +20,11 @@
 //   - Have proper timeouts, retry handling and request deduplication.
 //   - Make sure to cache the resulting [Document] to avoid unnecessary future
 //     requests. Contexts should not change for the lifetime of the application.
+//
+// [NewHTTPContextLoader] returns an implementation that retrieves contexts
+// over HTTP, and [NewFSLoader] one that serves them from a file system.
+// Remote contexts are untrusted input, use [WrapLoader] with policies like
+// [AllowHosts] and [DenyPrivateAddresses] to restrict what can be retrieved.
 type RemoteContextLoaderFunc func(context.Context, string) (Document, error)
 
 // Document holds a retrieved context.
@@ -27,7 +32,97 @@
 //   - URL holds the final URL a context was retrieved from, after following
 //     redirects.
 //   - Context holds the value of the @context element, or the empty map.
+//   - Cache holds the HTTP caching metadata of the response. Loaders that
+//     don't retrieve contexts over HTTP can leave it empty.
 type Document struct {
 	URL     string
 	Context json.RawMessage
+	Cache   CacheInfo
+}
+
+// CacheInfo holds the HTTP caching metadata of a retrieved context.
+//
+//   - ETag, LastModified and CacheControl hold the values of the
+//     corresponding response headers.
+//   - NotModified is set when a conditional request, see
+//     [WithConditionalRequest], resulted in a 304 Not Modified. The
+//     [Document] has no Context in that case.
+type CacheInfo struct {
+	ETag         string
+	LastModified string
+	CacheControl string
+	NotModified  bool
+}
+
+// extractContext returns the value of the @context entry of a context
+// document, or the empty map if it has none.
+//
+// It returns [ErrInvalidRemoteContext] if data is not a JSON object.
+func extractContext(data []byte) (json.RawMessage, error) {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil || obj == nil {
+		return nil, ErrInvalidRemoteContext
+	}
+
+	lctx, ok := obj[KeywordContext]
+	if !ok {
+		lctx = json.RawMessage(`{}`)
+	}
+
+	return lctx, nil
+}
+
+type conditionalRequestKey struct{}
+
+// WithConditionalRequest returns a context asking the loader to only return
+// the context if it changed since it was retrieved with the validators in
+// info.
+//
+// Loaders that support this send a conditional request and return a
+// [Document] with [CacheInfo.NotModified] set if the context didn't change.
+// Other loaders return the context as usual.
+func WithConditionalRequest(ctx context.Context, info CacheInfo) context.Context {
+	return context.WithValue(ctx, conditionalRequestKey{}, info)
+}
+
+// ConditionalRequest returns the validators set with
+// [WithConditionalRequest], if any.
+func ConditionalRequest(ctx context.Context) (CacheInfo, bool) {
+	info, ok := ctx.Value(conditionalRequestKey{}).(CacheInfo)
+	return info, ok
+}
+
+// RemoteDocumentLoaderFunc is called to retrieve a remote document.
+//
+// It returns a RemoteDocument, and an error in case retrieval failed.
+//
+// When building your own loader, please remember that:
+//   - [RemoteDocument.DocumentURL] is the URL the document was retrieved from
+//     after having followed any redirects.
+//   - Request a document with [ApplicationLDJSON], followed by
+//     [ApplicationJSON] with a lower preference.
+//   - When the response is not [ApplicationLDJSON], set
+//     [RemoteDocument.ContextURL] to the target of a Link header with the
+//     relation http://www.w3.org/ns/json-ld#context.
+//   - Have proper timeouts and limit the size of the response body.
+//
+// [NewHTTPDocumentLoader] returns an implementation that retrieves documents
+// over HTTP.
+type RemoteDocumentLoaderFunc func(context.Context, string) (RemoteDocument, error)
+
+// RemoteDocument holds a retrieved document.
+//
+//   - DocumentURL holds the final URL a document was retrieved from, after
+//     following redirects.
+//   - ContentType holds the media type of the document, without parameters.
+//   - ContextURL holds the URL of a context linked to with a Link header.
+//   - Profile holds the value of the profile parameter of the media type, if
+//     any.
+//   - Document holds the body of the response.
+type RemoteDocument struct {
+	DocumentURL string
+	ContentType string
+	ContextURL  string
+	Profile     string
+	Document    json.RawMessage
 }
@@ -0,0 +1,97 @@
+package longdistance
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lru is a least recently used cache holding up to size entries.
+//
+// It is not safe for concurrent use.
+type lru[K comparable, V any] struct {
+	size    int
+	order   *list.List
+	entries map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func newLRU[K comparable, V any](size int) *lru[K, V] {
+	return &lru[K, V]{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// get returns the value for key and marks it as recently used.
+func (c *lru[K, V]) get(key K) (V, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry[K, V]).value, true
+}
+
+// add stores value for key, evicting the least recently used entry if the
+// cache is full. A cache with a size below 1 stores nothing.
+func (c *lru[K, V]) add(key K, value V) {
+	if c.size < 1 {
+		return
+	}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lruEntry[K, V]).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+}
+
+// DefaultContextCacheSize is the default for [WithContextCacheSize].
+const DefaultContextCacheSize = 128
+
+// contextCache holds processed remote contexts. It is safe for concurrent
+// use.
+type contextCache struct {
+	mu    sync.Mutex
+	cache *lru[string, *Context]
+}
+
+func newContextCache(size int) *contextCache {
+	return &contextCache{cache: newLRU[string, *Context](size)}
+}
+
+// get returns a copy of the cached context.
+func (c *contextCache) get(key string) (*Context, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ctx, ok := c.cache.get(key)
+	if !ok {
+		return nil, false
+	}
+
+	return ctx.clone(), true
+}
+
+// add caches a copy of the context.
+func (c *contextCache) add(key string, ctx *Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.add(key, ctx.clone())
+}
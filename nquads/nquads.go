@@ -0,0 +1,42 @@
+// Package nquads reads and writes RDF datasets in the N-Quads format.
+//
+// A [Reader] parses a stream of N-Quads one statement at a time, reporting
+// syntax errors as a [ParseError] with the line and column they occurred on.
+// A [Writer] outputs quads in canonical N-Quads, so a dataset written by it
+// can be read back by the [Reader] without loss.
+//
+// See https://www.w3.org/TR/n-quads/.
+package nquads
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned as part of a [ParseError].
+var (
+	ErrUnexpectedCharacter = errors.New("unexpected character")
+	ErrUnexpectedEnd       = errors.New("unexpected end of line")
+	ErrInvalidIRI          = errors.New("invalid IRI")
+	ErrInvalidBlankNode    = errors.New("invalid blank node label")
+	ErrInvalidLanguage     = errors.New("invalid language tag")
+	ErrInvalidEscape       = errors.New("invalid escape sequence")
+	ErrLineTooLong         = errors.New("line too long")
+)
+
+// ParseError is returned for N-Quads syntax errors.
+//
+// Line and Column are 1-based. The column is a byte index into the line.
+type ParseError struct {
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("nquads: line %d, column %d: %s", e.Line, e.Column, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,281 @@
+package nquads_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/nquads"
+)
+
+func TestSyntax(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.nq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".nq")
+
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dataset, err := nquads.NewReader(bytes.NewReader(data)).ReadAll()
+
+			if strings.Contains(name, "-bad-") {
+				var perr *nquads.ParseError
+				if !errors.As(err, &perr) {
+					t.Fatalf("expected a parse error, got: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected successful parse, got: %s", err)
+			}
+
+			var buf bytes.Buffer
+			if err := nquads.NewWriter(&buf).WriteAll(dataset); err != nil {
+				t.Fatalf("failed to write: %s", err)
+			}
+
+			reloaded, err := nquads.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("expected successful parse of written quads, got: %s\n%s", err, buf.String())
+			}
+
+			if diff := cmp.Diff(dataset, reloaded); diff != "" {
+				t.Errorf("round-trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	s := ld.NewIRI("http://example/s")
+	p := ld.NewIRI("http://example/p")
+
+	tests := []struct {
+		name string
+		in   string
+		out  ld.Dataset
+	}{
+		{
+			name: "graph",
+			in:   "<http://example/s> <http://example/p> _:o <http://example/g> .",
+			out: ld.Dataset{
+				{Subject: s, Predicate: p, Object: ld.NewBlankNode("_:o"), Graph: ld.NewIRI("http://example/g")},
+			},
+		},
+		{
+			name: "blank node label ending before the dot",
+			in:   "_:s.1 <http://example/p> _:o.",
+			out: ld.Dataset{
+				{Subject: ld.NewBlankNode("_:s.1"), Predicate: p, Object: ld.NewBlankNode("_:o")},
+			},
+		},
+		{
+			name: "literals",
+			in: `<http://example/s> <http://example/p> "a\tb\u00e9\U0001F600" .
+<http://example/s> <http://example/p> "chat"@fr-BE .
+<http://example/s> <http://example/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example/s> <http://example/p> "x"^^<http://www.w3.org/2001/XMLSchema#string> .`,
+			out: ld.Dataset{
+				{Subject: s, Predicate: p, Object: ld.NewLiteral("a\tbé😀", "", "")},
+				{Subject: s, Predicate: p, Object: ld.NewLiteral("chat", "", "fr-BE")},
+				{Subject: s, Predicate: p, Object: ld.NewLiteral("1", ld.XSDInteger, "")},
+				{Subject: s, Predicate: p, Object: ld.NewLiteral("x", "", "")},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dataset, err := nquads.NewReader(strings.NewReader(tc.in)).ReadAll()
+			if err != nil {
+				t.Fatalf("expected successful parse, got: %s", err)
+			}
+
+			if diff := cmp.Diff(tc.out, dataset); diff != "" {
+				t.Errorf("parse mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		in     string
+		line   int
+		column int
+		err    error
+	}{
+		{
+			name:   "relative IRI",
+			in:     "# comment\n\n<s> <http://example/p> <http://example/o> .",
+			line:   3,
+			column: 1,
+			err:    nquads.ErrInvalidIRI,
+		},
+		{
+			name:   "space in IRI",
+			in:     "<http://example/s> <http://example/p> <http://example/ o> .",
+			line:   1,
+			column: 55,
+			err:    nquads.ErrInvalidIRI,
+		},
+		{
+			name:   "literal as subject",
+			in:     `"s" <http://example/p> <http://example/o> .`,
+			line:   1,
+			column: 1,
+			err:    nquads.ErrUnexpectedCharacter,
+		},
+		{
+			name:   "invalid escape",
+			in:     "<http://example/s> <http://example/p> <http://example/o> .\r\n<http://example/s> <http://example/p> \"\\a\" .",
+			line:   2,
+			column: 40,
+			err:    nquads.ErrInvalidEscape,
+		},
+		{
+			name:   "missing dot",
+			in:     "<http://example/s> <http://example/p> <http://example/o>",
+			line:   1,
+			column: 57,
+			err:    nquads.ErrUnexpectedEnd,
+		},
+		{
+			name:   "invalid language",
+			in:     `<http://example/s> <http://example/p> "o"@-en .`,
+			line:   1,
+			column: 42,
+			err:    nquads.ErrInvalidLanguage,
+		},
+		{
+			name:   "invalid blank node",
+			in:     "_:-s <http://example/p> <http://example/o> .",
+			line:   1,
+			column: 1,
+			err:    nquads.ErrInvalidBlankNode,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := nquads.NewReader(strings.NewReader(tc.in)).ReadAll()
+
+			var perr *nquads.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a parse error, got: %v", err)
+			}
+
+			if !errors.Is(err, tc.err) {
+				t.Errorf("expected error: %v, got: %v", tc.err, perr.Err)
+			}
+
+			if perr.Line != tc.line || perr.Column != tc.column {
+				t.Errorf("expected error at %d:%d, got: %d:%d", tc.line, tc.column, perr.Line, perr.Column)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	s := ld.NewIRI("http://example/s")
+	p := ld.NewIRI("http://example/p")
+
+	dataset := ld.Dataset{
+		{Subject: s, Predicate: p, Object: ld.NewIRI("http://example/o")},
+		{Subject: ld.NewBlankNode("_:b0"), Predicate: p, Object: ld.NewBlankNode("_:b1"), Graph: ld.NewBlankNode("_:g")},
+		{Subject: s, Predicate: p, Object: ld.NewLiteral("a\"b\\c\nd\te\x00f\x7fé", "", "")},
+		{Subject: s, Predicate: p, Object: ld.NewLiteral("chat", "", "fr")},
+		{Subject: s, Predicate: p, Object: ld.NewLiteral("1", ld.XSDInteger, ""), Graph: ld.NewIRI("http://example/g")},
+		{Subject: s, Predicate: p, Object: ld.NewIRI("http://example/a b")},
+	}
+
+	want := `<http://example/s> <http://example/p> <http://example/o> .
+_:b0 <http://example/p> _:b1 _:g .
+<http://example/s> <http://example/p> "a\"b\\c\nd\te\u0000f\u007Fé" .
+<http://example/s> <http://example/p> "chat"@fr .
+<http://example/s> <http://example/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> <http://example/g> .
+<http://example/s> <http://example/p> <http://example/a\u0020b> .
+`
+
+	var buf bytes.Buffer
+	if err := nquads.NewWriter(&buf).WriteAll(dataset); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("output mismatch (-want +got):\n%s", diff)
+	}
+
+	reloaded, err := nquads.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("expected successful parse, got: %s", err)
+	}
+
+	if diff := cmp.Diff(dataset, reloaded); diff != "" {
+		t.Errorf("round-trip mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("..", "testdata", "longdistance", "tordf", "*", "in.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(filepath.Dir(file)), func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			proc := ld.NewProcessor()
+
+			nodes, err := proc.Expand(t.Context(), bytes.NewReader(data), "")
+			if err != nil {
+				t.Fatalf("expected successful expand, got: %s", err)
+			}
+
+			dataset, err := proc.ToRDF(nodes)
+			if err != nil {
+				t.Fatalf("expected successful conversion to RDF, got: %s", err)
+			}
+
+			var buf bytes.Buffer
+			if err := nquads.NewWriter(&buf).WriteAll(dataset); err != nil {
+				t.Fatalf("failed to write: %s", err)
+			}
+
+			reloaded, err := nquads.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatalf("expected successful parse, got: %s", err)
+			}
+
+			if diff := cmp.Diff(dataset, reloaded); diff != "" {
+				t.Errorf("round-trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+package nquads
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// maxLineSize is the longest line the [Reader] accepts.
+const maxLineSize = 16 << 20
+
+// Reader reads quads from an N-Quads document.
+type Reader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewReader returns a new Reader that reads from r.
+func NewReader(r io.Reader) *Reader {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, maxLineSize)
+	s.Split(scanLines)
+
+	return &Reader{s: s}
+}
+
+// Read returns the next quad. Empty lines and comments are skipped.
+//
+// At the end of the input it returns [io.EOF]. Syntax errors are returned as
+// a [ParseError].
+func (r *Reader) Read() (ld.Quad, error) {
+	for r.s.Scan() {
+		r.line++
+
+		p := parser{src: r.s.Text(), line: r.line}
+		quad, ok, err := p.statement()
+		if err != nil {
+			return ld.Quad{}, err
+		}
+
+		if ok {
+			return quad, nil
+		}
+	}
+
+	if err := r.s.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return ld.Quad{}, &ParseError{Line: r.line + 1, Column: 1, Err: ErrLineTooLong}
+		}
+		return ld.Quad{}, err
+	}
+
+	return ld.Quad{}, io.EOF
+}
+
+// ReadAll reads all remaining quads.
+func (r *Reader) ReadAll() (ld.Dataset, error) {
+	dataset := ld.Dataset{}
+
+	for {
+		quad, err := r.Read()
+		if err == io.EOF {
+			return dataset, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		dataset = append(dataset, quad)
+	}
+}
+
+// scanLines splits on any of \n, \r and \r\n.
+func scanLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+
+		if !atEOF {
+			return 0, nil, nil
+		}
+
+		return i + 1, data[:i], nil
+	}
+
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+
+	return 0, nil, nil
+}
+
+// parser parses a single line of N-Quads.
+type parser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *parser) errorAt(pos int, err error) error {
+	return &ParseError{Line: p.line, Column: pos + 1, Err: err}
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *parser) skipWhitespace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// expect consumes c or returns an error.
+func (p *parser) expect(c byte) error {
+	if p.eof() {
+		return p.errorAt(p.pos, ErrUnexpectedEnd)
+	}
+
+	if p.src[p.pos] != c {
+		return p.errorAt(p.pos, ErrUnexpectedCharacter)
+	}
+
+	p.pos++
+	return nil
+}
+
+// statement parses a statement. It returns false if the line is empty or only
+// holds a comment.
+func (p *parser) statement() (ld.Quad, bool, error) {
+	p.skipWhitespace()
+	if p.eof() || p.peek() == '#' {
+		return ld.Quad{}, false, nil
+	}
+
+	var quad ld.Quad
+	var err error
+
+	// subject
+	quad.Subject, err = p.term(false)
+	if err != nil {
+		return ld.Quad{}, false, err
+	}
+
+	// predicate
+	p.skipWhitespace()
+	if p.peek() != '<' && !p.eof() {
+		return ld.Quad{}, false, p.errorAt(p.pos, ErrUnexpectedCharacter)
+	}
+
+	quad.Predicate, err = p.term(false)
+	if err != nil {
+		return ld.Quad{}, false, err
+	}
+
+	// object
+	p.skipWhitespace()
+	quad.Object, err = p.term(true)
+	if err != nil {
+		return ld.Quad{}, false, err
+	}
+
+	// graph label
+	p.skipWhitespace()
+	if c := p.peek(); c == '<' || c == '_' {
+		quad.Graph, err = p.term(false)
+		if err != nil {
+			return ld.Quad{}, false, err
+		}
+		p.skipWhitespace()
+	}
+
+	if err := p.expect('.'); err != nil {
+		return ld.Quad{}, false, err
+	}
+
+	p.skipWhitespace()
+	if !p.eof() && p.peek() != '#' {
+		return ld.Quad{}, false, p.errorAt(p.pos, ErrUnexpectedCharacter)
+	}
+
+	return quad, true, nil
+}
+
+// term parses an IRI, a blank node and if allowed, a literal.
+func (p *parser) term(literal bool) (ld.RDFTerm, error) {
+	switch p.peek() {
+	case '<':
+		iri, err := p.iri()
+		if err != nil {
+			return ld.RDFTerm{}, err
+		}
+		return ld.NewIRI(iri), nil
+	case '_':
+		return p.blankNode()
+	case '"':
+		if literal {
+			return p.literal()
+		}
+	case 0:
+		if p.eof() {
+			return ld.RDFTerm{}, p.errorAt(p.pos, ErrUnexpectedEnd)
+		}
+	}
+
+	return ld.RDFTerm{}, p.errorAt(p.pos, ErrUnexpectedCharacter)
+}
+
+// iri parses an IRIREF. The IRI must be absolute.
+func (p *parser) iri() (string, error) {
+	start := p.pos
+	if err := p.expect('<'); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorAt(p.pos, ErrUnexpectedEnd)
+		}
+
+		c := p.src[p.pos]
+		switch {
+		case c == '>':
+			p.pos++
+
+			iri := b.String()
+			if !hasScheme(iri) {
+				return "", p.errorAt(start, ErrInvalidIRI)
+			}
+
+			return iri, nil
+		case c == '\\':
+			r, err := p.uchar()
+			if err != nil {
+				return "", err
+			}
+			b.WriteRune(r)
+		case c <= 0x20 || strings.IndexByte("<\"{}|^`", c) >= 0:
+			return "", p.errorAt(p.pos, ErrInvalidIRI)
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// hasScheme returns if the IRI starts with a scheme, which makes it absolute.
+func hasScheme(iri string) bool {
+	scheme, _, ok := strings.Cut(iri, ":")
+	if !ok || scheme == "" {
+		return false
+	}
+
+	for i, c := range []byte(scheme) {
+		isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isOther := (c >= '0' && c <= '9') || c == '+' || c == '-' || c == '.'
+		if !isAlpha && (i == 0 || !isOther) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// uchar parses a \u or \U escape sequence.
+func (p *parser) uchar() (rune, error) {
+	start := p.pos
+	p.pos++
+
+	var size int
+	switch p.peek() {
+	case 'u':
+		size = 4
+	case 'U':
+		size = 8
+	default:
+		return 0, p.errorAt(start, ErrInvalidEscape)
+	}
+	p.pos++
+
+	if p.pos+size > len(p.src) {
+		return 0, p.errorAt(start, ErrInvalidEscape)
+	}
+
+	var r rune
+	for _, c := range []byte(p.src[p.pos : p.pos+size]) {
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			return 0, p.errorAt(start, ErrInvalidEscape)
+		}
+		r = r<<4 | rune(v)
+	}
+
+	if !utf8.ValidRune(r) {
+		return 0, p.errorAt(start, ErrInvalidEscape)
+	}
+
+	p.pos += size
+	return r, nil
+}
+
+// blankNode parses a BLANK_NODE_LABEL.
+func (p *parser) blankNode() (ld.RDFTerm, error) {
+	start := p.pos
+	if !strings.HasPrefix(p.src[p.pos:], "_:") {
+		return ld.RDFTerm{}, p.errorAt(start, ErrInvalidBlankNode)
+	}
+	p.pos += 2
+
+	r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+	if size <= 1 && r == utf8.RuneError || !isPNCharsU(r) && (r < '0' || r > '9') {
+		return ld.RDFTerm{}, p.errorAt(start, ErrInvalidBlankNode)
+	}
+	p.pos += size
+
+	for !p.eof() {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if size == 1 && r == utf8.RuneError || r != '.' && !isPNChars(r) {
+			break
+		}
+		p.pos += size
+	}
+
+	// a label can't end with a dot, it's the end of the statement instead
+	for p.src[p.pos-1] == '.' {
+		p.pos--
+	}
+
+	return ld.NewBlankNode(p.src[start:p.pos]), nil
+}
+
+func isPNCharsBase(r rune) bool {
+	return (r >= 'A' && r <= 'Z') ||
+		(r >= 'a' && r <= 'z') ||
+		(r >= 0x00C0 && r <= 0x00D6) ||
+		(r >= 0x00D8 && r <= 0x00F6) ||
+		(r >= 0x00F8 && r <= 0x02FF) ||
+		(r >= 0x0370 && r <= 0x037D) ||
+		(r >= 0x037F && r <= 0x1FFF) ||
+		(r >= 0x200C && r <= 0x200D) ||
+		(r >= 0x2070 && r <= 0x218F) ||
+		(r >= 0x2C00 && r <= 0x2FEF) ||
+		(r >= 0x3001 && r <= 0xD7FF) ||
+		(r >= 0xF900 && r <= 0xFDCF) ||
+		(r >= 0xFDF0 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0xEFFFF)
+}
+
+func isPNCharsU(r rune) bool {
+	return isPNCharsBase(r) || r == '_' || r == ':'
+}
+
+func isPNChars(r rune) bool {
+	return isPNCharsU(r) ||
+		r == '-' ||
+		(r >= '0' && r <= '9') ||
+		r == 0x00B7 ||
+		(r >= 0x0300 && r <= 0x036F) ||
+		(r >= 0x203F && r <= 0x2040)
+}
+
+// literal parses a literal with an optional datatype or language tag.
+func (p *parser) literal() (ld.RDFTerm, error) {
+	if err := p.expect('"'); err != nil {
+		return ld.RDFTerm{}, err
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return ld.RDFTerm{}, p.errorAt(p.pos, ErrUnexpectedEnd)
+		}
+
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			break
+		}
+
+		if c != '\\' {
+			b.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		if p.pos+1 >= len(p.src) {
+			return ld.RDFTerm{}, p.errorAt(p.pos, ErrInvalidEscape)
+		}
+
+		switch p.src[p.pos+1] {
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case '"':
+			b.WriteByte('"')
+		case '\'':
+			b.WriteByte('\'')
+		case '\\':
+			b.WriteByte('\\')
+		case 'u', 'U':
+			r, err := p.uchar()
+			if err != nil {
+				return ld.RDFTerm{}, err
+			}
+			b.WriteRune(r)
+			continue
+		default:
+			return ld.RDFTerm{}, p.errorAt(p.pos, ErrInvalidEscape)
+		}
+		p.pos += 2
+	}
+
+	value := b.String()
+
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "^^"):
+		p.pos += 2
+		if p.peek() != '<' {
+			if p.eof() {
+				return ld.RDFTerm{}, p.errorAt(p.pos, ErrUnexpectedEnd)
+			}
+			return ld.RDFTerm{}, p.errorAt(p.pos, ErrUnexpectedCharacter)
+		}
+
+		datatype, err := p.iri()
+		if err != nil {
+			return ld.RDFTerm{}, err
+		}
+
+		return ld.NewLiteral(value, datatype, ""), nil
+	case p.peek() == '@':
+		lang, err := p.language()
+		if err != nil {
+			return ld.RDFTerm{}, err
+		}
+
+		return ld.NewLiteral(value, "", lang), nil
+	default:
+		return ld.NewLiteral(value, "", ""), nil
+	}
+}
+
+// language parses a LANGTAG: @[a-zA-Z]+ ('-' [a-zA-Z0-9]+)*.
+func (p *parser) language() (string, error) {
+	start := p.pos
+	p.pos++
+
+	first := true
+	subtag := 0
+	for !p.eof() {
+		c := p.src[p.pos]
+		isAlpha := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+
+		if isAlpha || (isDigit && !first) {
+			subtag++
+		} else if c == '-' && subtag > 0 {
+			first = false
+			subtag = 0
+		} else {
+			break
+		}
+		p.pos++
+	}
+
+	if subtag == 0 {
+		return "", p.errorAt(start, ErrInvalidLanguage)
+	}
+
+	return p.src[start+1 : p.pos], nil
+}
@@ -0,0 +1,46 @@
+package nquads
+
+import (
+	"bufio"
+	"io"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+// Writer writes quads in canonical N-Quads.
+//
+// Output is buffered. Call [Writer.Flush] once done to ensure everything has
+// been written to the underlying [io.Writer].
+type Writer struct {
+	w *bufio.Writer
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Write writes a single quad, followed by a newline.
+func (w *Writer) Write(quad ld.Quad) error {
+	if _, err := w.w.WriteString(quad.String()); err != nil {
+		return err
+	}
+
+	return w.w.WriteByte('\n')
+}
+
+// WriteAll writes all quads of the dataset and flushes the output.
+func (w *Writer) WriteAll(dataset ld.Dataset) error {
+	for _, quad := range dataset {
+		if err := w.Write(quad); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+// Flush writes any buffered data to the underlying [io.Writer].
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
@@ -0,0 +1,70 @@
+package longdistance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// digestPrefix identifies the hash algorithm of a digest returned by
+// [ContextDigest].
+const digestPrefix = "sha256-"
+
+// ContextDigest returns the digest of a context, as used by [PinContexts].
+//
+// The context is normalised with the JSON Canonicalization Scheme (RFC 8785),
+// so formatting and the order of keys don't affect the digest. It's hashed
+// with SHA-256 and formatted like Subresource Integrity metadata: "sha256-"
+// followed by the base64-encoded hash.
+func ContextDigest(lctx json.RawMessage) (string, error) {
+	canonical, err := json.Canonicalize(lctx)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(canonical)
+	return digestPrefix + base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
+// PinContexts verifies that remote contexts haven't changed.
+//
+// The keys of pins are the IRIs of remote contexts, and the values their
+// digest as returned by [ContextDigest]. When the [Document.Context] returned
+// for a pinned IRI doesn't match its digest, an error wrapping
+// [ErrContextIntegrity] and [ErrLoadingRemoteContext] is returned. Contexts
+// that aren't pinned are returned as-is.
+//
+// A [Document] with [CacheInfo.NotModified] set has no context to verify, and
+// is returned as-is too. The context it refers to was verified when it was
+// retrieved, so a cache like [DiskCache] can wrap the pinned loader.
+func PinContexts(pins map[string]string) LoaderMiddleware {
+	return func(next RemoteContextLoaderFunc) RemoteContextLoaderFunc {
+		return func(ctx context.Context, iri string) (Document, error) {
+			doc, err := next(ctx, iri)
+			if err != nil {
+				return Document{}, err
+			}
+
+			want, ok := pins[iri]
+			if !ok || doc.Cache.NotModified {
+				return doc, nil
+			}
+
+			got, err := ContextDigest(doc.Context)
+			if err != nil {
+				return Document{}, fmt.Errorf("%w: %w: %s: %w",
+					ErrLoadingRemoteContext, ErrContextIntegrity, iri, err)
+			}
+
+			if got != want {
+				return Document{}, fmt.Errorf("%w: %w: %s: expected %s, got %s",
+					ErrLoadingRemoteContext, ErrContextIntegrity, iri, want, got)
+			}
+
+			return doc, nil
+		}
+	}
+}
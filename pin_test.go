@@ -0,0 +1,143 @@
+package longdistance_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestContextDigest(t *testing.T) {
+	a, err := ld.ContextDigest(json.RawMessage(`{"name": "https://example.org/name", "id": "@id"}`))
+	if err != nil {
+		t.Fatalf("expected a digest, got: %s", err)
+	}
+
+	b, err := ld.ContextDigest(json.RawMessage("{\n  \"id\":\"@id\",\n  \"name\":\"https://example.org/name\"\n}"))
+	if err != nil {
+		t.Fatalf("expected a digest, got: %s", err)
+	}
+
+	if a != b {
+		t.Errorf("expected equal digests, got: %s and %s", a, b)
+	}
+
+	if !strings.HasPrefix(a, "sha256-") {
+		t.Errorf("expected a sha256 digest, got: %s", a)
+	}
+
+	c, err := ld.ContextDigest(json.RawMessage(`{"name": "https://example.org/other"}`))
+	if err != nil {
+		t.Fatalf("expected a digest, got: %s", err)
+	}
+
+	if a == c {
+		t.Errorf("expected different digests for different contexts")
+	}
+
+	if _, err := ld.ContextDigest(json.RawMessage(`{`)); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestPinContexts(t *testing.T) {
+	served := map[string]json.RawMessage{
+		"https://example.org/pinned":   json.RawMessage(`{"name": "https://example.org/name"}`),
+		"https://example.org/changed":  json.RawMessage(`{"name": "https://evil.example/name"}`),
+		"https://example.org/unpinned": json.RawMessage(`{"name": "https://example.org/name"}`),
+	}
+
+	loader := func(_ context.Context, url string) (ld.Document, error) {
+		return ld.Document{URL: url, Context: served[url]}, nil
+	}
+
+	digest, err := ld.ContextDigest(json.RawMessage(`{"name":"https://example.org/name"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proc := ld.NewProcessor(ld.WithRemoteContextLoader(ld.WrapLoader(loader, ld.PinContexts(map[string]string{
+		"https://example.org/pinned":  digest,
+		"https://example.org/changed": digest,
+	}))))
+
+	tests := []struct {
+		name string
+		iri  string
+		err  bool
+	}{
+		{name: "matching", iri: "https://example.org/pinned"},
+		{name: "mismatching", iri: "https://example.org/changed", err: true},
+		{name: "unpinned", iri: "https://example.org/unpinned"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			in := `{"@context": "` + tc.iri + `", "name": "Alice"}`
+			_, err := proc.Expand(t.Context(), strings.NewReader(in), "")
+
+			if !tc.err {
+				if err != nil {
+					t.Fatalf("expected successful expansion, got: %s", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ld.ErrContextIntegrity) || !errors.Is(err, ld.ErrLoadingRemoteContext) {
+				t.Fatalf("expected an integrity error, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestPinContextsDiskCache(t *testing.T) {
+	srv := &contextServer{cacheControl: "no-cache"}
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+
+	digest, err := ld.ContextDigest(contextFor(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := ld.NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader := ld.WrapLoader(ld.NewHTTPContextLoader(ts.Client()),
+		cache.Wrap,
+		ld.PinContexts(map[string]string{ts.URL: digest}),
+	)
+
+	// the second load is revalidated with a conditional request, which the
+	// server answers with 304 Not Modified
+	for i := range 2 {
+		doc, err := loader(t.Context(), ts.URL)
+		if err != nil {
+			t.Fatalf("load %d: expected successful load, got: %s", i, err)
+		}
+
+		if diff := cmp.Diff(contextFor(0), doc.Context, JSONDiff()); diff != "" {
+			t.Errorf("load %d: context mismatch (-want +got):\n%s", i, diff)
+		}
+	}
+
+	if _, conditional := srv.counts(); conditional != 1 {
+		t.Errorf("expected 1 conditional request, got: %d", conditional)
+	}
+
+	// a changed context is still caught on revalidation
+	srv.set(1, "no-cache")
+
+	if _, err := loader(t.Context(), ts.URL); !errors.Is(err, ld.ErrContextIntegrity) {
+		t.Fatalf("expected error: %v, got: %v", ld.ErrContextIntegrity, err)
+	}
+}
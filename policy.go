@@ -0,0 +1,372 @@
+package longdistance
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// LoaderMiddleware wraps a [RemoteContextLoaderFunc] to change or restrict
+// its behaviour.
+type LoaderMiddleware func(RemoteContextLoaderFunc) RemoteContextLoaderFunc
+
+// WrapLoader wraps loader with the middleware. The first middleware is the
+// outermost, so it sees requests first.
+func WrapLoader(loader RemoteContextLoaderFunc, mw ...LoaderMiddleware) RemoteContextLoaderFunc {
+	for _, m := range slices.Backward(mw) {
+		loader = m(loader)
+	}
+	return loader
+}
+
+// Policy identifies the loader policy that rejected a request.
+type Policy string
+
+const (
+	PolicyScheme  Policy = "scheme"
+	PolicyHost    Policy = "host"
+	PolicyAddress Policy = "address"
+	PolicySize    Policy = "size"
+	PolicyLimit   Policy = "limit"
+)
+
+// PolicyError is returned when retrieving a remote context is refused by a
+// loader policy. It wraps [ErrLoadingRemoteContext].
+type PolicyError struct {
+	Policy Policy
+	URL    string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("%s: %s rejected by %s policy: %s",
+		ErrLoadingRemoteContext, e.URL, e.Policy, e.Reason)
+}
+
+func (e *PolicyError) Unwrap() error {
+	return ErrLoadingRemoteContext
+}
+
+// AllowSchemes only allows contexts to be retrieved from URLs with one of
+// the schemes.
+func AllowSchemes(schemes ...string) LoaderMiddleware {
+	return policy(func(_ context.Context, iri string) error {
+		u, err := url.Parse(iri)
+		if err != nil {
+			return &PolicyError{Policy: PolicyScheme, URL: iri, Reason: "invalid URL"}
+		}
+
+		if !slices.ContainsFunc(schemes, func(s string) bool {
+			return strings.EqualFold(s, u.Scheme)
+		}) {
+			return &PolicyError{
+				Policy: PolicyScheme,
+				URL:    iri,
+				Reason: fmt.Sprintf("scheme %q not allowed", u.Scheme),
+			}
+		}
+
+		return nil
+	})
+}
+
+// AllowHosts only allows contexts to be retrieved from the hosts.
+//
+// A host matches exactly, ignoring case. A host starting with "*." matches
+// any subdomain of the remainder, but not the remainder itself.
+//
+// Only the URL of the context is checked. Use [CheckRedirect] on the HTTP
+// client to check the URLs it's redirected to as well.
+func AllowHosts(hosts ...string) LoaderMiddleware {
+	return hostPolicy(hosts, true)
+}
+
+// DenyHosts refuses to retrieve contexts from the hosts. Hosts are matched
+// like [AllowHosts] does.
+func DenyHosts(hosts ...string) LoaderMiddleware {
+	return hostPolicy(hosts, false)
+}
+
+func hostPolicy(hosts []string, allow bool) LoaderMiddleware {
+	return policy(func(_ context.Context, iri string) error {
+		u, err := url.Parse(iri)
+		if err != nil {
+			return &PolicyError{Policy: PolicyHost, URL: iri, Reason: "invalid URL"}
+		}
+
+		host := u.Hostname()
+		matched := slices.ContainsFunc(hosts, func(pattern string) bool {
+			return matchHost(pattern, host)
+		})
+
+		if matched != allow {
+			reason := fmt.Sprintf("host %q not allowed", host)
+			if !allow {
+				reason = fmt.Sprintf("host %q denied", host)
+			}
+			return &PolicyError{Policy: PolicyHost, URL: iri, Reason: reason}
+		}
+
+		return nil
+	})
+}
+
+func matchHost(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return len(host) > len(suffix)+1 &&
+			strings.EqualFold(host[len(host)-len(suffix)-1:], "."+suffix)
+	}
+	return strings.EqualFold(pattern, host)
+}
+
+// DenyPrivateAddresses refuses to retrieve contexts from hosts that resolve
+// to a loopback, private, link-local or otherwise non-public address. When
+// resolver is nil, [net.DefaultResolver] is used.
+//
+// The host is resolved before the request is made, so a malicious DNS server
+// can still return a different address when the loader connects. Use
+// [DenyPrivateAddressesControl] on the dialer of the HTTP client to check
+// the address that is connected to as well. Redirects are only checked when
+// the HTTP client uses [CheckRedirect].
+func DenyPrivateAddresses(resolver *net.Resolver) LoaderMiddleware {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	return policy(func(ctx context.Context, iri string) error {
+		u, err := url.Parse(iri)
+		if err != nil {
+			return &PolicyError{Policy: PolicyAddress, URL: iri, Reason: "invalid URL"}
+		}
+
+		host := u.Hostname()
+
+		var addrs []netip.Addr
+		if addr, err := netip.ParseAddr(host); err == nil {
+			addrs = []netip.Addr{addr}
+		} else {
+			addrs, err = resolver.LookupNetIP(ctx, "ip", host)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrLoadingRemoteContext, err)
+			}
+		}
+
+		for _, addr := range addrs {
+			if !isPublicAddr(addr) {
+				return &PolicyError{
+					Policy: PolicyAddress,
+					URL:    iri,
+					Reason: fmt.Sprintf("address %s is not public", addr),
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// policy returns a middleware that checks the URL of a context with check
+// before retrieving it.
+//
+// The check is also recorded in the context passed to the loader, so that
+// [CheckRedirect] can apply it to every redirect the HTTP client follows.
+func policy(check policyCheck) LoaderMiddleware {
+	return func(next RemoteContextLoaderFunc) RemoteContextLoaderFunc {
+		return func(ctx context.Context, iri string) (Document, error) {
+			if err := check(ctx, iri); err != nil {
+				return Document{}, err
+			}
+
+			return next(withPolicyCheck(ctx, check), iri)
+		}
+	}
+}
+
+// policyCheck checks if a context may be retrieved from the URL.
+type policyCheck func(ctx context.Context, iri string) error
+
+type policyChecksKey struct{}
+
+// withPolicyCheck records the check in ctx, next to any checks that are
+// already recorded.
+func withPolicyCheck(ctx context.Context, check policyCheck) context.Context {
+	checks, _ := ctx.Value(policyChecksKey{}).([]policyCheck)
+	return context.WithValue(ctx, policyChecksKey{}, append(slices.Clip(checks), check))
+}
+
+// maxRedirects is the number of redirects [CheckRedirect] follows, the same
+// as the default of [http.Client].
+const maxRedirects = 10
+
+// CheckRedirect can be used as the CheckRedirect function of an
+// [http.Client] to apply the policies of [AllowSchemes], [AllowHosts],
+// [DenyHosts] and [DenyPrivateAddresses] to every redirect. Without it, a
+// context on an allowed host can redirect to a host the policies refuse.
+//
+// [NewHTTPContextLoader] uses it for any client that doesn't have a
+// CheckRedirect function of its own. Like the default of [http.Client], it
+// stops after 10 redirects.
+func CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	checks, _ := req.Context().Value(policyChecksKey{}).([]policyCheck)
+	for _, check := range checks {
+		if err := check(req.Context(), req.URL.String()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DenyPrivateAddressesControl can be used as the Control function of a
+// [net.Dialer] to refuse connections to addresses [DenyPrivateAddresses]
+// doesn't allow.
+func DenyPrivateAddressesControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return &PolicyError{Policy: PolicyAddress, URL: address, Reason: "invalid address"}
+	}
+
+	if !isPublicAddr(addrPort.Addr()) {
+		return &PolicyError{
+			Policy: PolicyAddress,
+			URL:    address,
+			Reason: fmt.Sprintf("address %s is not public", addrPort.Addr()),
+		}
+	}
+
+	return nil
+}
+
+// nonPublicPrefixes are special-purpose ranges not covered by the methods of
+// [netip.Addr].
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() {
+		return false
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LimitSize refuses contexts larger than n bytes.
+//
+// The context is only checked once it has been retrieved. Use
+// [WithLoaderMaxBodySize] to also limit how much of a response
+// [NewHTTPContextLoader] reads.
+func LimitSize(n int64) LoaderMiddleware {
+	return func(next RemoteContextLoaderFunc) RemoteContextLoaderFunc {
+		return func(ctx context.Context, iri string) (Document, error) {
+			doc, err := next(ctx, iri)
+			if err != nil {
+				return Document{}, err
+			}
+
+			if int64(len(doc.Context)) > n {
+				return Document{}, &PolicyError{
+					Policy: PolicySize,
+					URL:    iri,
+					Reason: fmt.Sprintf("context exceeds %d bytes", n),
+				}
+			}
+
+			return doc, nil
+		}
+	}
+}
+
+// LimitRemoteContexts limits the number of distinct remote contexts that can
+// be retrieved during a single call to a method of [Processor], like
+// [Processor.Expand].
+//
+// Calls to the loader made outside of a [Processor] are not limited. Remote
+// contexts the processor has cached, see [WithContextCacheSize], are not
+// retrieved and don't count towards the limit.
+func LimitRemoteContexts(n int) LoaderMiddleware {
+	return func(next RemoteContextLoaderFunc) RemoteContextLoaderFunc {
+		return func(ctx context.Context, iri string) (Document, error) {
+			if set, ok := ctx.Value(remoteContextsKey{}).(*remoteContexts); ok {
+				if !set.add(iri, n) {
+					return Document{}, &PolicyError{
+						Policy: PolicyLimit,
+						URL:    iri,
+						Reason: fmt.Sprintf("more than %d remote contexts", n),
+					}
+				}
+			}
+
+			return next(ctx, iri)
+		}
+	}
+}
+
+type remoteContextsKey struct{}
+
+// remoteContexts tracks the remote contexts retrieved during a call.
+type remoteContexts struct {
+	mu   sync.Mutex
+	urls map[string]struct{}
+}
+
+// add records the URL, unless this would result in more than limit URLs
+// being recorded.
+func (r *remoteContexts) add(url string, limit int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.urls[url]; ok {
+		return true
+	}
+
+	if len(r.urls) >= limit {
+		return false
+	}
+
+	r.urls[url] = struct{}{}
+	return true
+}
+
+// withRemoteContexts starts tracking the remote contexts retrieved with ctx,
+// unless this is already the case.
+func withRemoteContexts(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(remoteContextsKey{}).(*remoteContexts); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, remoteContextsKey{}, &remoteContexts{
+		urls: make(map[string]struct{}),
+	})
+}
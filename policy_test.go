@@ -0,0 +1,221 @@
+package longdistance_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestLoaderPolicies(t *testing.T) {
+	loader := func(_ context.Context, url string) (ld.Document, error) {
+		return ld.Document{URL: url, Context: json.RawMessage(`{"name": "https://example.org/name"}`)}, nil
+	}
+
+	tests := []struct {
+		name   string
+		mw     []ld.LoaderMiddleware
+		url    string
+		policy ld.Policy
+	}{
+		{name: "scheme allowed", mw: []ld.LoaderMiddleware{ld.AllowSchemes("https")}, url: "HTTPS://example.org/"},
+		{name: "scheme not allowed", mw: []ld.LoaderMiddleware{ld.AllowSchemes("https")}, url: "http://example.org/", policy: ld.PolicyScheme},
+		{name: "host allowed", mw: []ld.LoaderMiddleware{ld.AllowHosts("example.org")}, url: "https://EXAMPLE.org/"},
+		{name: "host not allowed", mw: []ld.LoaderMiddleware{ld.AllowHosts("example.org")}, url: "https://example.com/", policy: ld.PolicyHost},
+		{name: "subdomain allowed", mw: []ld.LoaderMiddleware{ld.AllowHosts("*.example.org")}, url: "https://a.b.example.org/"},
+		{name: "wildcard excludes domain", mw: []ld.LoaderMiddleware{ld.AllowHosts("*.example.org")}, url: "https://example.org/", policy: ld.PolicyHost},
+		{name: "wildcard suffix only", mw: []ld.LoaderMiddleware{ld.AllowHosts("*.example.org")}, url: "https://badexample.org/", policy: ld.PolicyHost},
+		{name: "host denied", mw: []ld.LoaderMiddleware{ld.DenyHosts("*.example.com")}, url: "https://evil.example.com/", policy: ld.PolicyHost},
+		{name: "host not denied", mw: []ld.LoaderMiddleware{ld.DenyHosts("*.example.com")}, url: "https://example.org/"},
+		{name: "public address", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "https://93.184.215.14/"},
+		{name: "loopback", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://127.0.0.1:8080/", policy: ld.PolicyAddress},
+		{name: "loopback IPv6", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://[::1]/", policy: ld.PolicyAddress},
+		{name: "mapped loopback", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://[::ffff:127.0.0.1]/", policy: ld.PolicyAddress},
+		{name: "private", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://10.1.2.3/", policy: ld.PolicyAddress},
+		{name: "link-local", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://169.254.169.254/latest/meta-data", policy: ld.PolicyAddress},
+		{name: "shared address space", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://100.64.0.1/", policy: ld.PolicyAddress},
+		{name: "resolved loopback", mw: []ld.LoaderMiddleware{ld.DenyPrivateAddresses(nil)}, url: "http://localhost/", policy: ld.PolicyAddress},
+		{name: "size within limit", mw: []ld.LoaderMiddleware{ld.LimitSize(64)}, url: "https://example.org/"},
+		{name: "size over limit", mw: []ld.LoaderMiddleware{ld.LimitSize(8)}, url: "https://example.org/", policy: ld.PolicySize},
+		{
+			name:   "first rejecting policy wins",
+			mw:     []ld.LoaderMiddleware{ld.AllowSchemes("https"), ld.AllowHosts("example.org")},
+			url:    "http://example.com/",
+			policy: ld.PolicyScheme,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ld.WrapLoader(loader, tc.mw...)(t.Context(), tc.url)
+			if tc.policy == "" {
+				if err != nil {
+					t.Fatalf("expected successful load, got: %s", err)
+				}
+				return
+			}
+
+			var perr *ld.PolicyError
+			if !errors.As(err, &perr) {
+				t.Fatalf("expected a policy error, got: %v", err)
+			}
+
+			if !errors.Is(err, ld.ErrLoadingRemoteContext) {
+				t.Errorf("expected error to wrap: %v", ld.ErrLoadingRemoteContext)
+			}
+
+			if perr.Policy != tc.policy || perr.URL != tc.url {
+				t.Errorf("expected %s policy for %s, got: %s for %s", tc.policy, tc.url, perr.Policy, perr.URL)
+			}
+		})
+	}
+}
+
+func TestLimitRemoteContexts(t *testing.T) {
+	var calls int
+	loader := ld.WrapLoader(func(_ context.Context, url string) (ld.Document, error) {
+		calls++
+		return ld.Document{URL: url, Context: json.RawMessage(`{}`)}, nil
+	}, ld.LimitRemoteContexts(2))
+
+	tests := []struct {
+		name   string
+		in     string
+		policy bool
+	}{
+		{
+			name: "within limit",
+			in:   `{"@context": ["https://example.org/a", "https://example.org/b", "https://example.org/a"], "@id": "https://example.org/"}`,
+		},
+		{
+			name:   "over limit",
+			in:     `{"@context": ["https://example.org/a", "https://example.org/b", "https://example.org/c"], "@id": "https://example.org/"}`,
+			policy: true,
+		},
+		{
+			name:   "over limit in nested context",
+			in:     `{"@context": ["https://example.org/a", "https://example.org/b"], "https://example.org/p": {"@context": "https://example.org/c"}}`,
+			policy: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			proc := ld.NewProcessor(ld.WithRemoteContextLoader(loader))
+
+			_, err := proc.Expand(t.Context(), strings.NewReader(tc.in), "")
+
+			var perr *ld.PolicyError
+			if tc.policy {
+				if !errors.As(err, &perr) || perr.Policy != ld.PolicyLimit {
+					t.Fatalf("expected a limit policy error, got: %v", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("expected successful expansion, got: %s", err)
+			}
+		})
+	}
+
+	calls = 0
+	if _, err := loader(t.Context(), "https://example.org/d"); err != nil || calls != 1 {
+		t.Errorf("expected loads outside of a processor to be unlimited, got: %v", err)
+	}
+}
+
+func TestHTTPContextLoaderPolicies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@context": {"name": "https://example.org/name"}}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Run("dial control", func(t *testing.T) {
+		dialer := &net.Dialer{Control: ld.DenyPrivateAddressesControl}
+		client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+
+		_, err := ld.NewHTTPContextLoader(client)(t.Context(), srv.URL)
+
+		var perr *ld.PolicyError
+		if !errors.As(err, &perr) || perr.Policy != ld.PolicyAddress {
+			t.Fatalf("expected an address policy error, got: %v", err)
+		}
+	})
+
+	t.Run("body size", func(t *testing.T) {
+		_, err := ld.NewHTTPContextLoader(srv.Client(), ld.WithLoaderMaxBodySize(16))(t.Context(), srv.URL)
+
+		var perr *ld.PolicyError
+		if !errors.As(err, &perr) || perr.Policy != ld.PolicySize {
+			t.Fatalf("expected a size policy error, got: %v", err)
+		}
+	})
+
+	t.Run("body size within limit", func(t *testing.T) {
+		_, err := ld.NewHTTPContextLoader(srv.Client(), ld.WithLoaderMaxBodySize(1024))(t.Context(), srv.URL)
+		if err != nil {
+			t.Fatalf("expected successful load, got: %s", err)
+		}
+	})
+}
+
+func TestHTTPContextLoaderRedirectPolicies(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/context.jsonld", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ld.ApplicationLDJSON)
+		w.Write([]byte(`{"@context": {"name": "https://example.org/name"}}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	// the server listens on 127.0.0.1, so redirecting to localhost reaches
+	// the same server under another host
+	local := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	mux.Handle("/redirect", http.RedirectHandler(local+"/context.jsonld", http.StatusFound))
+	mux.Handle("/same-host", http.RedirectHandler("/context.jsonld", http.StatusFound))
+
+	t.Run("redirect to a refused host", func(t *testing.T) {
+		loader := ld.WrapLoader(ld.NewHTTPContextLoader(srv.Client()), ld.AllowHosts("127.0.0.1"))
+
+		_, err := loader(t.Context(), srv.URL+"/redirect")
+
+		var perr *ld.PolicyError
+		if !errors.As(err, &perr) || perr.Policy != ld.PolicyHost {
+			t.Fatalf("expected a host policy error, got: %v", err)
+		}
+	})
+
+	t.Run("redirect to a denied host", func(t *testing.T) {
+		loader := ld.WrapLoader(ld.NewHTTPContextLoader(srv.Client()), ld.DenyHosts("localhost"))
+
+		_, err := loader(t.Context(), srv.URL+"/redirect")
+
+		var perr *ld.PolicyError
+		if !errors.As(err, &perr) || perr.Policy != ld.PolicyHost {
+			t.Fatalf("expected a host policy error, got: %v", err)
+		}
+	})
+
+	t.Run("redirect to an allowed host", func(t *testing.T) {
+		loader := ld.WrapLoader(ld.NewHTTPContextLoader(srv.Client()), ld.AllowHosts("127.0.0.1"))
+
+		doc, err := loader(t.Context(), srv.URL+"/same-host")
+		if err != nil {
+			t.Fatalf("expected successful load, got: %s", err)
+		}
+
+		if doc.URL != srv.URL+"/context.jsonld" {
+			t.Errorf("expected URL %s, got: %s", srv.URL+"/context.jsonld", doc.URL)
+		}
+	})
+}
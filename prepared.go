@@ -0,0 +1,147 @@
+package longdistance
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// PreparedContext is a context that has been processed ahead of time, so it
+// can be reused across calls to [Processor.CompactWithContext] and
+// [Processor.ExpandWithContext].
+//
+// Create one with [Processor.PrepareContext]. It's safe for concurrent use.
+type PreparedContext struct {
+	raw json.RawMessage
+	ctx *Context
+}
+
+// PrepareContext processes rawCtx, the value of a @context entry, into a
+// [PreparedContext].
+//
+// In addition to processing the context, the inverse context used for
+// compaction is built in full. This is normally done lazily and repeated for
+// every call to [Processor.Compact]. Use this when you repeatedly compact or
+// expand documents with the same context.
+//
+// The baseURL is used to resolve relative IRIs in the context, like for
+// [Processor.Context].
+func (p *Processor) PrepareContext(
+	ctx context.Context,
+	rawCtx json.RawMessage,
+	baseURL string,
+) (*PreparedContext, error) {
+	ctx = withRemoteContexts(ctx)
+
+	dec := json.NewDecoder(bytes.NewReader(rawCtx))
+	ldCtx, err := p.context(ctx, nil, dec, baseURL, newCtxProcessingOpts())
+	if err != nil {
+		return nil, err
+	}
+
+	if ldCtx != nil {
+		ldCtx.buildInverse()
+	}
+
+	return &PreparedContext{
+		raw: rawCtx,
+		ctx: ldCtx,
+	}, nil
+}
+
+// Context returns the processed context. It returns nil if the context was
+// empty.
+//
+// The context is shared, it must not be modified.
+func (c *PreparedContext) Context() *Context {
+	return c.ctx
+}
+
+// Raw returns the context as it was passed to [Processor.PrepareContext].
+func (c *PreparedContext) Raw() json.RawMessage {
+	return c.raw
+}
+
+// CompactWithContext is like [Processor.Compact], but uses a context prepared
+// with [Processor.PrepareContext] as the compaction context.
+//
+// The context is included in the output as the value of @context.
+func (p *Processor) CompactWithContext(
+	ctx context.Context,
+	dst io.Writer,
+	compactionCtx *PreparedContext,
+	document []Node,
+) error {
+	ctx = withRemoteContexts(ctx)
+	return p.compactTo(ctx, dst, compactionCtx.ctx, compactionCtx.raw, document)
+}
+
+// ExpandWithContext is like [Processor.Expand], but uses a context prepared
+// with [Processor.PrepareContext] as the expand context. It replaces the one
+// set with [WithExpandContext].
+//
+// The base IRI of the prepared context is replaced with the url of the
+// document, unless the context sets an @base.
+func (p *Processor) ExpandWithContext(
+	ctx context.Context,
+	document io.Reader,
+	url string,
+	expandCtx *PreparedContext,
+) ([]Node, error) {
+	return p.expandDocument(ctx, expandCtx, document, url, "")
+}
+
+// preparedExpandContext returns the context set with [WithExpandContext],
+// processing it on first use against baseIRI.
+//
+// Only successfully processed contexts are kept, so a context that failed to
+// load is retried on the next call. A context with a relative @vocab depends
+// on the base IRI, so it's processed again for every document.
+//
+// The context is processed without holding the lock, as that may retrieve
+// remote contexts. Concurrent first calls may each process it, the remote
+// contexts it references are cached by the processor either way.
+func (p *Processor) preparedExpandContext(ctx context.Context, baseIRI string) (*PreparedContext, error) {
+	p.expandCtx.mu.Lock()
+	prepared := p.expandCtx.prepared
+	p.expandCtx.mu.Unlock()
+
+	if prepared != nil {
+		return prepared, nil
+	}
+
+	var obj json.Object
+	if err := json.Unmarshal(p.expandContext, &obj); err != nil {
+		return nil, ErrInvalidLocalContext
+	}
+
+	rawctx := p.expandContext
+	if v, ok := obj[KeywordContext]; ok {
+		rawctx = v
+	}
+
+	pctx, err := p.PrepareContext(ctx, rawctx, baseIRI)
+	if err != nil {
+		return nil, err
+	}
+
+	if pctx.ctx == nil || !pctx.ctx.baseDependent {
+		p.expandCtx.mu.Lock()
+		if p.expandCtx.prepared == nil {
+			p.expandCtx.prepared = pctx
+		}
+		p.expandCtx.mu.Unlock()
+	}
+	return pctx, nil
+}
+
+// expandContextState holds the context set with [WithExpandContext] once it
+// has been processed. It's shared by processors derived with
+// [Processor.With], as long as they process contexts the same way.
+type expandContextState struct {
+	mu       sync.Mutex
+	prepared *PreparedContext
+}
@@ -0,0 +1,308 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestCompactWithContext(t *testing.T) {
+	doc := LoadData(t, "observatory/createnote.json")
+	compCtx := LoadData(t, "observatory/context.jsonld")
+
+	p := ld.NewProcessor(
+		ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+	)
+
+	exp, err := p.Expand(t.Context(), bytes.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want bytes.Buffer
+	if err := p.Compact(t.Context(), &want, compCtx, exp, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	prepared, err := p.PrepareContext(t.Context(), compCtx, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(compCtx, prepared.Raw(), JSONDiff()); diff != "" {
+		t.Errorf("raw context mismatch (-want +got):\n%s", diff)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 10 {
+				var got bytes.Buffer
+				if err := p.CompactWithContext(t.Context(), &got, prepared, exp); err != nil {
+					t.Errorf("expected no error, got: %s", err)
+					return
+				}
+
+				if diff := cmp.Diff(json.RawMessage(want.Bytes()), json.RawMessage(got.Bytes()), JSONDiff()); diff != "" {
+					t.Errorf("compaction mismatch (-want +got):\n%s", diff)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestCompactWithContextPropagate checks that a context that doesn't propagate
+// can be prepared, which requires building the inverse of the context it
+// reverts to.
+func TestCompactWithContextPropagate(t *testing.T) {
+	p := ld.NewProcessor()
+
+	compCtx := json.RawMessage(`{"@propagate": false, "name": "https://example.org/name"}`)
+	nodes := []ld.Node{{
+		ID: "https://example.org/1",
+		Properties: ld.Properties{
+			"https://example.org/name": []ld.Node{{Value: json.RawMessage(`"Alice"`)}},
+		},
+	}}
+
+	var want bytes.Buffer
+	if err := p.Compact(t.Context(), &want, compCtx, nodes, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	var (
+		prepared *ld.PreparedContext
+		err      error
+	)
+	go func() {
+		defer close(done)
+		prepared, err = p.PrepareContext(t.Context(), compCtx, "")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("preparing the context did not finish")
+	}
+
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	var got bytes.Buffer
+	if err := p.CompactWithContext(t.Context(), &got, prepared, nodes); err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(json.RawMessage(want.Bytes()), json.RawMessage(got.Bytes()), JSONDiff()); diff != "" {
+		t.Errorf("compaction mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExpandWithContext(t *testing.T) {
+	p := ld.NewProcessor()
+
+	prepared, err := p.PrepareContext(t.Context(), json.RawMessage(`{
+		"@vocab": "https://example.org/ns#",
+		"knows": {"@type": "@id"}
+	}`), "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		url  string
+		out  string
+	}{
+		{
+			name: "expand context",
+			in:   `{"@id": "alice", "name": "Alice", "knows": "bob"}`,
+			url:  "https://one.example/",
+			out:  `[{"@id": "https://one.example/alice", "https://example.org/ns#name": [{"@value": "Alice"}], "https://example.org/ns#knows": [{"@id": "https://one.example/bob"}]}]`,
+		},
+		{
+			name: "different base",
+			in:   `{"@id": "alice", "knows": "bob"}`,
+			url:  "https://two.example/",
+			out:  `[{"@id": "https://two.example/alice", "https://example.org/ns#knows": [{"@id": "https://two.example/bob"}]}]`,
+		},
+		{
+			name: "with document context",
+			in:   `{"@context": {"name": "https://example.org/other#name"}, "name": "Alice", "age": 42}`,
+			out:  `[{"https://example.org/other#name": [{"@value": "Alice"}], "https://example.org/ns#age": [{"@value": 42}]}]`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			res, err := p.ExpandWithContext(t.Context(), bytes.NewReader([]byte(tc.in)), tc.url, prepared)
+			if err != nil {
+				t.Fatalf("expected no error, got: %s", err)
+			}
+
+			got, err := json.Marshal(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(json.RawMessage(tc.out), json.RawMessage(got), JSONDiff()); diff != "" {
+				t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExpandContextProcessedOnce(t *testing.T) {
+	var calls atomic.Int32
+	loader := func(_ context.Context, url string) (ld.Document, error) {
+		calls.Add(1)
+		return ld.Document{
+			URL:     url,
+			Context: json.RawMessage(`{"name": "https://example.org/ns#name"}`),
+		}, nil
+	}
+
+	p := ld.NewProcessor(
+		ld.WithRemoteContextLoader(loader),
+		ld.WithContextCacheSize(0),
+		ld.WithExpandContext(json.RawMessage(`{"@context": "https://example.org/context"}`)),
+	)
+
+	for range 3 {
+		res, err := p.Expand(t.Context(), bytes.NewReader([]byte(`{"name": "Alice"}`)), "")
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		got, err := json.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := json.RawMessage(`[{"https://example.org/ns#name": [{"@value": "Alice"}]}]`)
+		if diff := cmp.Diff(want, json.RawMessage(got), JSONDiff()); diff != "" {
+			t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+		}
+	}
+
+	if n := calls.Load(); n != 1 {
+		t.Errorf("expected the expand context to be loaded once, got: %d", n)
+	}
+}
+
+func TestExpandContextConcurrent(t *testing.T) {
+	var calls atomic.Int32
+	both := make(chan struct{})
+	loader := func(_ context.Context, url string) (ld.Document, error) {
+		if calls.Add(1) == 2 {
+			close(both)
+		}
+
+		// only returns once both expansions retrieve the context at the
+		// same time, which fails if processing it is serialized
+		select {
+		case <-both:
+		case <-time.After(5 * time.Second):
+			return ld.Document{}, ld.ErrLoadingRemoteContext
+		}
+
+		return ld.Document{
+			URL:     url,
+			Context: json.RawMessage(`{"name": "https://example.org/ns#name"}`),
+		}, nil
+	}
+
+	p := ld.NewProcessor(
+		ld.WithRemoteContextLoader(loader),
+		ld.WithContextCacheSize(0),
+		ld.WithExpandContext(json.RawMessage(`{"@context": "https://example.org/context"}`)),
+	)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = p.Expand(t.Context(), bytes.NewReader([]byte(`{"name": "Alice"}`)), "")
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+	}
+}
+
+// TestBlankPreparedContextConcurrent checks that the blank context a prepared
+// context that doesn't propagate reverts to isn't modified by the documents
+// it's used for. Run with -race.
+func TestBlankPreparedContextConcurrent(t *testing.T) {
+	p := ld.NewProcessor()
+
+	prepared, err := p.PrepareContext(t.Context(), json.RawMessage(`{
+		"@propagate": false,
+		"@vocab": "https://example.org/ns#"
+	}`), "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// the document reverts to the blank context before its own
+			// context is applied
+			in := fmt.Sprintf(`{"@context": {"@vocab": "https://example.org/%d#"}, "name": "x"}`, i)
+			want := fmt.Sprintf(`[{"https://example.org/%d#name": [{"@value": "x"}]}]`, i)
+
+			for range 10 {
+				res, err := p.ExpandWithContext(t.Context(), strings.NewReader(in), "", prepared)
+				if err != nil {
+					t.Errorf("expected no error, got: %s", err)
+					return
+				}
+
+				got, err := json.Marshal(res)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				if diff := cmp.Diff(json.RawMessage(want), json.RawMessage(got), JSONDiff()); diff != "" {
+					t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if prev := prepared.Context().PreviousContext(); prev.VocabularyMapping() != "" {
+		t.Errorf("expected the previous context to be unchanged, got @vocab: %s", prev.VocabularyMapping())
+	}
+}
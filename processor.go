@@ -2,7 +2,9 @@
 
 import (
 	"encoding/json"
+	"iter"
 	"log/slog"
+	"maps"
 	"slices"
 )
 
@@ -12,7 +14,8 @@
 // Processor represents a JSON-LD processor.
 //
 // Your application should only ever need one of them. Do not create a new one
-// for each request you're handling.
+// for each request you're handling. To vary settings per request, like the
+// base IRI, derive a processor with [Processor.With] instead.
 //
 // Create one with [NewProcessor] and pass any [ProcessorOption] to configure
 // the processor.
@@ -22,16 +25,54 @@ type Processor struct {
 	compactArrays             bool
 	compactToRelative         bool
 	loader                    RemoteContextLoaderFunc
+	documentLoader            RemoteDocumentLoaderFunc
 	logger                    *slog.Logger
 	expandContext             json.RawMessage
 	excludeIRIsFromCompaction []string
 	remapPrefixIRIs           map[string]string
 	validateContextFunc       ValidateContextFunc
 	processedContext          map[string]*Context
+	produceGeneralizedRDF     bool
+	useNativeTypes            bool
+	useRDFType                bool
+	ordered                   bool
+	contextCacheSize          int
+	frameFlags                frameFlags
+	frameOmitGraph            *bool
+	contextCache              *contextCache
+
+	expandCtx *expandContextState
 
 	disallowedKeys map[string]struct{}
+
+	// applied records the options that have been applied, so [Processor.With]
+	// knows what a derived processor can share
+	applied appliedOptions
 }
 
+// appliedOptions is a set of options that have been applied to a processor.
+type appliedOptions uint8
+
+const (
+	applied10Processing appliedOptions = 1 << iota
+	appliedRemoteContextLoader
+	appliedRemapPrefixIRIs
+	appliedValidateContext
+	appliedProcessedContext
+	appliedContextCacheSize
+	appliedExpandContext
+	appliedDisallowedKeywords
+
+	// appliedContextOptions are the options that change how contexts are
+	// processed
+	appliedContextOptions = applied10Processing |
+		appliedRemoteContextLoader |
+		appliedRemapPrefixIRIs |
+		appliedValidateContext |
+		appliedProcessedContext |
+		appliedContextCacheSize
+)
+
 // NewProcessor creates a new JSON-LD processor.
 //
 // By default:
@@ -45,17 +86,26 @@ type Processor struct {
 //     [WithCompactToRelative].
 //   - Logger is [slog.DiscardHandler]. Set it with [WithLogger]. The logger is
 //     only used to emit warnings.
+//   - Processed remote contexts are cached. Change the size of the cache with
+//     [WithContextCacheSize].
 func NewProcessor(options ...ProcessorOption) *Processor {
 	p := &Processor{
 		compactArrays:     true,
 		compactToRelative: true,
 		logger:            slog.New(slog.DiscardHandler),
+		contextCacheSize:  DefaultContextCacheSize,
+		expandCtx:         &expandContextState{},
+		frameFlags: frameFlags{
+			embed: KeywordOnce,
+		},
 	}
 
 	for _, opt := range options {
 		opt(p)
 	}
 
+	p.contextCache = newContextCache(p.contextCacheSize)
+
 	if p.expandContext != nil {
 		p.processedContext = nil
 	}
@@ -63,10 +113,73 @@ func NewProcessor(options ...ProcessorOption) *Processor {
 	return p
 }
 
+// With returns a processor derived from p, with the options applied on top
+// of the ones p was created with. The processor p is left unchanged.
+//
+// Deriving a processor is cheap. It shares the loaders, the cache of
+// processed remote contexts and the processed [WithExpandContext] context
+// with p, so this can be done for every call:
+//
+//	nodes, err := p.With(ld.WithBaseIRI(requestURL)).Expand(ctx, body, "")
+//
+// Options that change how contexts are processed, like
+// [WithRemoteContextLoader], [With10Processing], [WithRemapPrefixIRIs],
+// [WithValidateContext], [WithProcessedContext] and [WithContextCacheSize],
+// give the derived processor a cache of its own, even when they're passed nil
+// or the value p already has. [WithExpandContext] results in the new expand
+// context being processed on first use. Contexts whose result depends on the
+// base IRI, like one with a relative @vocab, are never shared, so a derived
+// processor with another base IRI doesn't see them.
+func (p *Processor) With(options ...ProcessorOption) *Processor {
+	d := *p
+
+	// options add to these, so give the derived processor its own copy
+	d.remapPrefixIRIs = maps.Clone(p.remapPrefixIRIs)
+	d.processedContext = maps.Clone(p.processedContext)
+
+	d.applied = 0
+	for _, opt := range options {
+		opt(&d)
+	}
+
+	contextChanged := d.applied&appliedContextOptions != 0
+
+	if contextChanged {
+		d.contextCache = newContextCache(d.contextCacheSize)
+	}
+
+	if contextChanged || d.applied&appliedExpandContext != 0 {
+		d.expandCtx = &expandContextState{}
+	}
+
+	if d.expandContext != nil {
+		d.processedContext = nil
+	}
+
+	return &d
+}
+
+// entries iterates over a map. When ordered is set, the keys are iterated over
+// in code point order.
+func entries[M ~map[string]V, V any](m M, ordered bool) iter.Seq2[string, V] {
+	if !ordered {
+		return maps.All(m)
+	}
+
+	return func(yield func(string, V) bool) {
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			if !yield(k, m[k]) {
+				return
+			}
+		}
+	}
+}
+
 // With10Processing sets the processing mode to json-ld-1.0.
 func With10Processing(b bool) ProcessorOption {
 	return func(p *Processor) {
 		p.modeLD10 = b
+		p.applied |= applied10Processing
 	}
 }
 
@@ -74,6 +187,15 @@ func With10Processing(b bool) ProcessorOption {
 func WithRemoteContextLoader(l RemoteContextLoaderFunc) ProcessorOption {
 	return func(p *Processor) {
 		p.loader = l
+		p.applied |= appliedRemoteContextLoader
+	}
+}
+
+// WithRemoteDocumentLoader sets the loader used by [Processor.ExpandURL] to
+// retrieve documents.
+func WithRemoteDocumentLoader(l RemoteDocumentLoaderFunc) ProcessorOption {
+	return func(p *Processor) {
+		p.documentLoader = l
 	}
 }
 
@@ -113,9 +235,13 @@ func WithCompactToRelative(b bool) ProcessorOption {
 
 // WithExpandContext provides an additional out-of-band context
 // that's used during expansion.
+//
+// The context is processed once, on first use. Use
+// [Processor.ExpandWithContext] to pass a different context per call.
 func WithExpandContext(ctx json.RawMessage) ProcessorOption {
 	return func(p *Processor) {
 		p.expandContext = ctx
+		p.applied |= appliedExpandContext
 	}
 }
 
@@ -137,6 +263,7 @@ func WithRemapPrefixIRIs(old, new string) ProcessorOption {
 			p.remapPrefixIRIs = make(map[string]string, 2)
 		}
 		p.remapPrefixIRIs[old] = new
+		p.applied |= appliedRemapPrefixIRIs
 	}
 }
 
@@ -152,15 +279,21 @@ func WithRemapPrefixIRIs(old, new string) ProcessorOption {
 //   - [KeywordGraph]
 //   - [KeywordNest]
 //   - [KeywordReverse]
+//
+// Using the option more than once adds to the keywords. A processor derived
+// with [Processor.With] replaces the keywords of its parent instead, so pass
+// no keywords to allow all of them again.
 func WithDisallowedKeywords(keyword ...string) ProcessorOption {
 	disableable := []string{
 		KeywordIncluded, KeywordIndex, KeywordGraph, KeywordNest, KeywordReverse,
 	}
 
 	return func(p *Processor) {
-		if p.disallowedKeys == nil {
+		// the keywords of a parent processor are replaced, not added to
+		if p.applied&appliedDisallowedKeywords == 0 {
 			p.disallowedKeys = make(map[string]struct{}, len(keyword))
 		}
+		p.applied |= appliedDisallowedKeywords
 
 		for _, kw := range keyword {
 			if slices.Contains(disableable, kw) {
@@ -182,6 +315,7 @@ func WithDisallowedKeywords(keyword ...string) ProcessorOption {
 func WithValidateContext(f ValidateContextFunc) ProcessorOption {
 	return func(p *Processor) {
 		p.validateContextFunc = f
+		p.applied |= appliedValidateContext
 	}
 }
 
@@ -197,11 +331,129 @@ func WithValidateContext(f ValidateContextFunc) ProcessorOption {
 //
 // This has no benefit if [WithExpandContext] is used, as in that case terms are
 // already defined on the context before any remote contexts are retrieved.
+//
+// To avoid processing the context at startup, restore it from a snapshot with
+// [LoadContextSnapshot]. The cmd/ctxsnapshot tool generates Go source that
+// embeds one.
 func WithProcessedContext(iri string, ctx *Context) ProcessorOption {
 	return func(p *Processor) {
 		if p.processedContext == nil {
 			p.processedContext = make(map[string]*Context, 2)
 		}
 		p.processedContext[iri] = ctx
+		p.applied |= appliedProcessedContext
+	}
+}
+
+// WithProduceGeneralizedRDF sets whether [Processor.ToRDF] can produce
+// generalized RDF, where blank nodes are allowed as the predicate of a triple.
+func WithProduceGeneralizedRDF(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.produceGeneralizedRDF = b
+	}
+}
+
+// WithUseNativeTypes sets whether [Processor.FromRDF] converts xsd:string,
+// xsd:boolean, xsd:integer and xsd:double literals to native JSON values.
+//
+// Numbers are converted to float64 first, so this can result in a loss of
+// precision.
+func WithUseNativeTypes(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.useNativeTypes = b
+	}
+}
+
+// WithUseRDFType sets whether [Processor.FromRDF] keeps rdf:type as a regular
+// property instead of turning it into @type.
+func WithUseRDFType(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.useRDFType = b
+	}
+}
+
+// WithOrdered sets whether keys are processed in code point order during
+// expansion and compaction.
+//
+// Without it the keys of an object are processed in whichever order Go's map
+// iteration yields them. When multiple keys end up contributing values to the
+// same property, the order of those values can differ between runs. Enable
+// this when you need byte-for-byte reproducible output, at the cost of having
+// to sort keys.
+func WithOrdered(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.ordered = b
+	}
+}
+
+// WithFrameEmbed sets the default value of @embed for [Processor.Frame], used
+// for any frame that doesn't set it. It must be one of [KeywordAlways],
+// [KeywordOnce] or [KeywordNever]. Any other value is ignored.
+//
+// Defaults to [KeywordOnce].
+func WithFrameEmbed(embed string) ProcessorOption {
+	return func(p *Processor) {
+		switch embed {
+		case KeywordAlways, KeywordOnce, KeywordNever:
+			p.frameFlags.embed = embed
+		}
+	}
+}
+
+// WithFrameExplicit sets the default value of @explicit for [Processor.Frame].
+// When set, only properties that are in the frame are included in the output.
+func WithFrameExplicit(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.frameFlags.explicit = b
+	}
+}
+
+// WithFrameOmitDefault sets the default value of @omitDefault for
+// [Processor.Frame]. When set, properties of the frame that are missing from a
+// node aren't added to it with their default value.
+func WithFrameOmitDefault(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.frameFlags.omitDefault = b
+	}
+}
+
+// WithFrameRequireAll sets the default value of @requireAll for
+// [Processor.Frame]. When set, a node only matches a frame if it matches all
+// of its properties instead of any of them.
+func WithFrameRequireAll(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.frameFlags.requireAll = b
+	}
+}
+
+// WithFrameOmitGraph sets whether [Processor.Frame] leaves out the top-level
+// @graph when framing results in a single node. When disabled, the result is
+// always wrapped in a @graph.
+//
+// Defaults to true in JSON-LD 1.1 processing mode, and false in JSON-LD 1.0
+// processing mode.
+func WithFrameOmitGraph(b bool) ProcessorOption {
+	return func(p *Processor) {
+		p.frameOmitGraph = &b
+	}
+}
+
+// WithContextCacheSize sets the number of processed remote contexts the
+// processor caches. A size below 1 disables the cache.
+//
+// The result of applying a remote context is cached keyed by its IRI and the
+// active context it was applied to, as long as that active context is the
+// result of applying other remote contexts to an empty context. This covers
+// documents starting with a list of well-known contexts, like
+// ["https://www.w3.org/ns/activitystreams", "https://w3id.org/security/v1"].
+// Contexts are only retrieved from the loader on a cache miss. Results that
+// depend on the base IRI of the document, like a remote context with a
+// relative @vocab, aren't cached.
+//
+// Defaults to [DefaultContextCacheSize].
+func WithContextCacheSize(n int) ProcessorOption {
+	return func(p *Processor) {
+		p.contextCacheSize = n
+		p.applied |= appliedContextCacheSize
 	}
 }
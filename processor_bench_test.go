@@ -7,6 +7,7 @@
 	"testing"
 
 	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/contexts"
 )
 
 func BenchmarkContextProcessing(b *testing.B) {
@@ -17,6 +18,7 @@ func BenchmarkContextProcessing(b *testing.B) {
 
 		p := ld.NewProcessor(
 			ld.WithRemoteContextLoader(StaticLoader(b, "as.jsonld")),
+			ld.WithContextCacheSize(0),
 		)
 
 		for b.Loop() {
@@ -63,6 +65,7 @@ func BenchmarkCompact(b *testing.B) {
 
 		p := ld.NewProcessor(
 			ld.WithRemoteContextLoader(StaticLoader(b, "as.jsonld")),
+			ld.WithContextCacheSize(0),
 		)
 
 		for b.Loop() {
@@ -87,6 +90,26 @@ func BenchmarkCompact(b *testing.B) {
 			}
 		}
 	})
+
+	b.Run("with prepared context", func(b *testing.B) {
+		b.ReportAllocs()
+
+		p := ld.NewProcessor(
+			ld.WithRemoteContextLoader(StaticLoader(b, "as.jsonld")),
+		)
+
+		prepared, err := p.PrepareContext(b.Context(), compCtx, "")
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for b.Loop() {
+			err := p.CompactWithContext(b.Context(), io.Discard, prepared, exp)
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
 }
 
 func BenchmarkExpand(b *testing.B) {
@@ -98,6 +121,7 @@ func BenchmarkExpand(b *testing.B) {
 
 		p := ld.NewProcessor(
 			ld.WithRemoteContextLoader(StaticLoader(b, "as.jsonld")),
+			ld.WithContextCacheSize(0),
 		)
 
 		for b.Loop() {
@@ -124,3 +148,57 @@ func BenchmarkExpand(b *testing.B) {
 		}
 	})
 }
+
+func BenchmarkMultipleContexts(b *testing.B) {
+	doc := json.RawMessage(`{
+		"@context": [
+			"https://www.w3.org/ns/activitystreams",
+			"https://w3id.org/security/v1",
+			{
+				"toot": "http://joinmastodon.org/ns#",
+				"discoverable": "toot:discoverable"
+			}
+		],
+		"id": "https://example.org/users/alice",
+		"type": "Person",
+		"discoverable": true,
+		"publicKey": {
+			"id": "https://example.org/users/alice#main-key",
+			"owner": "https://example.org/users/alice",
+			"publicKeyPem": "-----BEGIN PUBLIC KEY-----"
+		}
+	}`)
+
+	b.Run("without context cache", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(doc)))
+
+		p := ld.NewProcessor(
+			ld.WithRemoteContextLoader(contexts.Loader(nil)),
+			ld.WithContextCacheSize(0),
+		)
+
+		for b.Loop() {
+			_, err := p.Expand(b.Context(), bytes.NewReader(doc), "")
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("with context cache", func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(int64(len(doc)))
+
+		p := ld.NewProcessor(
+			ld.WithRemoteContextLoader(contexts.Loader(nil)),
+		)
+
+		for b.Loop() {
+			_, err := p.Expand(b.Context(), bytes.NewReader(doc), "")
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
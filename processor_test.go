@@ -2,7 +2,13 @@
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -94,3 +100,482 @@ func TestValidateContextFunc(t *testing.T) {
 		t.Fatalf("expected: %s, got: %s", ld.ErrInvalid, err)
 	}
 }
+
+func TestOrdered(t *testing.T) {
+	proc := ld.NewProcessor(ld.WithOrdered(true))
+
+	lctx := json.RawMessage(`{` +
+		`"name":"http://example.org/label",` +
+		`"title":"http://example.org/label",` +
+		`"labels":{"@id":"http://example.org/label","@container":"@language"},` +
+		`"items":{"@id":"http://example.org/item","@container":"@index"}` +
+		`}`)
+
+	input := json.RawMessage(`{
+		"@context": ` + string(lctx) + `,
+		"@id": "http://example.org/x",
+		"title": "t",
+		"name": "n",
+		"labels": {"nl": "nl", "en": "en", "de": "de"},
+		"items": {"z": "z", "a": "a", "m": "m"}
+	}`)
+
+	wantExpanded := `[{"@id":"http://example.org/x",` +
+		`"http://example.org/item":[{"@index":"a","@value":"a"},{"@index":"m","@value":"m"},{"@index":"z","@value":"z"}],` +
+		`"http://example.org/label":[{"@language":"de","@value":"de"},{"@language":"en","@value":"en"},{"@language":"nl","@value":"nl"},{"@value":"n"},{"@value":"t"}]}]`
+
+	wantCompacted := `{"@context":` + string(lctx) + `,` +
+		`"@id":"http://example.org/x",` +
+		`"items":{"a":"a","m":"m","z":"z"},` +
+		`"labels":{"de":"de","en":"en","nl":"nl"},` +
+		`"name":["n","t"]}` + "\n"
+
+	for range 20 {
+		nodes, err := proc.Expand(t.Context(), bytes.NewReader(input), "")
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		expanded, err := json.Marshal(nodes)
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if diff := cmp.Diff(wantExpanded, string(expanded)); diff != "" {
+			t.Fatalf("expansion mismatch (-want +got):\n%s", diff)
+		}
+
+		var dst bytes.Buffer
+		if err := proc.Compact(t.Context(), &dst, lctx, nodes, ""); err != nil {
+			t.Fatal(err.Error())
+		}
+
+		if diff := cmp.Diff(wantCompacted, dst.String()); diff != "" {
+			t.Fatalf("compaction mismatch (-want +got):\n%s", diff)
+		}
+	}
+}
+
+func TestContextCache(t *testing.T) {
+	remotes := map[string]json.RawMessage{
+		"https://example.org/a":        json.RawMessage(`{"@vocab": "https://example.org/a#", "name": "https://example.org/a#name"}`),
+		"https://example.org/b":        json.RawMessage(`{"name": "https://example.org/b#name", "knows": {"@id": "https://example.org/b#knows", "@type": "@id"}}`),
+		"https://example.org/relative": json.RawMessage(`{"@vocab": "#"}`),
+		"https://example.org/scoped":   json.RawMessage(`{"author": {"@id": "https://example.org/s#author", "@context": "https://example.org/b"}}`),
+	}
+
+	newProc := func(size int) (*ld.Processor, *int) {
+		calls := 0
+		loader := func(_ context.Context, url string) (ld.Document, error) {
+			calls++
+			lctx, ok := remotes[url]
+			if !ok {
+				return ld.Document{}, ld.ErrLoadingRemoteContext
+			}
+			return ld.Document{URL: url, Context: lctx}, nil
+		}
+		return ld.NewProcessor(
+			ld.WithRemoteContextLoader(loader),
+			ld.WithContextCacheSize(size),
+		), &calls
+	}
+
+	tests := []struct {
+		name  string
+		in    string
+		base  string
+		out   string
+		calls int
+	}{
+		{
+			name:  "remote contexts",
+			in:    `{"@context": ["https://example.org/a", "https://example.org/b"], "@id": "1", "name": "x", "knows": "2"}`,
+			base:  "https://one.example/",
+			out:   `[{"@id": "https://one.example/1", "https://example.org/b#name": [{"@value": "x"}], "https://example.org/b#knows": [{"@id": "https://one.example/2"}]}]`,
+			calls: 2,
+		},
+		{
+			name:  "remote contexts with different base",
+			in:    `{"@context": ["https://example.org/a", "https://example.org/b"], "@id": "1", "knows": "2"}`,
+			base:  "https://two.example/",
+			out:   `[{"@id": "https://two.example/1", "https://example.org/b#knows": [{"@id": "https://two.example/2"}]}]`,
+			calls: 0,
+		},
+		{
+			name:  "followed by a local context",
+			in:    `{"@context": ["https://example.org/a", "https://example.org/b", {"name": "https://example.org/c#name"}], "name": "x"}`,
+			out:   `[{"https://example.org/c#name": [{"@value": "x"}]}]`,
+			calls: 0,
+		},
+		{
+			name:  "single remote context",
+			in:    `{"@context": "https://example.org/b", "name": "x"}`,
+			out:   `[{"https://example.org/b#name": [{"@value": "x"}]}]`,
+			calls: 1,
+		},
+		{
+			name:  "preceded by a local context",
+			in:    `{"@context": [{"@vocab": "https://example.org/c#"}, "https://example.org/b"], "other": "x"}`,
+			out:   `[{"https://example.org/c#other": [{"@value": "x"}]}]`,
+			calls: 1,
+		},
+		{
+			name:  "relative vocabulary",
+			in:    `{"@context": "https://example.org/relative", "name": "x"}`,
+			base:  "https://one.example/doc",
+			out:   `[{"https://one.example/doc#name": [{"@value": "x"}]}]`,
+			calls: 1,
+		},
+		{
+			name:  "relative vocabulary with different base",
+			in:    `{"@context": "https://example.org/relative", "name": "x"}`,
+			base:  "https://two.example/doc",
+			out:   `[{"https://two.example/doc#name": [{"@value": "x"}]}]`,
+			calls: 1,
+		},
+		{
+			name:  "scoped remote context",
+			in:    `{"@context": "https://example.org/scoped", "author": {"name": "x"}}`,
+			out:   `[{"https://example.org/s#author": [{"https://example.org/b#name": [{"@value": "x"}]}]}]`,
+			calls: 3,
+		},
+		{
+			name:  "scoped remote context again",
+			in:    `{"@context": "https://example.org/scoped", "author": {"name": "y"}}`,
+			out:   `[{"https://example.org/s#author": [{"https://example.org/b#name": [{"@value": "y"}]}]}]`,
+			calls: 0,
+		},
+	}
+
+	cached, cachedCalls := newProc(ld.DefaultContextCacheSize)
+	uncached, uncachedCalls := newProc(0)
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for name, proc := range map[string]*ld.Processor{"cached": cached, "uncached": uncached} {
+				nodes, err := proc.Expand(t.Context(), strings.NewReader(tc.in), tc.base)
+				if err != nil {
+					t.Fatalf("%s: expected successful expansion, got: %s", name, err)
+				}
+
+				got, err := json.Marshal(nodes)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if diff := cmp.Diff(json.RawMessage(tc.out), json.RawMessage(got), JSONDiff()); diff != "" {
+					t.Errorf("%s: expansion mismatch (-want +got):\n%s", name, diff)
+				}
+			}
+		})
+	}
+
+	wantCalls := 0
+	for _, tc := range tests {
+		wantCalls += tc.calls
+	}
+
+	if *cachedCalls != wantCalls {
+		t.Errorf("expected %d loads with cache, got: %d", wantCalls, *cachedCalls)
+	}
+
+	if *uncachedCalls != 16 {
+		t.Errorf("expected 16 loads without cache, got: %d", *uncachedCalls)
+	}
+
+	// a local context between remote ones changes what the ones after it
+	// apply to, so the result must not be shared with the same remote
+	// contexts on their own, in either order
+	withLocal := `{"@context": ["https://example.org/a", {"other": "https://example.org/x#other"}, "https://example.org/b"], "other": "x"}`
+	withoutLocal := `{"@context": ["https://example.org/a", "https://example.org/b"], "other": "x"}`
+	outWithLocal := `[{"https://example.org/x#other": [{"@value": "x"}]}]`
+	outWithoutLocal := `[{"https://example.org/a#other": [{"@value": "x"}]}]`
+
+	for _, order := range []struct {
+		name string
+		in   [2]string
+		out  [2]string
+	}{
+		{
+			name: "local context first",
+			in:   [2]string{withLocal, withoutLocal},
+			out:  [2]string{outWithLocal, outWithoutLocal},
+		},
+		{
+			name: "local context last",
+			in:   [2]string{withoutLocal, withLocal},
+			out:  [2]string{outWithoutLocal, outWithLocal},
+		},
+	} {
+		t.Run(order.name, func(t *testing.T) {
+			proc, _ := newProc(ld.DefaultContextCacheSize)
+
+			for i := range order.in {
+				nodes, err := proc.Expand(t.Context(), strings.NewReader(order.in[i]), "")
+				if err != nil {
+					t.Fatalf("expected successful expansion, got: %s", err)
+				}
+
+				got, err := json.Marshal(nodes)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if diff := cmp.Diff(json.RawMessage(order.out[i]), json.RawMessage(got), JSONDiff()); diff != "" {
+					t.Errorf("expansion %d mismatch (-want +got):\n%s", i, diff)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessorWith(t *testing.T) {
+	var calls atomic.Int32
+	loader := func(_ context.Context, url string) (ld.Document, error) {
+		calls.Add(1)
+		if url != "https://example.org/ctx" {
+			return ld.Document{}, ld.ErrLoadingRemoteContext
+		}
+		return ld.Document{URL: url, Context: json.RawMessage(`{"@vocab": "https://example.org/ns#", "knows": {"@type": "@id"}}`)}, nil
+	}
+
+	parent := ld.NewProcessor(ld.WithRemoteContextLoader(loader))
+
+	in := `{"@context": "https://example.org/ctx", "@id": "1", "knows": "2"}`
+	compactCtx := json.RawMessage(`{"@vocab": "https://example.org/ns#"}`)
+
+	run := func(p *ld.Processor, base string) (string, string, error) {
+		nodes, err := p.Expand(context.Background(), strings.NewReader(in), base)
+		if err != nil {
+			return "", "", err
+		}
+
+		var dst bytes.Buffer
+		if err := p.Compact(context.Background(), &dst, compactCtx, nodes, ""); err != nil {
+			return "", "", err
+		}
+
+		return nodes[0].ID, dst.String(), nil
+	}
+
+	// IRIs are compacted relative to the base IRI of the processor
+	out := func(base string, compactArrays bool) string {
+		node := `"@id": "` + base + `1", "knows": {"@id": "` + base + `2"}`
+		if !compactArrays {
+			node = `"@graph": [{"@id": "` + base + `1", "knows": [{"@id": "` + base + `2"}]}]`
+		}
+		return `{"@context": {"@vocab": "https://example.org/ns#"}, ` + node + `}`
+	}
+
+	t.Run("concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		errs := make(chan error, 20)
+
+		for i := range 20 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				base := "https://" + strconv.Itoa(i) + ".example/"
+				compactArrays := i%2 == 0
+				p := parent.With(ld.WithBaseIRI(base), ld.WithCompactArrays(compactArrays))
+
+				id, got, err := run(p, "")
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if id != base+"1" {
+					errs <- fmt.Errorf("processor %d: expected @id %s1, got: %s", i, base, id)
+				}
+
+				if diff := cmp.Diff(json.RawMessage(out("", compactArrays)), json.RawMessage(got), JSONDiff()); diff != "" {
+					errs <- fmt.Errorf("processor %d: compaction mismatch (-want +got):\n%s", i, diff)
+				}
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Error(err)
+		}
+
+		// the parent's settings are left as they were
+		_, got, err := run(parent, "https://parent.example/")
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		if diff := cmp.Diff(json.RawMessage(out("https://parent.example/", true)), json.RawMessage(got), JSONDiff()); diff != "" {
+			t.Errorf("compaction mismatch (-want +got):\n%s", diff)
+		}
+
+		if n := calls.Load(); n != 1 {
+			t.Errorf("expected derived processors to share the context cache, got %d loads", n)
+		}
+	})
+
+	t.Run("loader", func(t *testing.T) {
+		var derivedCalls atomic.Int32
+		p := parent.With(ld.WithRemoteContextLoader(func(ctx context.Context, url string) (ld.Document, error) {
+			derivedCalls.Add(1)
+			return loader(ctx, url)
+		}))
+
+		if _, _, err := run(p, "https://example.org/"); err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		if n := derivedCalls.Load(); n != 1 {
+			t.Errorf("expected the derived loader to be used with its own cache, got %d loads", n)
+		}
+	})
+
+	t.Run("no loader", func(t *testing.T) {
+		// the context the parent has cached must not be used once the loader
+		// has been removed
+		if _, _, err := run(parent, "https://example.org/"); err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		p := parent.With(ld.WithRemoteContextLoader(nil))
+
+		if _, _, err := run(p, "https://example.org/"); !errors.Is(err, ld.ErrLoadingRemoteContext) {
+			t.Fatalf("expected error: %s, got: %v", ld.ErrLoadingRemoteContext, err)
+		}
+	})
+
+	t.Run("disallowed keywords", func(t *testing.T) {
+		graph := `{"@graph": [{"@id": "https://example.org/1"}]}`
+		nest := `{"@context": {"data": "@nest"}, "@id": "https://example.org/1", "data": {}}`
+
+		p := ld.NewProcessor(ld.WithDisallowedKeywords(ld.KeywordGraph, ld.KeywordNest))
+
+		tests := []struct {
+			name    string
+			proc    *ld.Processor
+			graphOK bool
+			nestOK  bool
+		}{
+			{name: "parent", proc: p},
+			{name: "replaced", proc: p.With(ld.WithDisallowedKeywords(ld.KeywordNest)), graphOK: true},
+			{name: "cleared", proc: p.With(ld.WithDisallowedKeywords()), graphOK: true, nestOK: true},
+			{name: "added up within one call", proc: p.With(
+				ld.WithDisallowedKeywords(),
+				ld.WithDisallowedKeywords(ld.KeywordGraph),
+				ld.WithDisallowedKeywords(ld.KeywordNest),
+			)},
+		}
+
+		for _, tc := range tests {
+			for doc, ok := range map[string]bool{graph: tc.graphOK, nest: tc.nestOK} {
+				_, err := tc.proc.Expand(t.Context(), strings.NewReader(doc), "")
+				if ok && err != nil {
+					t.Errorf("%s: expected no error for %s, got: %s", tc.name, doc, err)
+				}
+				if !ok && !errors.Is(err, ld.ErrDisallowedKeyword) {
+					t.Errorf("%s: expected error: %s for %s, got: %v", tc.name, ld.ErrDisallowedKeyword, doc, err)
+				}
+			}
+		}
+	})
+
+	t.Run("remap prefix IRIs", func(t *testing.T) {
+		doc := `{"@context": {"schema": "http://schema.org#"}, "schema:name": "Alice"}`
+
+		p := ld.NewProcessor(ld.WithRemapPrefixIRIs("http://schema.org#", "http://schema.org/"))
+		remapped := p.With(ld.WithRemapPrefixIRIs("https://example.org#", "https://example.org/"))
+
+		for name, proc := range map[string]*ld.Processor{"parent": p, "derived": remapped} {
+			nodes, err := proc.Expand(t.Context(), strings.NewReader(doc), "")
+			if err != nil {
+				t.Fatalf("%s: expected no error, got: %s", name, err)
+			}
+
+			if _, ok := nodes[0].Properties["http://schema.org/name"]; !ok {
+				t.Errorf("%s: expected IRI to remap, got: %#v", name, nodes[0])
+			}
+		}
+
+		doc = `{"@context": {"ex": "https://example.org#"}, "ex:name": "Alice"}`
+
+		nodes, err := p.Expand(t.Context(), strings.NewReader(doc), "")
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		if _, ok := nodes[0].Properties["https://example.org#name"]; !ok {
+			t.Errorf("expected the parent to be unaffected, got: %#v", nodes[0])
+		}
+	})
+
+	t.Run("expand context", func(t *testing.T) {
+		doc := `{"name": "Alice"}`
+
+		p := ld.NewProcessor(ld.WithExpandContext(json.RawMessage(`{"name": "https://example.org/a#name"}`)))
+
+		// process the parent's expand context first, so it would be shared
+		// if the derived processor didn't get its own
+		nodes, err := p.Expand(t.Context(), strings.NewReader(doc), "")
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		derived := p.With(ld.WithExpandContext(json.RawMessage(`{"name": "https://example.org/b#name"}`)))
+		derivedNodes, err := derived.Expand(t.Context(), strings.NewReader(doc), "")
+		if err != nil {
+			t.Fatalf("expected no error, got: %s", err)
+		}
+
+		if _, ok := nodes[0].Properties["https://example.org/a#name"]; !ok {
+			t.Errorf("expected the parent's expand context, got: %#v", nodes[0])
+		}
+
+		if _, ok := derivedNodes[0].Properties["https://example.org/b#name"]; !ok {
+			t.Errorf("expected the derived expand context, got: %#v", derivedNodes[0])
+		}
+	})
+
+	// a relative @vocab resolves against the base IRI, so the processed
+	// context must not leak from one derived processor into another
+	t.Run("base-relative context", func(t *testing.T) {
+		relative := func(_ context.Context, url string) (ld.Document, error) {
+			return ld.Document{URL: url, Context: json.RawMessage(`{"@vocab": "#"}`)}, nil
+		}
+
+		tests := []struct {
+			name string
+			proc *ld.Processor
+			doc  string
+		}{
+			{
+				name: "remote context",
+				proc: ld.NewProcessor(ld.WithRemoteContextLoader(relative)),
+				doc:  `{"@context": "https://example.org/relative", "name": "Alice"}`,
+			},
+			{
+				name: "expand context",
+				proc: ld.NewProcessor(ld.WithExpandContext(json.RawMessage(`{"@vocab": "#"}`))),
+				doc:  `{"name": "Alice"}`,
+			},
+		}
+
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {
+				for _, base := range []string{"https://one.example/doc", "https://two.example/doc"} {
+					nodes, err := tc.proc.With(ld.WithBaseIRI(base)).Expand(t.Context(), strings.NewReader(tc.doc), "")
+					if err != nil {
+						t.Fatalf("expected no error, got: %s", err)
+					}
+
+					if _, ok := nodes[0].Properties[base+"#name"]; !ok {
+						t.Errorf("expected %s#name, got: %#v", base, nodes[0])
+					}
+				}
+			})
+		}
+	})
+}
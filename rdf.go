@@ -0,0 +1,201 @@
+package longdistance
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RDF and XSD IRIs used when converting between JSON-LD and RDF.
+const (
+	RDFType       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	RDFFirst      = "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"
+	RDFRest       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"
+	RDFNil        = "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"
+	RDFList       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#List"
+	RDFLangString = "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"
+	RDFJSON       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON"
+
+	XSDString   = "http://www.w3.org/2001/XMLSchema#string"
+	XSDBoolean  = "http://www.w3.org/2001/XMLSchema#boolean"
+	XSDInteger  = "http://www.w3.org/2001/XMLSchema#integer"
+	XSDDouble   = "http://www.w3.org/2001/XMLSchema#double"
+	XSDDateTime = "http://www.w3.org/2001/XMLSchema#dateTime"
+	XSDDate     = "http://www.w3.org/2001/XMLSchema#date"
+)
+
+// TermKind is the kind of an [RDFTerm].
+type TermKind uint8
+
+// Kinds of RDF terms.
+const (
+	KindIRI TermKind = iota + 1
+	KindBlankNode
+	KindLiteral
+)
+
+// RDFTerm is an RDF term: an IRI, a blank node or a literal.
+//
+// The zero value is not a valid term. It's used as the graph name of quads in
+// the default graph.
+type RDFTerm struct {
+	Kind     TermKind
+	Value    string // IRI, blank node identifier including _: or lexical form
+	Datatype string // datatype IRI of a literal
+	Language string // language tag of a literal
+}
+
+// NewIRI returns an IRI term.
+func NewIRI(iri string) RDFTerm {
+	return RDFTerm{Kind: KindIRI, Value: iri}
+}
+
+// NewBlankNode returns a blank node term. The identifier must include the
+// _: prefix.
+func NewBlankNode(id string) RDFTerm {
+	return RDFTerm{Kind: KindBlankNode, Value: id}
+}
+
+// NewLiteral returns a literal term.
+//
+// When language is set, the datatype is always rdf:langString. When neither
+// is set, the datatype is xsd:string.
+func NewLiteral(value, datatype, language string) RDFTerm {
+	if language != "" {
+		datatype = RDFLangString
+	}
+
+	if datatype == "" {
+		datatype = XSDString
+	}
+
+	return RDFTerm{
+		Kind:     KindLiteral,
+		Value:    value,
+		Datatype: datatype,
+		Language: language,
+	}
+}
+
+// IsZero returns if this is the zero term.
+func (t RDFTerm) IsZero() bool {
+	return t == RDFTerm{}
+}
+
+// IsIRI returns if the term is an IRI.
+func (t RDFTerm) IsIRI() bool {
+	return t.Kind == KindIRI
+}
+
+// IsBlankNode returns if the term is a blank node.
+func (t RDFTerm) IsBlankNode() bool {
+	return t.Kind == KindBlankNode
+}
+
+// IsLiteral returns if the term is a literal.
+func (t RDFTerm) IsLiteral() bool {
+	return t.Kind == KindLiteral
+}
+
+// String returns the term in canonical N-Quads syntax.
+func (t RDFTerm) String() string {
+	switch t.Kind {
+	case KindIRI:
+		var b strings.Builder
+		b.WriteByte('<')
+		escapeIRI(&b, t.Value)
+		b.WriteByte('>')
+		return b.String()
+	case KindBlankNode:
+		return t.Value
+	case KindLiteral:
+		var b strings.Builder
+		b.WriteByte('"')
+		escapeLiteral(&b, t.Value)
+		b.WriteByte('"')
+
+		if t.Language != "" {
+			b.WriteByte('@')
+			b.WriteString(t.Language)
+		} else if t.Datatype != "" && t.Datatype != XSDString {
+			b.WriteString("^^<")
+			b.WriteString(t.Datatype)
+			b.WriteByte('>')
+		}
+
+		return b.String()
+	default:
+		return ""
+	}
+}
+
+// escapeIRI escapes the characters that aren't allowed in an IRIREF.
+func escapeIRI(b *strings.Builder, s string) {
+	for _, r := range s {
+		if r <= 0x20 || strings.ContainsRune("<>\"{}|^`\\", r) {
+			fmt.Fprintf(b, `\u%04X`, r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+}
+
+func escapeLiteral(b *strings.Builder, s string) {
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+}
+
+// Quad is an RDF triple, with the name of the graph it belongs to.
+//
+// Quads in the default graph have the zero [RDFTerm] as their Graph.
+type Quad struct {
+	Subject   RDFTerm
+	Predicate RDFTerm
+	Object    RDFTerm
+	Graph     RDFTerm
+}
+
+// String returns the quad as a line in canonical N-Quads syntax, without the
+// trailing newline.
+func (q Quad) String() string {
+	var b strings.Builder
+	b.WriteString(q.Subject.String())
+	b.WriteByte(' ')
+	b.WriteString(q.Predicate.String())
+	b.WriteByte(' ')
+	b.WriteString(q.Object.String())
+	if !q.Graph.IsZero() {
+		b.WriteByte(' ')
+		b.WriteString(q.Graph.String())
+	}
+	b.WriteString(" .")
+
+	return b.String()
+}
+
+// Dataset is an RDF dataset.
+//
+// It consists of the quads of the default graph as well as those of any
+// named graph.
+type Dataset []Quad
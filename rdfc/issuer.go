@@ -0,0 +1,92 @@
+package rdfc
+
+import (
+	"iter"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// issuer implements the Issue Identifier algorithm. Identifiers are issued
+// with the _: prefix.
+type issuer struct {
+	prefix  string
+	counter int
+	issued  map[string]string
+	order   []string
+}
+
+func newIssuer(prefix string) *issuer {
+	return &issuer{
+		prefix: prefix,
+		issued: map[string]string{},
+	}
+}
+
+// issue returns the identifier issued for id, issuing a new one if needed.
+func (i *issuer) issue(id string) string {
+	// 1)
+	if res, ok := i.issued[id]; ok {
+		return res
+	}
+
+	// 2)
+	res := i.prefix + strconv.Itoa(i.counter)
+
+	// 3)
+	i.issued[id] = res
+	i.order = append(i.order, id)
+
+	// 4)
+	i.counter++
+
+	// 5)
+	return res
+}
+
+func (i *issuer) has(id string) bool {
+	_, ok := i.issued[id]
+	return ok
+}
+
+func (i *issuer) clone() *issuer {
+	return &issuer{
+		prefix:  i.prefix,
+		counter: i.counter,
+		issued:  maps.Clone(i.issued),
+		order:   slices.Clone(i.order),
+	}
+}
+
+// permutations yields all permutations of the sorted list in lexicographic
+// order. The yielded slice is reused between iterations.
+func permutations(list []string) iter.Seq[[]string] {
+	return func(yield func([]string) bool) {
+		perm := slices.Clone(list)
+
+		for {
+			if !yield(perm) {
+				return
+			}
+
+			// find the rightmost element smaller than its successor
+			i := len(perm) - 2
+			for i >= 0 && perm[i] >= perm[i+1] {
+				i--
+			}
+
+			if i < 0 {
+				return
+			}
+
+			// swap it with the rightmost element larger than it
+			j := len(perm) - 1
+			for perm[j] <= perm[i] {
+				j--
+			}
+
+			perm[i], perm[j] = perm[j], perm[i]
+			slices.Reverse(perm[i+1:])
+		}
+	}
+}
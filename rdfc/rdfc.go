@@ -0,0 +1,495 @@
+// Package rdfc implements RDF Dataset Canonicalization (RDFC-1.0).
+//
+// Canonicalization assigns deterministic labels to the blank nodes of a
+// dataset, so that two datasets that only differ in how their blank nodes are
+// labelled result in the same canonical N-Quads. This is needed to compute a
+// stable hash or signature of a dataset, like for Data Integrity proofs.
+//
+// A dataset is usually obtained from a list of [ld.Node] using
+// [ld.Processor.ToRDF].
+//
+// See https://www.w3.org/TR/rdf-canon/.
+package rdfc
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/nquads"
+)
+
+// DefaultWorkLimit is the default limit for [WithWorkLimit].
+const DefaultWorkLimit = 100_000
+
+var (
+	// ErrUnsupportedHash is returned when a hash other than SHA-256 or SHA-384
+	// is requested.
+	ErrUnsupportedHash = errors.New("unsupported hash algorithm")
+
+	// ErrWorkLimitExceeded is returned when canonicalization takes more work
+	// than allowed by [WithWorkLimit].
+	ErrWorkLimitExceeded = errors.New("work limit exceeded")
+)
+
+// Option configures the canonicalization.
+type Option func(*canonicalizer)
+
+// WithHash sets the hash algorithm to use. This can be [crypto.SHA256] or
+// [crypto.SHA384].
+//
+// Defaults to [crypto.SHA256].
+func WithHash(h crypto.Hash) Option {
+	return func(c *canonicalizer) {
+		c.hash = h
+	}
+}
+
+// WithWorkLimit limits the amount of work canonicalization is allowed to do.
+//
+// Datasets with many blank nodes that can't be told apart take an exponential
+// amount of time to canonicalize. Every computation of an N-degree hash and
+// every permutation of related blank nodes that's considered counts as one
+// unit of work. Once the limit is exceeded, [ErrWorkLimitExceeded] is
+// returned.
+//
+// Defaults to [DefaultWorkLimit]. A limit below 1 disables it.
+func WithWorkLimit(n int) Option {
+	return func(c *canonicalizer) {
+		c.workLimit = n
+	}
+}
+
+// Canonicalize returns the dataset with canonical blank node labels, in the
+// order of their canonical N-Quads. Duplicate quads are removed.
+//
+// Blank node predicates, which only occur in generalized RDF, are labelled
+// like any other blank node.
+func Canonicalize(dataset ld.Dataset, opts ...Option) (ld.Dataset, error) {
+	c, err := newCanonicalizer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.canonicalize(dataset)
+}
+
+// IssuedIdentifiers returns the canonical label of every blank node in the
+// dataset, keyed by the label it has in the dataset. Both include the _:
+// prefix, like the Value of a blank node [ld.RDFTerm].
+//
+// This is the issued identifiers map of the canonicalization algorithm, which
+// can be used to relabel data related to the dataset, like selective
+// disclosure proofs.
+func IssuedIdentifiers(dataset ld.Dataset, opts ...Option) (map[string]string, error) {
+	c, err := newCanonicalizer(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.label(dataset); err != nil {
+		return nil, err
+	}
+
+	return maps.Clone(c.canonicalIssuer.issued), nil
+}
+
+// Write writes the dataset as canonical N-Quads.
+func Write(dst io.Writer, dataset ld.Dataset, opts ...Option) error {
+	canonical, err := Canonicalize(dataset, opts...)
+	if err != nil {
+		return err
+	}
+
+	return nquads.NewWriter(dst).WriteAll(canonical)
+}
+
+type canonicalizer struct {
+	hash      crypto.Hash
+	newHash   func() hash.Hash
+	workLimit int
+	work      int
+
+	blankNodeToQuads map[string][]ld.Quad
+	canonicalIssuer  *issuer
+}
+
+func newCanonicalizer(opts []Option) (*canonicalizer, error) {
+	c := &canonicalizer{
+		hash:      crypto.SHA256,
+		workLimit: DefaultWorkLimit,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	switch c.hash {
+	case crypto.SHA256:
+		c.newHash = sha256.New
+	case crypto.SHA384:
+		c.newHash = sha512.New384
+	default:
+		return nil, ErrUnsupportedHash
+	}
+
+	return c, nil
+}
+
+func (c *canonicalizer) canonicalize(dataset ld.Dataset) (ld.Dataset, error) {
+	seen, err := c.label(dataset)
+	if err != nil {
+		return nil, err
+	}
+
+	// 6)
+	relabel := func(term ld.RDFTerm) ld.RDFTerm {
+		if term.IsBlankNode() {
+			return ld.NewBlankNode(c.canonicalIssuer.issue(term.Value))
+		}
+		return term
+	}
+
+	type line struct {
+		quad ld.Quad
+		nq   string
+	}
+
+	lines := make([]line, 0, len(seen))
+	for quad := range seen {
+		quad = ld.Quad{
+			Subject:   relabel(quad.Subject),
+			Predicate: relabel(quad.Predicate),
+			Object:    relabel(quad.Object),
+			Graph:     relabel(quad.Graph),
+		}
+		lines = append(lines, line{quad: quad, nq: quad.String()})
+	}
+
+	slices.SortFunc(lines, func(a, b line) int {
+		return strings.Compare(a.nq, b.nq)
+	})
+
+	result := make(ld.Dataset, 0, len(lines))
+	for _, l := range lines {
+		result = append(result, l.quad)
+	}
+
+	return result, nil
+}
+
+// label issues the canonical labels of the blank nodes in the dataset, steps
+// 1 to 5 of the algorithm. It returns the quads of the dataset without
+// duplicates.
+func (c *canonicalizer) label(dataset ld.Dataset) (map[ld.Quad]struct{}, error) {
+	// 1)
+	c.blankNodeToQuads = map[string][]ld.Quad{}
+	c.canonicalIssuer = newIssuer("_:c14n")
+
+	// 2)
+	seen := make(map[ld.Quad]struct{}, len(dataset))
+	for _, quad := range dataset {
+		if _, ok := seen[quad]; ok {
+			continue
+		}
+		seen[quad] = struct{}{}
+
+		for i, term := range components(quad) {
+			if !term.IsBlankNode() || slices.Contains(components(quad)[:i], term) {
+				continue
+			}
+			c.blankNodeToQuads[term.Value] = append(c.blankNodeToQuads[term.Value], quad)
+		}
+	}
+
+	// 3)
+	hashToBlankNodes := map[string][]string{}
+	for _, id := range slices.Sorted(maps.Keys(c.blankNodeToQuads)) {
+		h := c.hashFirstDegreeQuads(id)
+		hashToBlankNodes[h] = append(hashToBlankNodes[h], id)
+	}
+
+	// 4)
+	for _, h := range slices.Sorted(maps.Keys(hashToBlankNodes)) {
+		// 4.1)
+		if len(hashToBlankNodes[h]) > 1 {
+			continue
+		}
+
+		// 4.2)
+		c.canonicalIssuer.issue(hashToBlankNodes[h][0])
+
+		// 4.3)
+		delete(hashToBlankNodes, h)
+	}
+
+	// 5)
+	for _, h := range slices.Sorted(maps.Keys(hashToBlankNodes)) {
+		// 5.1)
+		var hashPathList []nDegreeResult
+
+		// 5.2)
+		for _, id := range hashToBlankNodes[h] {
+			// 5.2.1)
+			if c.canonicalIssuer.has(id) {
+				continue
+			}
+
+			// 5.2.2) 5.2.3)
+			tmp := newIssuer("_:b")
+			tmp.issue(id)
+
+			// 5.2.4)
+			result, err := c.hashNDegreeQuads(id, tmp)
+			if err != nil {
+				return nil, err
+			}
+			hashPathList = append(hashPathList, result)
+		}
+
+		// 5.3)
+		slices.SortStableFunc(hashPathList, func(a, b nDegreeResult) int {
+			return strings.Compare(a.hash, b.hash)
+		})
+
+		for _, result := range hashPathList {
+			// 5.3.1)
+			for _, id := range result.issuer.order {
+				c.canonicalIssuer.issue(id)
+			}
+		}
+	}
+
+	return seen, nil
+}
+
+// components returns the terms of a quad that are considered for blank nodes.
+// The predicate can only be a blank node in generalized RDF.
+func components(quad ld.Quad) []ld.RDFTerm {
+	return []ld.RDFTerm{quad.Subject, quad.Predicate, quad.Object, quad.Graph}
+}
+
+func (c *canonicalizer) sum(data string) string {
+	h := c.newHash()
+	h.Write([]byte(data))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFirstDegreeQuads implements the Hash First Degree Quads algorithm.
+func (c *canonicalizer) hashFirstDegreeQuads(id string) string {
+	replace := func(term ld.RDFTerm) ld.RDFTerm {
+		if !term.IsBlankNode() {
+			return term
+		}
+		if term.Value == id {
+			return ld.NewBlankNode("_:a")
+		}
+		return ld.NewBlankNode("_:z")
+	}
+
+	// 1) 2) 3)
+	lines := make([]string, 0, len(c.blankNodeToQuads[id]))
+	for _, quad := range c.blankNodeToQuads[id] {
+		// 3.1)
+		quad = ld.Quad{
+			Subject:   replace(quad.Subject),
+			Predicate: replace(quad.Predicate),
+			Object:    replace(quad.Object),
+			Graph:     replace(quad.Graph),
+		}
+		lines = append(lines, quad.String()+"\n")
+	}
+
+	// 4)
+	slices.Sort(lines)
+
+	// 5)
+	return c.sum(strings.Join(lines, ""))
+}
+
+// hashRelatedBlankNode implements the Hash Related Blank Node algorithm.
+func (c *canonicalizer) hashRelatedBlankNode(
+	related string,
+	quad ld.Quad,
+	iss *issuer,
+	position string,
+) string {
+	// 1)
+	var id string
+	switch {
+	case c.canonicalIssuer.has(related):
+		id = c.canonicalIssuer.issue(related)
+	case iss.has(related):
+		id = iss.issue(related)
+	default:
+		id = c.hashFirstDegreeQuads(related)
+	}
+
+	// 2)
+	input := position
+
+	// 3) a blank node predicate is hashed as a related blank node in the "p"
+	// position instead, since its label isn't canonical
+	switch {
+	case position == "g" || position == "p":
+	case quad.Predicate.IsBlankNode():
+		input += "_:"
+	default:
+		input += "<" + quad.Predicate.Value + ">"
+	}
+
+	// 4) 5)
+	return c.sum(input + id)
+}
+
+type nDegreeResult struct {
+	hash   string
+	issuer *issuer
+}
+
+// hashNDegreeQuads implements the Hash N-Degree Quads algorithm.
+func (c *canonicalizer) hashNDegreeQuads(id string, iss *issuer) (nDegreeResult, error) {
+	if err := c.spend(); err != nil {
+		return nDegreeResult{}, err
+	}
+
+	// 1)
+	hashToRelated := map[string][]string{}
+
+	// 2) 3)
+	for _, quad := range c.blankNodeToQuads[id] {
+		// 3.1)
+		for position, term := range map[string]ld.RDFTerm{
+			"s": quad.Subject,
+			"p": quad.Predicate,
+			"o": quad.Object,
+			"g": quad.Graph,
+		} {
+			if !term.IsBlankNode() || term.Value == id {
+				continue
+			}
+
+			// 3.1.1)
+			h := c.hashRelatedBlankNode(term.Value, quad, iss, position)
+
+			// 3.1.2)
+			hashToRelated[h] = append(hashToRelated[h], term.Value)
+		}
+	}
+
+	// 4)
+	var data strings.Builder
+
+	// 5)
+	for _, h := range slices.Sorted(maps.Keys(hashToRelated)) {
+		// 5.1)
+		data.WriteString(h)
+
+		// 5.2) 5.3)
+		var chosenPath string
+		var chosenIssuer *issuer
+
+		// 5.4)
+		related := hashToRelated[h]
+		slices.Sort(related)
+
+		for perm := range permutations(related) {
+			if err := c.spend(); err != nil {
+				return nDegreeResult{}, err
+			}
+
+			// 5.4.1) 5.4.2) 5.4.3)
+			issuerCopy := iss.clone()
+			var path strings.Builder
+			var recursionList []string
+
+			skip := func() bool {
+				return chosenPath != "" &&
+					path.Len() >= len(chosenPath) &&
+					path.String() > chosenPath
+			}
+
+			// 5.4.4)
+			skipped := false
+			for _, rel := range perm {
+				if c.canonicalIssuer.has(rel) {
+					// 5.4.4.1)
+					path.WriteString(c.canonicalIssuer.issue(rel))
+				} else {
+					// 5.4.4.2)
+					if !issuerCopy.has(rel) {
+						recursionList = append(recursionList, rel)
+					}
+					path.WriteString(issuerCopy.issue(rel))
+				}
+
+				// 5.4.4.3)
+				if skip() {
+					skipped = true
+					break
+				}
+			}
+
+			if skipped {
+				continue
+			}
+
+			// 5.4.5)
+			for _, rel := range recursionList {
+				// 5.4.5.1)
+				result, err := c.hashNDegreeQuads(rel, issuerCopy)
+				if err != nil {
+					return nDegreeResult{}, err
+				}
+
+				// 5.4.5.2) 5.4.5.3)
+				path.WriteString(issuerCopy.issue(rel))
+				path.WriteString("<" + result.hash + ">")
+
+				// 5.4.5.4)
+				issuerCopy = result.issuer
+
+				// 5.4.5.5)
+				if skip() {
+					skipped = true
+					break
+				}
+			}
+
+			if skipped {
+				continue
+			}
+
+			// 5.4.6)
+			if chosenPath == "" || path.String() < chosenPath {
+				chosenPath = path.String()
+				chosenIssuer = issuerCopy
+			}
+		}
+
+		// 5.5)
+		data.WriteString(chosenPath)
+
+		// 5.6)
+		iss = chosenIssuer
+	}
+
+	// 6)
+	return nDegreeResult{hash: c.sum(data.String()), issuer: iss}, nil
+}
+
+func (c *canonicalizer) spend() error {
+	c.work++
+	if c.workLimit > 0 && c.work > c.workLimit {
+		return ErrWorkLimitExceeded
+	}
+	return nil
+}
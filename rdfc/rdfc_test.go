@@ -0,0 +1,312 @@
+package rdfc_test
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/nquads"
+	"sourcery.dny.nu/longdistance/rdfc"
+)
+
+var requireW3C = flag.Bool("w3c", false, "fail instead of skip when the W3C test manifest hasn't been imported")
+
+// manifest is the layout of the RDFC-1.0 test suite manifest, which
+// local-manifest.json follows too.
+type manifest struct {
+	Entries []manifestEntry `json:"entries"`
+}
+
+type manifestEntry struct {
+	ID            string `json:"id"`
+	Type          string `json:"type"`
+	Name          string `json:"name"`
+	Action        string `json:"action"`
+	Result        string `json:"result"`
+	HashAlgorithm string `json:"hashAlgorithm"`
+}
+
+func loadDataset(tb testing.TB, path string) ld.Dataset {
+	tb.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+
+	dataset, err := nquads.NewReader(f).ReadAll()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return dataset
+}
+
+// TestManifest runs the RDFC-1.0 test suite from testdata/w3c, and the tests
+// of this package in local-manifest.json.
+//
+// The W3C suite is skipped while it hasn't been imported, or fails when run
+// with -w3c. See testdata/w3c/README.md.
+func TestManifest(t *testing.T) {
+	for _, manifest := range []string{"w3c/manifest.jsonld", "local-manifest.json"} {
+		t.Run(manifest, func(t *testing.T) {
+			t.Parallel()
+			runManifest(t, manifest)
+		})
+	}
+}
+
+func runManifest(t *testing.T, path string) {
+	data, err := os.ReadFile(filepath.Join("testdata", path))
+	if errors.Is(err, fs.ErrNotExist) {
+		if *requireW3C {
+			t.Fatalf("manifest %s has not been imported", path)
+		}
+		t.Skipf("manifest %s has not been imported", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join("testdata", filepath.Dir(path))
+
+	for _, entry := range m.Entries {
+		t.Run(strings.TrimPrefix(entry.ID, "#"), func(t *testing.T) {
+			t.Parallel()
+
+			dataset := loadDataset(t, filepath.Join(dir, entry.Action))
+
+			var opts []rdfc.Option
+			if entry.HashAlgorithm == "SHA384" {
+				opts = append(opts, rdfc.WithHash(crypto.SHA384))
+			}
+
+			switch entry.Type {
+			case "rdfc:RDFC10NegativeEvalTest":
+				_, err := rdfc.Canonicalize(dataset, opts...)
+				if !errors.Is(err, rdfc.ErrWorkLimitExceeded) {
+					t.Fatalf("expected error: %v, got: %v", rdfc.ErrWorkLimitExceeded, err)
+				}
+			case "rdfc:RDFC10EvalTest":
+				var buf bytes.Buffer
+				if err := rdfc.Write(&buf, dataset, opts...); err != nil {
+					t.Fatalf("expected successful canonicalization, got: %s", err)
+				}
+
+				want, err := os.ReadFile(filepath.Join(dir, entry.Result))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if diff := cmp.Diff(string(want), buf.String()); diff != "" {
+					t.Errorf("%s: canonicalization mismatch (-want +got):\n%s", entry.Name, diff)
+				}
+			case "rdfc:RDFC10MapTest":
+				issued, err := rdfc.IssuedIdentifiers(dataset, opts...)
+				if err != nil {
+					t.Fatalf("expected successful canonicalization, got: %s", err)
+				}
+
+				// the suite lists the identifiers without the _: prefix
+				got := make(map[string]string, len(issued))
+				for k, v := range issued {
+					got[strings.TrimPrefix(k, "_:")] = strings.TrimPrefix(v, "_:")
+				}
+
+				data, err := os.ReadFile(filepath.Join(dir, entry.Result))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var want map[string]string
+				if err := json.Unmarshal(data, &want); err != nil {
+					t.Fatal(err)
+				}
+
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Errorf("%s: issued identifiers mismatch (-want +got):\n%s", entry.Name, diff)
+				}
+			default:
+				t.Fatalf("unknown test type: %s", entry.Type)
+			}
+		})
+	}
+}
+
+// TestIsomorphic checks that relabelling the blank nodes of a dataset and
+// shuffling its quads doesn't change the result. Unlike the manifest tests,
+// this doesn't need an expected result, so the inputs of testdata that have
+// none are only checked here.
+func TestIsomorphic(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*-in.nq"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := map[string]crypto.Hash{
+		"SHA256": crypto.SHA256,
+		"SHA384": crypto.SHA384,
+	}
+
+	for _, file := range files {
+		if filepath.Base(file) == "local-006-in.nq" {
+			continue
+		}
+
+		for name, hash := range hashes {
+			t.Run(filepath.Base(file)+"/"+name, func(t *testing.T) {
+				t.Parallel()
+
+				dataset := loadDataset(t, file)
+
+				want, err := rdfc.Canonicalize(dataset, rdfc.WithHash(hash))
+				if err != nil {
+					t.Fatalf("expected successful canonicalization, got: %s", err)
+				}
+
+				rng := rand.New(rand.NewPCG(1, 2))
+
+				for range 10 {
+					labels := map[string]string{}
+					relabel := func(term ld.RDFTerm) ld.RDFTerm {
+						if !term.IsBlankNode() {
+							return term
+						}
+						if _, ok := labels[term.Value]; !ok {
+							labels[term.Value] = "_:r" + strconv.Itoa(rng.IntN(1_000_000))
+						}
+						return ld.NewBlankNode(labels[term.Value])
+					}
+
+					shuffled := make(ld.Dataset, 0, len(dataset))
+					for _, quad := range dataset {
+						shuffled = append(shuffled, ld.Quad{
+							Subject:   relabel(quad.Subject),
+							Predicate: relabel(quad.Predicate),
+							Object:    relabel(quad.Object),
+							Graph:     relabel(quad.Graph),
+						})
+					}
+					rng.Shuffle(len(shuffled), func(i, j int) {
+						shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+					})
+
+					got, err := rdfc.Canonicalize(shuffled, rdfc.WithHash(hash))
+					if err != nil {
+						t.Fatalf("expected successful canonicalization, got: %s", err)
+					}
+
+					if diff := cmp.Diff(want, got); diff != "" {
+						t.Fatalf("canonicalization of relabelled dataset mismatch (-want +got):\n%s", diff)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFromNodes(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "testdata", "longdistance", "tordf", "named-graph", "in.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proc := ld.NewProcessor()
+
+	nodes, err := proc.Expand(t.Context(), bytes.NewReader(data), "")
+	if err != nil {
+		t.Fatalf("expected successful expand, got: %s", err)
+	}
+
+	dataset, err := proc.ToRDF(nodes)
+	if err != nil {
+		t.Fatalf("expected successful conversion to RDF, got: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := rdfc.Write(&buf, dataset); err != nil {
+		t.Fatalf("expected successful canonicalization, got: %s", err)
+	}
+
+	want := `<http://example.org/g> <http://example.org/label> "graph" .
+<http://example.org/s> <http://example.org/p> "o" <http://example.org/g> .
+_:c14n0 <http://example.org/p> "bnode" <http://example.org/g> .
+`
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Errorf("canonicalization mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	dataset := loadDataset(t, filepath.Join("testdata", "spec-002-in.nq"))
+
+	t.Run("unsupported hash", func(t *testing.T) {
+		_, err := rdfc.Canonicalize(dataset, rdfc.WithHash(crypto.SHA1))
+		if !errors.Is(err, rdfc.ErrUnsupportedHash) {
+			t.Fatalf("expected error: %v, got: %v", rdfc.ErrUnsupportedHash, err)
+		}
+	})
+
+	t.Run("work limit", func(t *testing.T) {
+		_, err := rdfc.Canonicalize(dataset, rdfc.WithWorkLimit(2))
+		if !errors.Is(err, rdfc.ErrWorkLimitExceeded) {
+			t.Fatalf("expected error: %v, got: %v", rdfc.ErrWorkLimitExceeded, err)
+		}
+	})
+
+	t.Run("work limit disabled", func(t *testing.T) {
+		_, err := rdfc.Canonicalize(dataset, rdfc.WithWorkLimit(0))
+		if err != nil {
+			t.Fatalf("expected successful canonicalization, got: %s", err)
+		}
+	})
+}
+
+func TestGeneralized(t *testing.T) {
+	dataset := func(s, p1, p2 string) ld.Dataset {
+		return ld.Dataset{
+			{Subject: ld.NewBlankNode(s), Predicate: ld.NewBlankNode(p1), Object: ld.NewLiteral("a", "", "")},
+			{Subject: ld.NewBlankNode(s), Predicate: ld.NewBlankNode(p2), Object: ld.NewLiteral("b", "", "")},
+		}
+	}
+
+	want := `_:c14n1 _:c14n0 "a" .
+_:c14n1 _:c14n2 "b" .
+`
+
+	for _, labels := range [][3]string{
+		{"_:s", "_:p1", "_:p2"},
+		{"_:s", "_:p2", "_:p1"},
+		{"_:p1", "_:s", "_:a"},
+		{"_:z", "_:b", "_:a"},
+	} {
+		for range 10 {
+			var got bytes.Buffer
+			if err := rdfc.Write(&got, dataset(labels[0], labels[1], labels[2])); err != nil {
+				t.Fatalf("expected successful canonicalization, got: %s", err)
+			}
+
+			if diff := cmp.Diff(want, got.String()); diff != "" {
+				t.Fatalf("canonicalization mismatch (-want +got):\n%s", diff)
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package longdistance
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// snapshotVersion is the version of the snapshot format. It's bumped whenever
+// the format changes in a way older versions of the library can't read.
+const snapshotVersion = 1
+
+// contextSnapshot is the serialised form of a [Context].
+type contextSnapshot struct {
+	Version          int                     `json:"version"`
+	Terms            map[string]termSnapshot `json:"terms"`
+	Prefixes         []string                `json:"prefixes,omitempty"`
+	Protected        []string                `json:"protected,omitempty"`
+	CurrentBaseIRI   string                  `json:"currentBaseIRI,omitempty"`
+	OriginalBaseIRI  string                  `json:"originalBaseIRI,omitempty"`
+	Vocab            string                  `json:"vocab,omitempty"`
+	DefaultLanguage  string                  `json:"defaultLanguage,omitempty"`
+	DefaultDirection string                  `json:"defaultDirection,omitempty"`
+	Previous         *contextSnapshot        `json:"previous,omitempty"`
+}
+
+// termSnapshot is the serialised form of a [Term].
+//
+// The scoped context is stored as a string. Embedding it as JSON would
+// reformat it, and term definitions are compared byte for byte when
+// redefining protected terms.
+type termSnapshot struct {
+	IRI       string `json:"iri,omitempty"`
+	Prefix    bool   `json:"prefix,omitempty"`
+	Protected bool   `json:"protected,omitempty"`
+	Reverse   bool   `json:"reverse,omitempty"`
+
+	BaseIRI   string   `json:"baseIRI,omitempty"`
+	Context   string   `json:"context,omitempty"`
+	Container []string `json:"container,omitempty"`
+	Direction string   `json:"direction,omitempty"`
+	Index     string   `json:"index,omitempty"`
+	Language  string   `json:"language,omitempty"`
+	Nest      string   `json:"nest,omitempty"`
+	Type      string   `json:"type,omitempty"`
+}
+
+func newTermSnapshot(t Term) termSnapshot {
+	return termSnapshot{
+		IRI:       t.IRI,
+		Prefix:    t.Prefix,
+		Protected: t.Protected,
+		Reverse:   t.Reverse,
+		BaseIRI:   t.BaseIRI,
+		Context:   string(t.Context),
+		Container: t.Container,
+		Direction: t.Direction,
+		Index:     t.Index,
+		Language:  t.Language,
+		Nest:      t.Nest,
+		Type:      t.Type,
+	}
+}
+
+func (t termSnapshot) term() Term {
+	var lctx json.RawMessage
+	if t.Context != "" {
+		lctx = json.RawMessage(t.Context)
+	}
+
+	return Term{
+		IRI:       t.IRI,
+		Prefix:    t.Prefix,
+		Protected: t.Protected,
+		Reverse:   t.Reverse,
+		BaseIRI:   t.BaseIRI,
+		Context:   lctx,
+		Container: t.Container,
+		Direction: t.Direction,
+		Index:     t.Index,
+		Language:  t.Language,
+		Nest:      t.Nest,
+		Type:      t.Type,
+	}
+}
+
+// MarshalJSON serialises the context to a snapshot.
+//
+// The snapshot holds the state of the processed context: its term
+// definitions, protected terms, vocabulary mapping, base IRI, default
+// language and direction, and the context to revert to for non-propagated
+// contexts. Keys are sorted, so the same context always results in the same
+// snapshot.
+//
+// Restore it with [LoadContextSnapshot]. This avoids having to process a
+// context again, for example when used with [WithProcessedContext].
+func (c *Context) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.snapshot())
+}
+
+func (c *Context) snapshot() *contextSnapshot {
+	if c == nil {
+		return nil
+	}
+
+	terms := make(map[string]termSnapshot, len(c.defs))
+	for k, def := range c.defs {
+		terms[k] = newTermSnapshot(def)
+	}
+
+	return &contextSnapshot{
+		Version:          snapshotVersion,
+		Terms:            terms,
+		Prefixes:         slices.Sorted(maps.Keys(c.prefixes)),
+		Protected:        slices.Sorted(maps.Keys(c.protected)),
+		CurrentBaseIRI:   c.currentBaseIRI,
+		OriginalBaseIRI:  c.originalBaseIRI,
+		Vocab:            c.vocabMapping,
+		DefaultLanguage:  c.defaultLang,
+		DefaultDirection: c.defaultDirection,
+		Previous:         c.previousCtx.snapshot(),
+	}
+}
+
+// UnmarshalJSON restores a context from a snapshot created with
+// [Context.MarshalJSON].
+func (c *Context) UnmarshalJSON(data []byte) error {
+	var snap contextSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSnapshot, err)
+	}
+
+	res, err := snap.restore()
+	if err != nil {
+		return err
+	}
+
+	*c = *res
+	return nil
+}
+
+func (s *contextSnapshot) restore() (*Context, error) {
+	if s.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidSnapshot, s.Version)
+	}
+
+	res := newContext(s.OriginalBaseIRI)
+	res.currentBaseIRI = s.CurrentBaseIRI
+	res.vocabMapping = s.Vocab
+	res.defaultLang = s.DefaultLanguage
+	res.defaultDirection = s.DefaultDirection
+
+	for k, def := range s.Terms {
+		res.defs[k] = def.term()
+	}
+
+	for _, k := range s.Prefixes {
+		res.prefixes[k] = struct{}{}
+	}
+
+	for _, k := range s.Protected {
+		res.protected[k] = struct{}{}
+	}
+
+	if s.Previous != nil {
+		prev, err := s.Previous.restore()
+		if err != nil {
+			return nil, err
+		}
+		res.previousCtx = prev
+	}
+
+	return res, nil
+}
+
+// LoadContextSnapshot restores a context from a snapshot created with
+// [Context.MarshalJSON].
+func LoadContextSnapshot(data []byte) (*Context, error) {
+	var res Context
+	if err := res.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// MustLoadContextSnapshot is like [LoadContextSnapshot] but panics if the
+// snapshot is invalid. It's intended for snapshots embedded in a program.
+func MustLoadContextSnapshot(data []byte) *Context {
+	res, err := LoadContextSnapshot(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return res
+}
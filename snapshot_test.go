@@ -0,0 +1,110 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+)
+
+func TestContextSnapshot(t *testing.T) {
+	lctx := json.RawMessage(`[{
+		"@version": 1.1,
+		"@propagate": false,
+		"@base": "https://example.org/base/",
+		"@vocab": "https://example.org/ns#",
+		"@language": "en",
+		"@direction": "ltr",
+		"ex": "https://example.org/ex#",
+		"knows": {"@id": "ex:knows", "@type": "@id", "@protected": true},
+		"tags": {"@id": "ex:tags", "@container": "@set"},
+		"name": {"@id": "ex:name", "@context": {"@language": "nl"}}
+	}, {
+		"local": "ex:local"
+	}]`)
+
+	orig := ProcessContext(t, lctx, "https://example.org/context")
+
+	data, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	restored, err := ld.LoadContextSnapshot(data)
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	again, err := json.Marshal(restored)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, again) {
+		t.Errorf("expected identical snapshots, got:\n%s\n%s", data, again)
+	}
+
+	if diff := cmp.Diff(orig.TermMap(), restored.TermMap()); diff != "" {
+		t.Errorf("term mismatch (-want +got):\n%s", diff)
+	}
+
+	if restored.PreviousContext() == nil {
+		t.Errorf("expected the previous context to be restored")
+	}
+}
+
+func TestContextSnapshotExpand(t *testing.T) {
+	doc := LoadData(t, "observatory/createnote.json")
+
+	data, err := json.Marshal(ProcessContext(t, LoadData(t, "as.jsonld"), ASURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := ld.MustLoadContextSnapshot(data)
+
+	want, err := ld.NewProcessor(
+		ld.WithRemoteContextLoader(StaticLoader(t, "as.jsonld")),
+		ld.WithOrdered(true),
+	).Expand(t.Context(), bytes.NewReader(doc), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ld.NewProcessor(
+		ld.WithProcessedContext(ASURL, restored),
+		ld.WithOrdered(true),
+	).Expand(t.Context(), bytes.NewReader(doc), "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %s", err)
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("expansion mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestContextSnapshotInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "invalid JSON", data: `{`},
+		{name: "no version", data: `{"terms": {}}`},
+		{name: "unknown version", data: `{"version": 999, "terms": {}}`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ld.LoadContextSnapshot([]byte(tc.data))
+			if !errors.Is(err, ld.ErrInvalidSnapshot) {
+				t.Fatalf("expected error: %s, got: %v", ld.ErrInvalidSnapshot, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,310 @@
+package longdistance
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"sourcery.dny.nu/longdistance/internal/iri"
+	"sourcery.dny.nu/longdistance/internal/json"
+)
+
+// ToRDF transforms a list of [Node] into an RDF [Dataset].
+//
+// Nodes in a @graph end up in the named graph identified by the @id of the
+// node holding the @graph. Everything else ends up in the default graph.
+// Anything that can't be represented in RDF, like properties and nodes with a
+// relative IRI, is dropped.
+//
+// Properties that are blank nodes are dropped unless generalized RDF is
+// enabled with [WithProduceGeneralizedRDF].
+func (p *Processor) ToRDF(document []Node) (Dataset, error) {
+	// 1)
+	nm := newNodeMap()
+	if err := nm.generate(document, KeywordDefault, "", nil, "", nil); err != nil {
+		return nil, err
+	}
+
+	dataset := Dataset{}
+
+	// 2)
+	for _, graphName := range slices.Sorted(maps.Keys(nm.graphs)) {
+		// 2.1)
+		var graphTerm RDFTerm
+		if graphName != KeywordDefault {
+			term, ok := resourceTerm(graphName)
+			if !ok {
+				continue
+			}
+			graphTerm = term
+		}
+
+		graph := nm.graphs[graphName]
+
+		// 2.2) 2.3)
+		for _, subject := range slices.Sorted(maps.Keys(graph)) {
+			// 2.3.1)
+			subjectTerm, ok := resourceTerm(subject)
+			if !ok {
+				continue
+			}
+
+			node := graph[subject]
+
+			add := func(predicate, object RDFTerm) {
+				dataset = append(dataset, Quad{
+					Subject:   subjectTerm,
+					Predicate: predicate,
+					Object:    object,
+					Graph:     graphTerm,
+				})
+			}
+
+			// 2.3.2.1)
+			for _, typ := range node.Type {
+				if term, ok := resourceTerm(typ); ok {
+					add(NewIRI(RDFType), term)
+				}
+			}
+
+			// 2.3.2)
+			for _, property := range slices.Sorted(maps.Keys(node.Properties)) {
+				// 2.3.2.2) 2.3.2.3) 2.3.2.4)
+				if isKeyword(property) {
+					continue
+				}
+
+				predicate, ok := resourceTerm(property)
+				if !ok || (predicate.IsBlankNode() && !p.produceGeneralizedRDF) {
+					continue
+				}
+
+				// 2.3.2.5)
+				for _, item := range node.Properties[property] {
+					var listTriples []Quad
+
+					// 2.3.2.5.1)
+					object, ok := p.objectToRDF(nm.issuer, item, &listTriples)
+					if !ok {
+						continue
+					}
+
+					add(predicate, object)
+
+					// 2.3.2.5.2)
+					for _, triple := range listTriples {
+						triple.Graph = graphTerm
+						dataset = append(dataset, triple)
+					}
+				}
+			}
+		}
+	}
+
+	return dataset, nil
+}
+
+// objectToRDF implements the object to RDF conversion algorithm.
+//
+// The triples needed to represent a list are appended to listTriples. It
+// returns false if the item can't be represented in RDF.
+func (p *Processor) objectToRDF(
+	issuer *blankNodeIssuer,
+	item Node,
+	listTriples *[]Quad,
+) (RDFTerm, bool) {
+	// 1) 2)
+	if item.isNode() {
+		return resourceTerm(item.ID)
+	}
+
+	// 3)
+	if item.IsList() {
+		return p.listToRDF(issuer, item.List, listTriples), true
+	}
+
+	// 4) 5)
+	value := item.Value
+	var datatype string
+	if len(item.Type) > 0 {
+		datatype = item.Type[0]
+	}
+
+	// 6)
+	if datatype != "" && datatype != KeywordJSON && !iri.IsAbsolute(datatype) {
+		return RDFTerm{}, false
+	}
+
+	// 7)
+	if item.Language != "" && !wellFormedLanguage(item.Language) {
+		return RDFTerm{}, false
+	}
+
+	var lexical string
+
+	switch {
+	case datatype == KeywordJSON:
+		// 8)
+		canonical, err := json.Canonicalize(value)
+		if err != nil {
+			return RDFTerm{}, false
+		}
+		lexical = string(canonical)
+		datatype = RDFJSON
+	case json.IsString(value):
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			return RDFTerm{}, false
+		}
+		lexical = s
+	case string(value) == "true" || string(value) == "false":
+		// 9)
+		lexical = string(value)
+		if datatype == "" {
+			datatype = XSDBoolean
+		}
+	default:
+		f, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return RDFTerm{}, false
+		}
+
+		if f != math.Trunc(f) || math.Abs(f) >= 1e21 || datatype == XSDDouble {
+			// 10)
+			lexical = canonicalDouble(f)
+			if datatype == "" {
+				datatype = XSDDouble
+			}
+		} else {
+			// 11)
+			lexical = canonicalInteger(value, f)
+			if datatype == "" {
+				datatype = XSDInteger
+			}
+		}
+	}
+
+	// 12) 13) direction is dropped since rdfDirection is not supported
+	return NewLiteral(lexical, datatype, strings.ToLower(item.Language)), true
+}
+
+// listToRDF implements the list to RDF conversion algorithm.
+func (p *Processor) listToRDF(
+	issuer *blankNodeIssuer,
+	list []Node,
+	listTriples *[]Quad,
+) RDFTerm {
+	// 1)
+	if len(list) == 0 {
+		return NewIRI(RDFNil)
+	}
+
+	// 2)
+	bnodes := make([]RDFTerm, 0, len(list))
+	for range list {
+		bnodes = append(bnodes, NewBlankNode(issuer.issue("")))
+	}
+
+	// 3)
+	for i, item := range list {
+		subject := bnodes[i]
+
+		// 3.1) 3.2)
+		var embedded []Quad
+		if object, ok := p.objectToRDF(issuer, item, &embedded); ok {
+			// 3.3)
+			*listTriples = append(*listTriples, Quad{
+				Subject:   subject,
+				Predicate: NewIRI(RDFFirst),
+				Object:    object,
+			})
+		}
+
+		// 3.4)
+		rest := NewIRI(RDFNil)
+		if i < len(bnodes)-1 {
+			rest = bnodes[i+1]
+		}
+
+		*listTriples = append(*listTriples, Quad{
+			Subject:   subject,
+			Predicate: NewIRI(RDFRest),
+			Object:    rest,
+		})
+
+		// 3.5)
+		*listTriples = append(*listTriples, embedded...)
+	}
+
+	// 4)
+	return bnodes[0]
+}
+
+// resourceTerm returns the term for a node identifier, if it's a blank node
+// or a well-formed IRI.
+func resourceTerm(id string) (RDFTerm, bool) {
+	if strings.HasPrefix(id, BlankNode) {
+		return NewBlankNode(id), true
+	}
+
+	if id == "" || !iri.IsAbsolute(id) {
+		return RDFTerm{}, false
+	}
+
+	return NewIRI(id), true
+}
+
+// wellFormedLanguage returns if the language tag has the form described by
+// BCP47: [a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*.
+func wellFormedLanguage(lang string) bool {
+	for i, subtag := range strings.Split(lang, "-") {
+		if len(subtag) < 1 || len(subtag) > 8 {
+			return false
+		}
+
+		for _, r := range subtag {
+			isAlpha := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			isDigit := r >= '0' && r <= '9'
+			if !isAlpha && (i == 0 || !isDigit) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// canonicalDouble formats a number in the canonical lexical form of
+// xsd:double, like 1.1E0.
+//
+// The mantissa has the fewest digits needed to represent f exactly, like
+// [json.FormatNumber] does.
+func canonicalDouble(f float64) string {
+	mantissa, exp, _ := strings.Cut(strconv.FormatFloat(f, 'E', -1, 64), "E")
+
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+
+	e, _ := strconv.Atoi(exp)
+
+	return mantissa + "E" + strconv.Itoa(e)
+}
+
+// canonicalInteger formats an integral number in the canonical lexical form of
+// xsd:integer.
+//
+// When the raw value is written as an integer it's used as-is, to avoid any
+// loss of precision for large numbers.
+func canonicalInteger(raw json.RawMessage, f float64) string {
+	if s := string(raw); !strings.ContainsAny(s, ".eE") {
+		if s == "-0" {
+			return "0"
+		}
+		return s
+	}
+
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
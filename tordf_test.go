@@ -0,0 +1,165 @@
+package longdistance_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	ld "sourcery.dny.nu/longdistance"
+	"sourcery.dny.nu/longdistance/nquads"
+	"sourcery.dny.nu/longdistance/rdfc"
+)
+
+// toRDFSkip lists the W3C RDF serialisation tests that are skipped, with the
+// reason why. Entries that set rdfDirection are skipped by RunManifest.
+var toRDFSkip = map[string]string{}
+
+// TestToRDF runs the W3C RDF serialisation tests. The N-Quads the test
+// expects are compared to the produced dataset after canonicalizing both.
+func TestToRDF(t *testing.T) {
+	RunManifest(t, "w3c/toRdf-manifest.jsonld", toRDFSkip, func(t *testing.T, tc ManifestTest) {
+		p := tc.Processor(t)
+
+		var dataset ld.Dataset
+		nodes, err := p.Expand(t.Context(), bytes.NewReader(LoadData(t, tc.File(tc.Input))), tc.DocumentIRI())
+		if err == nil {
+			dataset, err = p.ToRDF(nodes)
+		}
+
+		if tc.CheckError(t, err) {
+			return
+		}
+
+		// positive syntax tests only check the input is accepted
+		if tc.Expect == "" {
+			return
+		}
+
+		f, err := os.Open(filepath.Join("testdata", tc.File(tc.Expect)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		want, err := nquads.NewReader(f).ReadAll()
+		if err != nil {
+			t.Fatalf("invalid N-Quads in %s: %s", tc.Expect, err)
+		}
+
+		if diff := cmp.Diff(canonicalQuads(t, want), canonicalQuads(t, dataset)); diff != "" {
+			t.Errorf("dataset mismatch (-want +got):\n%s", diff)
+		}
+	})
+}
+
+// canonicalQuads returns the quads of the canonicalized dataset in sorted
+// order, so datasets that only differ in blank node labels compare equal.
+func canonicalQuads(t *testing.T, dataset ld.Dataset) []string {
+	t.Helper()
+
+	canon, err := rdfc.Canonicalize(dataset)
+	if err != nil {
+		t.Fatalf("expected successful canonicalization, got: %s", err)
+	}
+
+	res := make([]string, 0, len(canon))
+	for _, quad := range canon {
+		res = append(res, quad.String())
+	}
+	slices.Sort(res)
+
+	return res
+}
+
+// TestToRDFLocal covers serialisation to RDF of documents outside of the W3C
+// test suite.
+func TestToRDFLocal(t *testing.T) {
+	tests := []struct {
+		name string
+		proc *ld.Processor
+		in   json.RawMessage
+		out  []string
+	}{
+		{
+			name: "basic",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/basic/in.json"),
+			out:  LoadQuads(t, "longdistance/tordf/basic/out.nq"),
+		},
+		{
+			name: "numbers",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/numbers/in.json"),
+			out:  LoadQuads(t, "longdistance/tordf/numbers/out.nq"),
+		},
+		{
+			name: "double precision",
+			proc: ld.NewProcessor(),
+			in:   json.RawMessage(`{"@id": "http://example.org/n", "http://example.org/float": [0.30000000000000004, 5e-324]}`),
+			out: []string{
+				`<http://example.org/n> <http://example.org/float> "3.0000000000000004E-1"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+				`<http://example.org/n> <http://example.org/float> "5.0E-324"^^<http://www.w3.org/2001/XMLSchema#double> .`,
+			},
+		},
+		{
+			name: "lists",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/list/in.json"),
+			out:  LoadQuads(t, "longdistance/tordf/list/out.nq"),
+		},
+		{
+			name: "named graph",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/named-graph/in.json"),
+			out:  LoadQuads(t, "longdistance/tordf/named-graph/out.nq"),
+		},
+		{
+			name: "JSON literal",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/json/in.json"),
+			out:  LoadQuads(t, "longdistance/tordf/json/out.nq"),
+		},
+		{
+			name: "blank node property",
+			proc: ld.NewProcessor(),
+			in:   LoadData(t, "longdistance/tordf/generalized/in.json"),
+			out:  []string{`<http://example.org/s> <http://example.org/p> "kept" .`},
+		},
+		{
+			name: "generalized RDF",
+			proc: ld.NewProcessor(ld.WithProduceGeneralizedRDF(true)),
+			in:   LoadData(t, "longdistance/tordf/generalized/in.json"),
+			out:  LoadQuads(t, "longdistance/tordf/generalized/out.nq"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			nodes, err := tc.proc.Expand(t.Context(), bytes.NewReader(tc.in), "")
+			if err != nil {
+				t.Fatalf("expected successful expand, got: %s", err)
+			}
+
+			dataset, err := tc.proc.ToRDF(nodes)
+			if err != nil {
+				t.Fatalf("expected successful conversion, got: %s", err)
+			}
+
+			got := make([]string, 0, len(dataset))
+			for _, quad := range dataset {
+				got = append(got, quad.String())
+			}
+			slices.Sort(got)
+
+			if diff := cmp.Diff(tc.out, got); diff != "" {
+				t.Errorf("dataset mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}